@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// menuActions maps the -action values to the menu choice they select.
+var menuActions = map[string]string{
+	"generate": "1",
+	"patch":    "2",
+	"restart":  "3",
+	"restore":  "4",
+	"init":     "5",
+}
+
+// clusterChangingActions maps the menu choices that write to the cluster to the verb the cluster
+// confirmation names them by; every one of them is guarded by -confirm-cluster or the typed name.
+var clusterChangingActions = map[string]string{
+	"2": "patch",
+	"3": "restart",
+	"4": "restore",
+}
+
+// validateAction rejects an unknown -action before anything runs.
+func validateAction() error {
+	if *actionName == "" {
+		return nil
+	}
+	if _, ok := menuActions[strings.ToLower(*actionName)]; !ok {
+		return fmt.Errorf("unknown -action %q (expected generate, patch, restart, restore or init)", *actionName)
+	}
+	return nil
+}
+
+// selectedAction returns the menu choice to run: the one named by -action, or the operator's
+// answer to the interactive menu.
+func selectedAction() string {
+	if *actionName != "" {
+		return menuActions[strings.ToLower(*actionName)]
+	}
+	return actionPrompt()
+}
+
+// nonInteractive reports whether the run was started from a script (-action), where the follow-up
+// prompts must not wait on stdin and their answers have to be given as flags instead.
+func nonInteractive() bool {
+	return *actionName != ""
+}
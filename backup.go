@@ -0,0 +1,321 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+// backupSnapshot is what the patch action records about a workload and its HPA right before
+// changing them: everything the patch can touch, so the restore action can put it back.
+type backupSnapshot struct {
+	TakenAt    time.Time                                  `json:"takenAt"`
+	Context    string                                     `json:"context,omitempty"` // the -context of the patch run, if any
+	Kind       string                                     `json:"kind"`
+	Namespace  string                                     `json:"namespace"`
+	Name       string                                     `json:"name"`
+	Containers []backupContainer                          `json:"containers"`
+	Strategy   *appsv1.DeploymentStrategy                 `json:"strategy,omitempty"` // Deployments only
+	Replicas   *int32                                     `json:"replicas,omitempty"` // set when the row scales the workload
+	HPA        *autoscalingv2.HorizontalPodAutoscalerSpec `json:"hpa,omitempty"`      // nil when there is no HPA
+	PDBName    string                                     `json:"pdbName,omitempty"`  // set when the row patches a PDB
+	PDB        *backupPDB                                 `json:"pdb,omitempty"`
+}
+
+// backupPDB holds the budgets of the PodDisruptionBudget of the snapshot.
+type backupPDB struct {
+	MinAvailable   *intstr.IntOrString `json:"minAvailable,omitempty"`
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+// backupContainer holds the resources of one container of the snapshot.
+type backupContainer struct {
+	Name      string                  `json:"name"`
+	Resources v1.ResourceRequirements `json:"resources"`
+}
+
+func (s backupSnapshot) key() string {
+	key := s.Kind + ":" + s.Namespace + "/" + s.Name
+	if s.Context != "" {
+		key = s.Context + ":" + key // The same workload in two clusters is two objects.
+	}
+	return key
+}
+
+// checkContext refuses to restore a snapshot taken through another kubeconfig context than the
+// one the restore talks to, so one cluster's state is never applied to another. Snapshots taken
+// without -context can't tell and are restored.
+func (s backupSnapshot) checkContext(context string) error {
+	if s.Context == "" || s.Context == context {
+		return nil
+	}
+	return fmt.Errorf("backup of %s was taken on context %q, not %q; restore it with -context=%s", s.key(), s.Context, context, s.Context)
+}
+
+// unsafeFileChars matches what can't appear in a backup file name, e.g. the ":" and "/" of EKS
+// context ARNs.
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// takeBackup reads the live workload and HPA of the row and writes them to
+// <-backup-dir>/backup-<namespace>-<name>[-<context>]-<timestamp>.yaml, returning the path. The
+// context keeps multi-context runs from overwriting each other's backups.
+func takeBackup(clientset kubernetes.Interface, row patchRow, now time.Time) (string, error) {
+	live, err := getWorkload(clientset, row)
+	if err != nil {
+		return "", err
+	}
+	snapshot := backupSnapshot{TakenAt: now.UTC(), Context: activeContext, Kind: row.Kind, Namespace: row.Namespace, Name: row.DeploymentName, Strategy: live.Strategy}
+	for _, container := range live.Template.Spec.Containers {
+		snapshot.Containers = append(snapshot.Containers, backupContainer{Name: container.Name, Resources: container.Resources})
+	}
+	// Only workloads without an HPA are scaled; the replicas of HPA-managed ones are the HPA's.
+	if _, scales := row.replicaTarget(); scales {
+		replicas := int32(1) // The API default when spec.replicas is unset.
+		if live.Replicas != nil {
+			replicas = *live.Replicas
+		}
+		snapshot.Replicas = &replicas
+	}
+
+	if hasReplicaCount(row.Kind) {
+		ctx, cancel := apiContext()
+		hpa, err := getHPA(ctx, clientset, row.Namespace, row.DeploymentName)
+		cancel()
+		switch {
+		case err == nil:
+			snapshot.HPA = &hpa.Spec
+		case !apierrors.IsNotFound(err):
+			return "", fmt.Errorf("failed to get HPA %s: %w", row.DeploymentName, err)
+		}
+	}
+
+	if row.UpdateResourceAndHPA && row.PDBName != "" {
+		ctx, cancel := apiContext()
+		pdb, err := clientset.PolicyV1().PodDisruptionBudgets(row.Namespace).Get(ctx, row.PDBName, metav1.GetOptions{})
+		cancel()
+		if err != nil {
+			return "", fmt.Errorf("failed to get PDB %s: %w", row.PDBName, err)
+		}
+		snapshot.PDBName = row.PDBName
+		snapshot.PDB = &backupPDB{MinAvailable: pdb.Spec.MinAvailable, MaxUnavailable: pdb.Spec.MaxUnavailable}
+	}
+
+	data, err := yaml.Marshal(snapshot)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode backup: %w", err)
+	}
+	if err := os.MkdirAll(*backupDir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	name := row.DeploymentName
+	if row.Kind != kindDeployment {
+		name = kubectlKind(row.Kind) + "-" + name // Keep a StatefulSet and a Deployment of the same name apart.
+	}
+	if activeContext != "" {
+		name += "-" + unsafeFileChars.ReplaceAllString(activeContext, "_")
+	}
+	path := filepath.Join(*backupDir, fmt.Sprintf("backup-%s-%s-%s.yaml", row.Namespace, name, now.UTC().Format("20060102T150405Z")))
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write backup: %w", err)
+	}
+	return path, nil
+}
+
+// loadBackups reads the snapshots matching the glob pattern and keeps the most recent one of
+// every object, i.e. the state right before the last patch run that changed it.
+func loadBackups(pattern string) ([]backupSnapshot, error) {
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup pattern %q: %w", pattern, err)
+	}
+
+	latest := make(map[string]backupSnapshot)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read backup: %w", err)
+		}
+		var snapshot backupSnapshot
+		if err := yaml.UnmarshalStrict(data, &snapshot); err != nil {
+			return nil, fmt.Errorf("invalid backup file %s: %w", path, err)
+		}
+		if snapshot.Kind == "" {
+			snapshot.Kind = kindDeployment
+		}
+		if current, ok := latest[snapshot.key()]; !ok || snapshot.TakenAt.After(current.TakenAt) {
+			latest[snapshot.key()] = snapshot
+		}
+	}
+
+	snapshots := make([]backupSnapshot, 0, len(latest))
+	for _, snapshot := range latest {
+		snapshots = append(snapshots, snapshot)
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].key() < snapshots[j].key() })
+	return snapshots, nil
+}
+
+// restoreSnapshot puts the container resources, rolling update strategy, replica count, HPA spec
+// and PDB budgets of the snapshot back. Everything else (images, env, labels) keeps its live value.
+func restoreSnapshot(clientset kubernetes.Interface, snapshot backupSnapshot) error {
+	ctx, cancel := apiContext()
+	defer cancel()
+
+	options := metav1.UpdateOptions{DryRun: dryRunAll()}
+	apps := clientset.AppsV1()
+	switch snapshot.Kind {
+	case kindStatefulSet:
+		sts, err := apps.StatefulSets(snapshot.Namespace).Get(ctx, snapshot.Name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get statefulset %s: %w", snapshot.Name, err)
+		}
+		restoreContainers(&sts.Spec.Template, snapshot.Containers)
+		if snapshot.Replicas != nil {
+			sts.Spec.Replicas = snapshot.Replicas
+		}
+		if _, err := apps.StatefulSets(snapshot.Namespace).Update(ctx, sts, options); err != nil {
+			return fmt.Errorf("failed to restore statefulset %s: %w", snapshot.Name, err)
+		}
+	case kindDaemonSet:
+		ds, err := apps.DaemonSets(snapshot.Namespace).Get(ctx, snapshot.Name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get daemonset %s: %w", snapshot.Name, err)
+		}
+		restoreContainers(&ds.Spec.Template, snapshot.Containers)
+		if _, err := apps.DaemonSets(snapshot.Namespace).Update(ctx, ds, options); err != nil {
+			return fmt.Errorf("failed to restore daemonset %s: %w", snapshot.Name, err)
+		}
+	default:
+		deploy, err := apps.Deployments(snapshot.Namespace).Get(ctx, snapshot.Name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get deployment %s: %w", snapshot.Name, err)
+		}
+		restoreContainers(&deploy.Spec.Template, snapshot.Containers)
+		if snapshot.Strategy != nil {
+			deploy.Spec.Strategy = *snapshot.Strategy
+		}
+		if snapshot.Replicas != nil {
+			deploy.Spec.Replicas = snapshot.Replicas
+		}
+		if _, err := apps.Deployments(snapshot.Namespace).Update(ctx, deploy, options); err != nil {
+			return fmt.Errorf("failed to restore deployment %s: %w", snapshot.Name, err)
+		}
+	}
+
+	if snapshot.HPA != nil {
+		hpa, err := getHPA(ctx, clientset, snapshot.Namespace, snapshot.Name)
+		if err != nil {
+			return fmt.Errorf("failed to get HPA %s: %w", snapshot.Name, err)
+		}
+		hpa.Spec = *snapshot.HPA
+		if _, err := updateHPA(ctx, clientset, hpa, options); err != nil {
+			return fmt.Errorf("failed to restore HPA %s: %w", snapshot.Name, err)
+		}
+	}
+
+	if snapshot.PDB != nil {
+		pdbs := clientset.PolicyV1().PodDisruptionBudgets(snapshot.Namespace)
+		pdb, err := pdbs.Get(ctx, snapshot.PDBName, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get PDB %s: %w", snapshot.PDBName, err)
+		}
+		pdb.Spec.MinAvailable, pdb.Spec.MaxUnavailable = snapshot.PDB.MinAvailable, snapshot.PDB.MaxUnavailable
+		if _, err := pdbs.Update(ctx, pdb, options); err != nil {
+			return fmt.Errorf("failed to restore PDB %s: %w", snapshot.PDBName, err)
+		}
+	}
+	return nil
+}
+
+// restoreContainers sets the resources of the containers recorded in the snapshot. Containers
+// added or removed since are left alone.
+func restoreContainers(template *v1.PodTemplateSpec, containers []backupContainer) {
+	for i, container := range template.Spec.Containers {
+		for _, saved := range containers {
+			if saved.Name == container.Name {
+				template.Spec.Containers[i].Resources = saved.Resources
+			}
+		}
+	}
+}
+
+// restoreFromBackup is the "Restore from backup" action: it re-applies the latest snapshot of
+// every object matching -restore-from or the pattern the operator enters (all snapshots in
+// -backup-dir by default).
+func restoreFromBackup() error {
+	pattern := *restoreFrom
+	if pattern == "" {
+		if nonInteractive() {
+			return withExitCode(exitUsage, fmt.Errorf("-action=restore needs -restore-from (a backup file or glob)"))
+		}
+		defaultPattern := filepath.Join(*backupDir, "backup-*.yaml")
+		fmt.Printf("\nBackup file(s) to restore (path or glob) [%s]: ", defaultPattern)
+		input, _ := stdinReader.ReadString('\n')
+		if pattern = strings.TrimSpace(input); pattern == "" {
+			pattern = defaultPattern
+		}
+	}
+
+	snapshots, err := loadBackups(pattern)
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
+	if len(snapshots) == 0 {
+		return withExitCode(exitNothingToDo, fmt.Errorf("no backups match %s", pattern))
+	}
+
+	clientset, _, err := getKubeClient()
+	if err != nil {
+		return err
+	}
+	context, _, _, err := currentCluster()
+	if err != nil {
+		return withExitCode(exitConnectivity, err)
+	}
+	summary.Action = "restored"
+	if *dryRun {
+		summary.Action = "would restore"
+	}
+	defer handleInterrupts()()
+	var errs []error
+	for i, snapshot := range snapshots {
+		if isInterrupted() {
+			return withExitCode(exitInterrupted, fmt.Errorf("interrupted: restored %d, %d failed, %d of %d backups not restored", summary.Succeeded, len(errs), len(snapshots)-i, len(snapshots)))
+		}
+		summary.addNamespace(snapshot.Namespace)
+		if err := snapshot.checkContext(context); err != nil {
+			logger.Error("restore refused", "err", err)
+			errs = append(errs, err)
+			summary.Failed++
+			continue
+		}
+		err := retryTransient("restore "+snapshot.key(), func() error { return restoreSnapshot(clientset, snapshot) })
+		if err != nil {
+			logger.Error("restore failed", "err", err)
+			errs = append(errs, err)
+			summary.Failed++
+			continue
+		}
+		logger.Info("restored", "kind", snapshot.Kind, "namespace", snapshot.Namespace, "name", snapshot.Name, "takenAt", snapshot.TakenAt.Local().Format(time.RFC3339))
+		summary.Succeeded++
+	}
+	if len(errs) > 0 {
+		return withExitCode(exitPartialFailure, fmt.Errorf("failed to restore %d of %d backups: %w", len(errs), len(snapshots), errors.Join(errs...)))
+	}
+	logger.Info("backups restored", "count", len(snapshots), "pattern", pattern)
+	return nil
+}
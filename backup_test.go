@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestBackupAndRestoreUndoesPatch(t *testing.T) {
+	previousDir := *backupDir
+	*backupDir = t.TempDir()
+	defer func() { *backupDir = previousDir }()
+
+	minReplicas := int32(2)
+	clientset := fake.NewSimpleClientset(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+			Spec: appsv1.DeploymentSpec{Template: v1.PodTemplateSpec{Spec: v1.PodSpec{Containers: []v1.Container{{
+				Name:      "app",
+				Image:     "web:1",
+				Resources: v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("100m")}},
+			}}}}},
+		},
+		&autoscalingv2.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+			Spec:       autoscalingv2.HorizontalPodAutoscalerSpec{MinReplicas: &minReplicas, MaxReplicas: 5},
+		},
+	)
+	row := patchRow{Kind: kindDeployment, Namespace: "shop", DeploymentName: "web"}
+	if _, err := takeBackup(clientset, row, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("takeBackup: %v", err)
+	}
+
+	// Simulate the patch and a later, unrelated image change.
+	ctx := context.Background()
+	deploy, _ := clientset.AppsV1().Deployments("shop").Get(ctx, "web", metav1.GetOptions{})
+	deploy.Spec.Template.Spec.Containers[0].Resources.Requests[v1.ResourceCPU] = resource.MustParse("2")
+	deploy.Spec.Template.Spec.Containers[0].Image = "web:2"
+	clientset.AppsV1().Deployments("shop").Update(ctx, deploy, metav1.UpdateOptions{})
+	hpa, _ := clientset.AutoscalingV2().HorizontalPodAutoscalers("shop").Get(ctx, "web", metav1.GetOptions{})
+	hpa.Spec.MaxReplicas = 50
+	clientset.AutoscalingV2().HorizontalPodAutoscalers("shop").Update(ctx, hpa, metav1.UpdateOptions{})
+
+	snapshots, err := loadBackups(filepath.Join(*backupDir, "backup-*.yaml"))
+	if err != nil || len(snapshots) != 1 {
+		t.Fatalf("loadBackups = %d snapshots, %v; want 1", len(snapshots), err)
+	}
+	if err := restoreSnapshot(clientset, snapshots[0]); err != nil {
+		t.Fatalf("restoreSnapshot: %v", err)
+	}
+
+	deploy, _ = clientset.AppsV1().Deployments("shop").Get(ctx, "web", metav1.GetOptions{})
+	container := deploy.Spec.Template.Spec.Containers[0]
+	if cpu := container.Resources.Requests[v1.ResourceCPU]; cpu.String() != "100m" || container.Image != "web:2" {
+		t.Errorf("container after restore = cpu %s, image %s; want cpu 100m and the live image web:2", cpu.String(), container.Image)
+	}
+	hpa, _ = clientset.AutoscalingV2().HorizontalPodAutoscalers("shop").Get(ctx, "web", metav1.GetOptions{})
+	if hpa.Spec.MaxReplicas != 5 {
+		t.Errorf("HPA maxReplicas after restore = %d, want 5", hpa.Spec.MaxReplicas)
+	}
+}
+
+func TestBackupAndRestoreUndoesScale(t *testing.T) {
+	previousDir := *backupDir
+	*backupDir = t.TempDir()
+	defer func() { *backupDir = previousDir }()
+
+	replicas := int32(2)
+	clientset := fake.NewSimpleClientset(&appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "shop"},
+		Spec:       appsv1.StatefulSetSpec{Replicas: &replicas},
+	})
+	// A StatefulSet without an HPA, scaled by the row.
+	row := patchRow{Kind: kindStatefulSet, Namespace: "shop", DeploymentName: "db", Replicas: "5", UpdateReplicas: true}
+	if _, err := takeBackup(clientset, row, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("takeBackup: %v", err)
+	}
+
+	ctx := context.Background()
+	sts, _ := clientset.AppsV1().StatefulSets("shop").Get(ctx, "db", metav1.GetOptions{})
+	scaled := int32(5)
+	sts.Spec.Replicas = &scaled
+	clientset.AppsV1().StatefulSets("shop").Update(ctx, sts, metav1.UpdateOptions{})
+
+	snapshots, err := loadBackups(filepath.Join(*backupDir, "backup-*.yaml"))
+	if err != nil || len(snapshots) != 1 {
+		t.Fatalf("loadBackups = %d snapshots, %v; want 1", len(snapshots), err)
+	}
+	if err := restoreSnapshot(clientset, snapshots[0]); err != nil {
+		t.Fatalf("restoreSnapshot: %v", err)
+	}
+	sts, _ = clientset.AppsV1().StatefulSets("shop").Get(ctx, "db", metav1.GetOptions{})
+	if sts.Spec.Replicas == nil || *sts.Spec.Replicas != 2 {
+		t.Errorf("replicas after restore = %v, want 2", sts.Spec.Replicas)
+	}
+}
+
+func TestBackupsKeepContextsApart(t *testing.T) {
+	previousDir, previousContext := *backupDir, activeContext
+	*backupDir = t.TempDir()
+	defer func() { *backupDir, activeContext = previousDir, previousContext }()
+
+	clientset := fake.NewSimpleClientset(&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"}})
+	row := patchRow{Kind: kindDeployment, Namespace: "shop", DeploymentName: "web"}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// The same workload in two clusters, backed up within the same second.
+	var paths []string
+	for _, context := range []string{"staging", "arn:aws:eks:eu-west-1:123456789012:cluster/prod"} {
+		activeContext = context
+		path, err := takeBackup(clientset, row, now)
+		if err != nil {
+			t.Fatalf("takeBackup on %s: %v", context, err)
+		}
+		paths = append(paths, path)
+	}
+	if paths[0] == paths[1] {
+		t.Fatalf("both contexts wrote %s", paths[0])
+	}
+	if want := "backup-shop-web-arn_aws_eks_eu-west-1_123456789012_cluster_prod-20240101T000000Z.yaml"; filepath.Base(paths[1]) != want {
+		t.Errorf("backup file = %s, want %s", filepath.Base(paths[1]), want)
+	}
+
+	snapshots, err := loadBackups(filepath.Join(*backupDir, "backup-*.yaml"))
+	if err != nil || len(snapshots) != 2 {
+		t.Fatalf("loadBackups = %d snapshots, %v; want one per context", len(snapshots), err)
+	}
+	for _, snapshot := range snapshots {
+		if err := snapshot.checkContext(snapshot.Context); err != nil {
+			t.Errorf("checkContext(%s) = %v, want nil for its own context", snapshot.Context, err)
+		}
+		if err := snapshot.checkContext("dev"); err == nil {
+			t.Errorf("checkContext(dev) = nil for a snapshot of %s, want a refusal", snapshot.Context)
+		}
+	}
+	if err := (backupSnapshot{Kind: kindDeployment, Name: "web"}).checkContext("dev"); err != nil {
+		t.Errorf("checkContext() = %v for a snapshot without context, want nil", err)
+	}
+}
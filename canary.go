@@ -0,0 +1,379 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// restartedAtAnnotation is the pod template annotation kubectl rollout restart sets.
+	restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+	// revisionAnnotation is set by the deployment controller on deployments and their ReplicaSets.
+	revisionAnnotation = "deployment.kubernetes.io/revision"
+)
+
+// triggerRollout restarts a deployment the same way kubectl rollout restart does, by stamping the
+// pod template with the current time.
+func triggerRollout(clientset kubernetes.Interface, namespace, deploymentName string) error {
+	ctx, cancel := apiContext()
+	defer cancel()
+
+	patchData := fmt.Sprintf(`{"spec":{"template":{"metadata":{"annotations":{%q:%q}}}}}`, restartedAtAnnotation, time.Now().Format(time.RFC3339))
+	err := retryTransient("restart deployment "+deploymentName, func() error {
+		_, err := clientset.AppsV1().Deployments(namespace).Patch(ctx, deploymentName, types.StrategicMergePatchType, []byte(patchData), metav1.PatchOptions{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to restart deployment %s: %w", deploymentName, err)
+	}
+	return nil
+}
+
+// setPaused pauses or resumes the rollout of a deployment.
+func setPaused(clientset kubernetes.Interface, namespace, deploymentName string, paused bool) error {
+	ctx, cancel := apiContext()
+	defer cancel()
+
+	patchData := fmt.Sprintf(`{"spec":{"paused":%t}}`, paused)
+	_, err := clientset.AppsV1().Deployments(namespace).Patch(ctx, deploymentName, types.MergePatchType, []byte(patchData), metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to set paused=%t on deployment %s: %w", paused, deploymentName, err)
+	}
+	return nil
+}
+
+// restartCanaryDeployments restarts the deployments of target (see restartCandidates) one at a
+// time in canary mode. An interrupt rolls back the canary in progress and stops the restart.
+func restartCanaryDeployments(target string) error {
+	clientset, namespace, err := getKubeClient()
+	if err != nil {
+		return err
+	}
+	candidates, err := restartCandidates(clientset, namespace, target)
+	if err != nil {
+		return err
+	}
+
+	summary.Action = "restarted"
+	summary.addNamespace(namespace)
+	defer handleInterrupts()()
+	for i, deploy := range candidates {
+		if isInterrupted() {
+			return withExitCode(exitInterrupted, fmt.Errorf("interrupted: %d of %d deployments not restarted", len(candidates)-i, len(candidates)))
+		}
+		if err := restartCanary(clientset, namespace, deploy.Name); err != nil {
+			summary.Failed++
+			return err
+		}
+		summary.Succeeded++
+	}
+	return nil
+}
+
+// canaryStrategy rolls one pod at a time: the new ReplicaSet gets a single surge pod, and no old
+// pod goes away until it is available, so nothing else rolls before the canary is judged.
+func canaryStrategy() appsv1.DeploymentStrategy {
+	maxSurge, maxUnavailable := intstr.FromInt(1), intstr.FromInt(0)
+	return appsv1.DeploymentStrategy{
+		Type:          appsv1.RollingUpdateDeploymentStrategyType,
+		RollingUpdate: &appsv1.RollingUpdateDeployment{MaxSurge: &maxSurge, MaxUnavailable: &maxUnavailable},
+	}
+}
+
+// startCanaryRollout restarts a deployment like triggerRollout, but with canaryStrategy in the same
+// update, and returns the strategy it replaced.
+func startCanaryRollout(clientset kubernetes.Interface, namespace, deploymentName string) (appsv1.DeploymentStrategy, error) {
+	ctx, cancel := apiContext()
+	defer cancel()
+
+	var original appsv1.DeploymentStrategy
+	err := retryTransient("restart deployment "+deploymentName, func() error {
+		deploy, err := clientset.AppsV1().Deployments(namespace).Get(ctx, deploymentName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		original = deploy.Spec.Strategy
+		deploy.Spec.Strategy = canaryStrategy()
+		deploy.Spec.Paused = false
+		if deploy.Spec.Template.Annotations == nil {
+			deploy.Spec.Template.Annotations = make(map[string]string)
+		}
+		deploy.Spec.Template.Annotations[restartedAtAnnotation] = time.Now().Format(time.RFC3339)
+		_, err = clientset.AppsV1().Deployments(namespace).Update(ctx, deploy, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return original, fmt.Errorf("failed to restart deployment %s: %w", deploymentName, err)
+	}
+	return original, nil
+}
+
+// endCanary puts the original strategy back and sets spec.paused. Changing the strategy of a
+// paused deployment doesn't roll anything.
+func endCanary(clientset kubernetes.Interface, namespace, deploymentName string, strategy appsv1.DeploymentStrategy, paused bool) error {
+	ctx, cancel := apiContext()
+	defer cancel()
+
+	err := retryTransient("update deployment "+deploymentName, func() error {
+		deploy, err := clientset.AppsV1().Deployments(namespace).Get(ctx, deploymentName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		deploy.Spec.Strategy = strategy
+		deploy.Spec.Paused = paused
+		_, err = clientset.AppsV1().Deployments(namespace).Update(ctx, deploy, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to restore the strategy of deployment %s: %w", deploymentName, err)
+	}
+	return nil
+}
+
+// restartCanary triggers a rollout that rolls a single pod (see canaryStrategy) and pauses it as
+// soon as the controller has created the new ReplicaSet, before the canary pod can be ready, so
+// exactly one pod runs the new revision. Once that pod is ready the operator decides whether to
+// resume the rollout or roll back to the previous revision. If no new pod becomes ready within
+// -canary-timeout, or the run is interrupted, the rollout is rolled back.
+func restartCanary(clientset kubernetes.Interface, namespace, deploymentName string) error {
+	logger.Info("canary restart", "namespace", namespace, "name", deploymentName)
+	strategy, err := startCanaryRollout(clientset, namespace, deploymentName)
+	if err != nil {
+		return err
+	}
+
+	// Until the new ReplicaSet exists the previous revision can't be told apart from the current
+	// one, so this short wait isn't cut by an interrupt; the readiness wait below is.
+	err = wait.PollUntilContextTimeout(context.Background(), 500*time.Millisecond, *canaryTimeout, true, func(ctx context.Context) (bool, error) {
+		return newReplicaSetCreated(ctx, clientset, namespace, deploymentName)
+	})
+	if err != nil {
+		err = fmt.Errorf("the controller didn't start the rollout of deployment %s: %w", deploymentName, err)
+		if restoreErr := endCanary(clientset, namespace, deploymentName, strategy, false); restoreErr != nil {
+			return errors.Join(err, restoreErr)
+		}
+		return err
+	}
+	err = setPaused(clientset, namespace, deploymentName, true)
+	if err == nil {
+		err = wait.PollUntilContextTimeout(interrupted, 2*time.Second, *canaryTimeout, true, func(ctx context.Context) (bool, error) {
+			return canaryReady(ctx, clientset, namespace, deploymentName)
+		})
+	}
+	if err != nil {
+		if isInterrupted() {
+			return abortCanary(clientset, namespace, deploymentName, strategy)
+		}
+		logger.Error("no canary pod became ready, rolling back", "namespace", namespace, "name", deploymentName, "timeout", *canaryTimeout, "err", err)
+		if rollbackErr := rollbackDeployment(clientset, namespace, deploymentName, strategy); rollbackErr != nil {
+			return rollbackErr
+		}
+		return fmt.Errorf("canary of deployment %s did not become ready: %w", deploymentName, err)
+	}
+
+	logger.Info("canary pod is ready and the rollout is paused", "namespace", namespace, "name", deploymentName)
+	resume, err := canaryDecision(deploymentName)
+	if err != nil {
+		if isInterrupted() {
+			return abortCanary(clientset, namespace, deploymentName, strategy)
+		}
+		// Nothing is rolled back unasked; the rollout stays paused with its own strategy.
+		if restoreErr := endCanary(clientset, namespace, deploymentName, strategy, true); restoreErr != nil {
+			return errors.Join(err, restoreErr)
+		}
+		return err
+	}
+	if resume {
+		if err := endCanary(clientset, namespace, deploymentName, strategy, false); err != nil {
+			return err
+		}
+		logger.Info("rollout resumed", "namespace", namespace, "name", deploymentName)
+		return nil
+	}
+
+	if err := rollbackDeployment(clientset, namespace, deploymentName, strategy); err != nil {
+		return err
+	}
+	logger.Info("rolled back to the previous revision", "namespace", namespace, "name", deploymentName)
+	return nil
+}
+
+// abortCanary rolls back the canary of an interrupted run, so Ctrl-C never leaves a deployment
+// paused halfway through its rollout.
+func abortCanary(clientset kubernetes.Interface, namespace, deploymentName string, strategy appsv1.DeploymentStrategy) error {
+	logger.Warn("interrupted, rolling back the canary", "namespace", namespace, "name", deploymentName)
+	if err := rollbackDeployment(clientset, namespace, deploymentName, strategy); err != nil {
+		return withExitCode(exitInterrupted, fmt.Errorf("interrupted, and rolling back the canary failed: %w", err))
+	}
+	return withExitCode(exitInterrupted, fmt.Errorf("interrupted: canary of deployment %s rolled back", deploymentName))
+}
+
+// validateCanaryDecision checks -canary-decision. Runs started with -action can't be asked, so
+// -canary needs the decision up front there.
+func validateCanaryDecision() error {
+	switch *canaryAnswer {
+	case "", "resume", "rollback":
+	default:
+		return fmt.Errorf("-canary-decision must be resume or rollback, got %q", *canaryAnswer)
+	}
+	if *canary && !*dryRun && nonInteractive() && *canaryAnswer == "" {
+		return fmt.Errorf("-canary with -action needs -canary-decision=resume or -canary-decision=rollback")
+	}
+	return nil
+}
+
+// canaryDecision returns whether the paused canary rollout of the deployment is resumed, taken from
+// -canary-decision or asked for. Only R and B are accepted; when stdin is closed before either
+// is given the rollout stays paused and an error is returned, so nothing is rolled back unasked.
+// An interrupt stops the question with an error too (see readAnswer).
+func canaryDecision(deploymentName string) (bool, error) {
+	switch *canaryAnswer {
+	case "resume":
+		return true, nil
+	case "rollback":
+		return false, nil
+	}
+	for {
+		fmt.Print("Resume the rollout (R) or roll back (B)? ")
+		input, err := readAnswer()
+		switch strings.TrimSpace(strings.ToUpper(input)) {
+		case "R":
+			return true, nil
+		case "B":
+			return false, nil
+		}
+		if isInterrupted() {
+			return false, err
+		}
+		if err != nil {
+			return false, fmt.Errorf("no answer for the canary of deployment %s, its rollout stays paused (resume it with kubectl rollout resume): %w", deploymentName, err)
+		}
+		fmt.Println("Please answer R or B.")
+	}
+}
+
+// readAnswer reads a line from stdinReader, giving up when the action is interrupted.
+func readAnswer() (string, error) {
+	type answer struct {
+		line string
+		err  error
+	}
+	answers := make(chan answer, 1)
+	go func() {
+		line, err := stdinReader.ReadString('\n')
+		answers <- answer{line, err}
+	}()
+	select {
+	case answer := <-answers:
+		return answer.line, answer.err
+	case <-interrupted.Done():
+		return "", interrupted.Err()
+	}
+}
+
+// newReplicaSetCreated reports whether the controller has observed the latest spec of the
+// deployment, i.e. created the ReplicaSet of the new revision.
+func newReplicaSetCreated(ctx context.Context, clientset kubernetes.Interface, namespace, deploymentName string) (bool, error) {
+	deploy, err := clientset.AppsV1().Deployments(namespace).Get(ctx, deploymentName, metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+	return deploy.Status.ObservedGeneration >= deploy.Generation, nil
+}
+
+// canaryReady reports whether the ReplicaSet of the deployment's current revision has a ready pod.
+func canaryReady(ctx context.Context, clientset kubernetes.Interface, namespace, deploymentName string) (bool, error) {
+	deploy, err := clientset.AppsV1().Deployments(namespace).Get(ctx, deploymentName, metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+	if deploy.Status.ObservedGeneration < deploy.Generation {
+		return false, nil // The controller hasn't created the new ReplicaSet yet.
+	}
+
+	replicaSets, err := ownedReplicaSets(ctx, clientset, deploy)
+	if err != nil {
+		return false, err
+	}
+	for _, rs := range replicaSets {
+		if rs.Annotations[revisionAnnotation] == deploy.Annotations[revisionAnnotation] {
+			return rs.Status.ReadyReplicas >= 1, nil
+		}
+	}
+	return false, nil
+}
+
+// ownedReplicaSets lists the ReplicaSets controlled by the deployment, newest revision first.
+func ownedReplicaSets(ctx context.Context, clientset kubernetes.Interface, deploy *appsv1.Deployment) ([]appsv1.ReplicaSet, error) {
+	selector, err := metav1.LabelSelectorAsSelector(deploy.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector on deployment %s: %w", deploy.Name, err)
+	}
+
+	list, err := clientset.AppsV1().ReplicaSets(deploy.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ReplicaSets of deployment %s: %w", deploy.Name, err)
+	}
+
+	var owned []appsv1.ReplicaSet
+	for _, rs := range list.Items {
+		if owner := metav1.GetControllerOf(&rs); owner != nil && owner.UID == deploy.UID {
+			owned = append(owned, rs)
+		}
+	}
+	sort.Slice(owned, func(i, j int) bool { return replicaSetRevision(owned[i]) > replicaSetRevision(owned[j]) })
+	return owned, nil
+}
+
+func replicaSetRevision(rs appsv1.ReplicaSet) int64 {
+	revision, _ := strconv.ParseInt(rs.Annotations[revisionAnnotation], 10, 64)
+	return revision
+}
+
+// rollbackDeployment restores the pod template of the previous revision and resumes the deployment,
+// which is what kubectl rollout undo does, and puts back the strategy the canary replaced.
+func rollbackDeployment(clientset kubernetes.Interface, namespace, deploymentName string, strategy appsv1.DeploymentStrategy) error {
+	ctx, cancel := apiContext()
+	defer cancel()
+
+	deploy, err := clientset.AppsV1().Deployments(namespace).Get(ctx, deploymentName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get deployment %s: %w", deploymentName, err)
+	}
+
+	replicaSets, err := ownedReplicaSets(ctx, clientset, deploy)
+	if err != nil {
+		return err
+	}
+	current, _ := strconv.ParseInt(deploy.Annotations[revisionAnnotation], 10, 64)
+
+	for _, rs := range replicaSets {
+		if replicaSetRevision(rs) >= current {
+			continue
+		}
+
+		template := *rs.Spec.Template.DeepCopy()
+		delete(template.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+		deploy.Spec.Template = template
+		deploy.Spec.Strategy = strategy
+		deploy.Spec.Paused = false
+
+		if _, err := clientset.AppsV1().Deployments(namespace).Update(ctx, deploy, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to roll back deployment %s: %w", deploymentName, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("no previous revision found for deployment %s", deploymentName)
+}
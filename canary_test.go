@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCanaryDecision(t *testing.T) {
+	tests := []struct {
+		flag, input string
+		want        bool
+		wantErr     bool
+	}{
+		{"", "r\n", true, false},
+		{"", "B\n", false, false},
+		{"", "maybe\nR\n", true, false},
+		{"", "", false, true},      // closed stdin leaves the rollout paused
+		{"", "maybe", false, true}, // so does an invalid last answer
+		{"rollback", "R\n", false, false},
+		{"resume", "", true, false},
+	}
+
+	savedReader, savedAnswer := stdinReader, *canaryAnswer
+	defer func() { stdinReader, *canaryAnswer = savedReader, savedAnswer }()
+	for _, tt := range tests {
+		stdinReader = bufio.NewReader(strings.NewReader(tt.input))
+		*canaryAnswer = tt.flag
+		got, err := canaryDecision("web")
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("canaryDecision() with -canary-decision=%q and input %q = %v, %v; want %v, error %v", tt.flag, tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateCanaryDecision(t *testing.T) {
+	savedAction, savedCanary, savedAnswer := *actionName, *canary, *canaryAnswer
+	defer func() { *actionName, *canary, *canaryAnswer = savedAction, savedCanary, savedAnswer }()
+
+	*actionName, *canary, *canaryAnswer = "restart", true, ""
+	if err := validateCanaryDecision(); err == nil {
+		t.Error("validateCanaryDecision() accepted -action=restart -canary without -canary-decision")
+	}
+	*canaryAnswer = "resume"
+	if err := validateCanaryDecision(); err != nil {
+		t.Errorf("validateCanaryDecision() = %v with -canary-decision=resume", err)
+	}
+	*canaryAnswer = "yes"
+	if err := validateCanaryDecision(); err == nil {
+		t.Error("validateCanaryDecision() accepted -canary-decision=yes")
+	}
+}
+
+func TestCanaryDecisionInterrupted(t *testing.T) {
+	savedReader, savedAnswer, savedInterrupted := stdinReader, *canaryAnswer, interrupted
+	defer func() { stdinReader, *canaryAnswer, interrupted = savedReader, savedAnswer, savedInterrupted }()
+
+	// Stdin that never answers, as when the operator presses Ctrl-C at the question.
+	pending, writer := io.Pipe()
+	defer writer.Close()
+	stdinReader = bufio.NewReader(pending)
+	*canaryAnswer = ""
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	interrupted = ctx
+
+	if _, err := canaryDecision("web"); err == nil || !isInterrupted() {
+		t.Errorf("canaryDecision() = %v after an interrupt, want an error", err)
+	}
+}
+
+func TestCanaryRolloutSwapsStrategy(t *testing.T) {
+	maxSurge := intstr.FromString("25%")
+	original := appsv1.DeploymentStrategy{
+		Type:          appsv1.RollingUpdateDeploymentStrategyType,
+		RollingUpdate: &appsv1.RollingUpdateDeployment{MaxSurge: &maxSurge, MaxUnavailable: &maxSurge},
+	}
+	clientset := fake.NewSimpleClientset(&appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+		Spec:       appsv1.DeploymentSpec{Strategy: original},
+	})
+	get := func() *appsv1.Deployment {
+		deploy, err := clientset.AppsV1().Deployments("shop").Get(context.TODO(), "web", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("get deployment: %v", err)
+		}
+		return deploy
+	}
+
+	replaced, err := startCanaryRollout(clientset, "shop", "web")
+	if err != nil {
+		t.Fatalf("startCanaryRollout: %v", err)
+	}
+	if !reflect.DeepEqual(replaced, original) {
+		t.Errorf("startCanaryRollout() returned %+v, want the original strategy", replaced)
+	}
+	// The restart and the one-pod strategy land in the same update.
+	deploy := get()
+	if !reflect.DeepEqual(deploy.Spec.Strategy, canaryStrategy()) || deploy.Spec.Template.Annotations[restartedAtAnnotation] == "" {
+		t.Errorf("deployment after startCanaryRollout = strategy %+v, annotations %v; want the canary strategy and a restart", deploy.Spec.Strategy, deploy.Spec.Template.Annotations)
+	}
+
+	if err := endCanary(clientset, "shop", "web", replaced, true); err != nil {
+		t.Fatalf("endCanary: %v", err)
+	}
+	if deploy := get(); !reflect.DeepEqual(deploy.Spec.Strategy, original) || !deploy.Spec.Paused {
+		t.Errorf("deployment after endCanary = strategy %+v, paused %v; want the original strategy, paused", deploy.Spec.Strategy, deploy.Spec.Paused)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// validateMaxReplicasAdjustment checks the -max-replicas-multiplier and -max-replicas-cap flags.
+func validateMaxReplicasAdjustment() error {
+	if *maxReplicasMultiplier <= 0 {
+		return fmt.Errorf("-max-replicas-multiplier must be greater than 0, got %g", *maxReplicasMultiplier)
+	}
+	if *maxReplicasCap < 0 {
+		return fmt.Errorf("-max-replicas-cap must not be negative, got %d", *maxReplicasCap)
+	}
+	return nil
+}
+
+// adjustMaxReplicas applies the global multiplier (rounded up) and then the cluster-wide cap to
+// the maxReplicas parsed from the CSV, never going below the row's minReplicas. Every change is
+// logged next to the original CSV value.
+func adjustMaxReplicas(row *patchRow) {
+	adjusted := row.MaxReplicas
+	if *maxReplicasMultiplier != 1 {
+		adjusted = int(math.Ceil(float64(adjusted) * *maxReplicasMultiplier))
+	}
+	if *maxReplicasCap > 0 && adjusted > *maxReplicasCap {
+		adjusted = *maxReplicasCap
+	}
+	if adjusted < row.MinReplicas {
+		logger.Warn("maxReplicas cap is below minReplicas, using minReplicas", "name", row.DeploymentName, "minReplicas", row.MinReplicas)
+		adjusted = row.MinReplicas
+	}
+
+	if adjusted != row.MaxReplicas {
+		logger.Info("maxReplicas adjusted", "name", row.DeploymentName, "csv", row.MaxReplicas, "adjusted", adjusted)
+		row.MaxReplicas = adjusted
+	}
+}
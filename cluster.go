@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"k8s.io/client-go/rest"
+)
+
+// currentCluster resolves the cluster name and API server URL the context in use (the active
+// -context or the kubeconfig's current context) points at.
+func currentCluster() (contextName, clusterName, server string, err error) {
+	if useInCluster() {
+		config, err := rest.InClusterConfig()
+		if err != nil {
+			return "", "", "", fmt.Errorf("failed to load in-cluster config: %w", err)
+		}
+		return inClusterName, inClusterName, config.Host, nil
+	}
+
+	config, err := rawKubeconfig()
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+
+	contextName = selectedContext(config)
+	contextConfig, exists := config.Contexts[contextName]
+	if !exists {
+		return "", "", "", fmt.Errorf("context %s not found in kubeconfig", contextName)
+	}
+
+	clusterName = contextConfig.Cluster
+	if cluster, exists := config.Clusters[clusterName]; exists {
+		server = cluster.Server
+	}
+	return contextName, clusterName, server, nil
+}
+
+// verifyExpectedCluster refuses to proceed unless the current context's cluster name or API
+// server URL equals expected.
+func verifyExpectedCluster(expected string) error {
+	contextName, clusterName, server, err := currentCluster()
+	if err != nil {
+		return err
+	}
+
+	if expected == clusterName || expected == server {
+		return nil
+	}
+	return fmt.Errorf("cluster mismatch: expected %q but context %q points at cluster %q (server %s)", expected, contextName, clusterName, server)
+}
+
+// clusterTarget is a cluster the run acts on, shown before anything runs.
+type clusterTarget struct {
+	Context, Cluster, Server, Namespace string
+}
+
+// runTargets resolves the cluster of every -context, or of the context in use without -context.
+func runTargets() ([]clusterTarget, error) {
+	contexts := []string(contextNames)
+	if len(contexts) == 0 {
+		contexts = []string{activeContext}
+	}
+	previous := activeContext
+	defer func() { activeContext = previous }()
+
+	var targets []clusterTarget
+	for _, name := range contexts {
+		activeContext = name
+		contextName, clusterName, server, err := currentCluster()
+		if err != nil {
+			return nil, err
+		}
+		namespace := "all namespaces"
+		if !*allNamespaces {
+			if namespace, err = getActiveNamespace(); err != nil {
+				return nil, err
+			}
+		}
+		targets = append(targets, clusterTarget{Context: contextName, Cluster: clusterName, Server: server, Namespace: namespace})
+	}
+	return targets, nil
+}
+
+// printTargets shows the context, cluster, API server and namespace the run is about to act on,
+// so the operator notices a wrong (e.g. production) context before answering the prompt.
+func printTargets() {
+	targets, err := runTargets()
+	if err != nil {
+		fmt.Printf("\n\nTarget cluster: unknown (%v)", err)
+		return
+	}
+	for _, target := range targets {
+		fmt.Printf("\n\nContext:    %s\nCluster:    %s (%s)\nNamespace:  %s", target.Context, target.Cluster, target.Server, target.Namespace)
+	}
+}
+
+// confirmClusterPrompt asks the operator to type the name of every target cluster before the
+// patch, restart and restore actions change it. A wrong or empty answer declines.
+func confirmClusterPrompt(action string) (bool, error) {
+	targets, err := runTargets()
+	if err != nil {
+		return false, err
+	}
+	for _, target := range targets {
+		fmt.Printf("\nThe %s action changes cluster %s (%s). Type the cluster name to confirm: ", action, target.Cluster, target.Server)
+		input, _ := stdinReader.ReadString('\n')
+		if input = strings.TrimSpace(input); input != target.Cluster {
+			logger.Warn("the cluster name doesn't match", "typed", input, "cluster", target.Cluster)
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// verifyConfirmedClusters checks -confirm-cluster before the actions that change the cluster: every
+// target cluster must be named, so a script run against the wrong context aborts before changing
+// anything. Without -confirm-cluster it only fails when -cluster-name-guard requires it.
+func verifyConfirmedClusters() error {
+	if len(confirmClusters) == 0 {
+		if *clusterNameGuard {
+			return fmt.Errorf("-cluster-name-guard is set: pass -confirm-cluster=<cluster name> to patch, restart or restore")
+		}
+		return nil
+	}
+	targets, err := runTargets()
+	if err != nil {
+		return err
+	}
+	for _, target := range targets {
+		if !slices.Contains(confirmClusters, target.Cluster) {
+			return fmt.Errorf("cluster mismatch: -confirm-cluster=%s but context %q points at cluster %q (server %s)", confirmClusters.String(), target.Context, target.Cluster, target.Server)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// validateConcurrency rejects a worker count that would process nothing.
+func validateConcurrency() error {
+	if *concurrency < 1 {
+		return fmt.Errorf("-concurrency must be at least 1, got %d", *concurrency)
+	}
+	return nil
+}
+
+// forEachConcurrently calls fn for every index in [0, count) on at most workers goroutines and
+// returns once all calls are done. fn must only write state owned by its index.
+func forEachConcurrently(workers, count int, fn func(i int)) {
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < count; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < count; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+// sortDeploymentInfo orders the rows by context, namespace, name and kind so the generated files don't
+// depend on listing or scheduling order.
+func sortDeploymentInfo(data []DeploymentInfo) {
+	sort.SliceStable(data, func(i, j int) bool {
+		a, b := data[i], data[j]
+		if a.Context != b.Context {
+			return a.Context < b.Context
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Kind < b.Kind
+	})
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestForEachConcurrentlyBoundsWorkers(t *testing.T) {
+	var running, peak int32
+	seen := make([]bool, 50)
+	forEachConcurrently(3, len(seen), func(i int) {
+		now := atomic.AddInt32(&running, 1)
+		for {
+			old := atomic.LoadInt32(&peak)
+			if now <= old || atomic.CompareAndSwapInt32(&peak, old, now) {
+				break
+			}
+		}
+		seen[i] = true
+		atomic.AddInt32(&running, -1)
+	})
+
+	for i, ok := range seen {
+		if !ok {
+			t.Errorf("index %d was not processed", i)
+		}
+	}
+	if peak > 3 {
+		t.Errorf("%d calls ran at once, want at most 3", peak)
+	}
+}
+
+func TestSortDeploymentInfo(t *testing.T) {
+	data := []DeploymentInfo{
+		{Namespace: "shop", Name: "web", Kind: kindStatefulSet},
+		{Namespace: "api", Name: "web", Kind: kindDeployment},
+		{Namespace: "shop", Name: "cart", Kind: kindDeployment},
+		{Namespace: "shop", Name: "web", Kind: kindDeployment},
+	}
+	sortDeploymentInfo(data)
+
+	want := []string{"api/web/Deployment", "shop/cart/Deployment", "shop/web/Deployment", "shop/web/StatefulSet"}
+	for i, deploy := range data {
+		if got := deploy.Namespace + "/" + deploy.Name + "/" + deploy.Kind; got != want[i] {
+			t.Errorf("row %d = %s, want %s", i, got, want[i])
+		}
+	}
+}
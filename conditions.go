@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+)
+
+// failingCondition returns "Reason: message" of the most relevant failing deployment condition,
+// or "" when the deployment reports no failure. ReplicaFailure is the most specific signal,
+// followed by a stalled rollout (Progressing=False) and finally Available=False.
+func failingCondition(conditions []appsv1.DeploymentCondition) string {
+	priority := []struct {
+		conditionType appsv1.DeploymentConditionType
+		failing       v1.ConditionStatus
+	}{
+		{appsv1.DeploymentReplicaFailure, v1.ConditionTrue},
+		{appsv1.DeploymentProgressing, v1.ConditionFalse},
+		{appsv1.DeploymentAvailable, v1.ConditionFalse},
+	}
+
+	for _, candidate := range priority {
+		for _, condition := range conditions {
+			if condition.Type == candidate.conditionType && condition.Status == candidate.failing {
+				if condition.Message == "" {
+					return condition.Reason
+				}
+				return fmt.Sprintf("%s: %s", condition.Reason, condition.Message)
+			}
+		}
+	}
+	return ""
+}
+
+// conditionsSummary renders the Conditions column. Unlike the Replica Issue column it is filled in
+// even when all replicas are available, so a rollout stuck on a new ReplicaSet (e.g.
+// "ProgressDeadlineExceeded" or "FailedCreate: exceeded quota") is visible right after a patch.
+func conditionsSummary(conditions []appsv1.DeploymentCondition) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	if failing := failingCondition(conditions); failing != "" {
+		return failing
+	}
+	return "Healthy"
+}
+
+// missingReplicas is how many desired pods are not available.
+func (d DeploymentInfo) missingReplicas() int32 {
+	if missing := d.Replicas - d.AvailableReplicas; missing > 0 {
+		return missing
+	}
+	return 0
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// stringList is a flag that can be repeated and also accepts comma-separated values.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// activeContext is the kubeconfig context the client-go and kubectl calls currently talk to, ""
+// for the kubeconfig's current context. Runs over several -context values switch it between
+// contexts, never while a context's workers are running.
+var activeContext string
+
+// validateContexts checks that every -context exists in the kubeconfig and makes the first one
+// active, so single-context runs need nothing else.
+func validateContexts() error {
+	if len(contextNames) == 0 {
+		return nil
+	}
+	if *inCluster {
+		return fmt.Errorf("-context selects kubeconfig contexts and can't be combined with -in-cluster")
+	}
+	config, err := rawKubeconfig()
+	if err != nil {
+		return fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	for _, name := range contextNames {
+		if _, ok := config.Contexts[name]; !ok {
+			known := make([]string, 0, len(config.Contexts))
+			for context := range config.Contexts {
+				known = append(known, context)
+			}
+			sort.Strings(known)
+			return fmt.Errorf("unknown -context %q (kubeconfig contexts: %v)", name, known)
+		}
+	}
+	activeContext = contextNames[0]
+	return nil
+}
+
+// multipleContexts reports whether the run covers more than one -context.
+func multipleContexts() bool {
+	return len(contextNames) > 1
+}
+
+// selectedContext returns the name of the context in use: the active -context, or the
+// kubeconfig's current context.
+func selectedContext(config clientcmdapi.Config) string {
+	if activeContext != "" {
+		return activeContext
+	}
+	return config.CurrentContext
+}
+
+// rowContexts groups the rows by the context recorded in their Context column, in sorted order.
+// Rows without one are patched through the active context.
+func rowContexts(rows []patchRow) ([]string, map[string][]patchRow) {
+	groups := make(map[string][]patchRow)
+	for _, row := range rows {
+		context := row.Context
+		if context == "" {
+			context = activeContext
+		}
+		groups[context] = append(groups[context], row)
+	}
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, groups
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const twoClusterKubeconfig = `apiVersion: v1
+kind: Config
+current-context: staging
+clusters:
+- name: eks-staging
+  cluster: {server: "https://staging.example.com"}
+- name: eks-prod
+  cluster: {server: "https://prod.example.com"}
+contexts:
+- name: staging
+  context: {cluster: eks-staging, user: ci, namespace: shop}
+- name: prod
+  context: {cluster: eks-prod, user: ci}
+users:
+- name: ci
+  user: {token: test}
+`
+
+func TestContextSelectsCluster(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte(twoClusterKubeconfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	savedKubeconfig, savedContexts, savedActive := *kubeconfig, contextNames, activeContext
+	defer func() { *kubeconfig, contextNames, activeContext = savedKubeconfig, savedContexts, savedActive }()
+	*kubeconfig = path
+
+	contextNames = stringList{"prod", "staging"}
+	if err := validateContexts(); err != nil {
+		t.Fatalf("validateContexts() = %v", err)
+	}
+	if _, cluster, _, _ := currentCluster(); cluster != "eks-prod" {
+		t.Errorf("cluster = %q, want eks-prod for the first -context", cluster)
+	}
+	if args := strings.Join(kubectlCommand("get", "pods").Args, " "); !strings.Contains(args, "--context=prod") {
+		t.Errorf("kubectl args = %q, want --context=prod", args)
+	}
+
+	contextNames = stringList{"dev"}
+	if err := validateContexts(); err == nil || !strings.Contains(err.Error(), `unknown -context "dev"`) {
+		t.Errorf("validateContexts() = %v, want an unknown context error", err)
+	}
+}
+
+func TestNamespaceFrom(t *testing.T) {
+	config := clientcmdapi.Config{
+		CurrentContext: "staging",
+		Clusters:       map[string]*clientcmdapi.Cluster{"eks": {Server: "https://eks.example.com"}},
+		AuthInfos:      map[string]*clientcmdapi.AuthInfo{"ci": {Token: "test"}},
+		Contexts: map[string]*clientcmdapi.Context{
+			"staging": {Cluster: "eks", AuthInfo: "ci", Namespace: "shop"},
+			"prod":    {Cluster: "eks", AuthInfo: "ci"},
+		},
+	}
+	savedOverride := *namespaceOverride
+	defer func() { *namespaceOverride = savedOverride }()
+	*namespaceOverride = ""
+
+	for _, tt := range []struct{ context, want string }{{"", "shop"}, {"prod", "default"}} {
+		clientConfig := clientcmd.NewDefaultClientConfig(config, &clientcmd.ConfigOverrides{CurrentContext: tt.context})
+		if namespace, err := namespaceFrom(clientConfig); err != nil || namespace != tt.want {
+			t.Errorf("namespaceFrom(context %q) = %q, %v; want %q", tt.context, namespace, err, tt.want)
+		}
+	}
+	clientConfig := clientcmd.NewDefaultClientConfig(config, &clientcmd.ConfigOverrides{CurrentContext: "dev"})
+	if _, err := namespaceFrom(clientConfig); err == nil {
+		t.Error("namespaceFrom() with an unknown context returned no error")
+	}
+
+	*namespaceOverride = "jobs"
+	if namespace, err := namespaceFrom(clientcmd.NewDefaultClientConfig(config, nil)); err != nil || namespace != "jobs" {
+		t.Errorf("namespaceFrom() with -namespace = %q, %v; want jobs", namespace, err)
+	}
+}
+
+func TestGetKubeClientReturnsErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte(twoClusterKubeconfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	savedKubeconfig, savedActive, savedOverride := *kubeconfig, activeContext, *namespaceOverride
+	defer func() { *kubeconfig, activeContext, *namespaceOverride = savedKubeconfig, savedActive, savedOverride }()
+	*kubeconfig, *namespaceOverride = path, ""
+
+	activeContext = ""
+	if clientset, namespace, err := getKubeClient(); err != nil || clientset == nil || namespace != "shop" {
+		t.Errorf("getKubeClient() = %v, %q, %v; want a clientset for namespace shop", clientset, namespace, err)
+	}
+	activeContext = "dev"
+	if _, _, err := getKubeClient(); err == nil || exitCode(err) != exitConnectivity {
+		t.Errorf("getKubeClient() with an unknown context = %v (exit code %d), want exit code %d", err, exitCode(err), exitConnectivity)
+	}
+}
+
+func TestRowContextsGroupsRows(t *testing.T) {
+	savedActive := activeContext
+	defer func() { activeContext = savedActive }()
+	activeContext = "staging"
+
+	names, groups := rowContexts([]patchRow{{DeploymentName: "web", Context: "prod"}, {DeploymentName: "api"}, {DeploymentName: "cart", Context: "prod"}})
+	if strings.Join(names, ",") != "prod,staging" || len(groups["prod"]) != 2 || len(groups["staging"]) != 1 {
+		t.Errorf("rowContexts() = %v, %v; want prod (2 rows) and staging (1 row)", names, groups)
+	}
+}
+
+func TestConfirmClusterPrompt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte(twoClusterKubeconfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	savedKubeconfig, savedContexts, savedActive, savedReader := *kubeconfig, contextNames, activeContext, stdinReader
+	defer func() {
+		*kubeconfig, contextNames, activeContext, stdinReader = savedKubeconfig, savedContexts, savedActive, savedReader
+	}()
+	*kubeconfig, contextNames, activeContext = path, nil, ""
+
+	targets, err := runTargets()
+	if err != nil || len(targets) != 1 || targets[0] != (clusterTarget{Context: "staging", Cluster: "eks-staging", Server: "https://staging.example.com", Namespace: "shop"}) {
+		t.Fatalf("runTargets() = %+v, %v, want the staging context", targets, err)
+	}
+
+	tests := []struct {
+		contexts stringList
+		input    string
+		want     bool
+	}{
+		{nil, "eks-staging\n", true},
+		{nil, "staging\n", false}, // the context name isn't the cluster name
+		{nil, "\n", false},
+		{stringList{"staging", "prod"}, "eks-staging\neks-prod\n", true},
+		{stringList{"staging", "prod"}, "eks-staging\neks-staging\n", false},
+	}
+	for _, tt := range tests {
+		contextNames = tt.contexts
+		stdinReader = bufio.NewReader(strings.NewReader(tt.input))
+		if got, err := confirmClusterPrompt("patch"); got != tt.want || err != nil {
+			t.Errorf("confirmClusterPrompt() with contexts %v and %q = %v, %v; want %v", tt.contexts, tt.input, got, err, tt.want)
+		}
+	}
+}
+
+func TestVerifyConfirmedClusters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte(twoClusterKubeconfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	savedKubeconfig, savedContexts, savedActive, savedConfirm, savedGuard := *kubeconfig, contextNames, activeContext, confirmClusters, *clusterNameGuard
+	defer func() {
+		*kubeconfig, contextNames, activeContext, confirmClusters, *clusterNameGuard = savedKubeconfig, savedContexts, savedActive, savedConfirm, savedGuard
+	}()
+	*kubeconfig, activeContext = path, ""
+
+	tests := []struct {
+		guard    bool
+		contexts stringList
+		confirm  stringList
+		wantErr  string
+	}{
+		{false, nil, nil, ""},
+		{true, nil, nil, "-cluster-name-guard is set"},
+		{true, nil, stringList{"eks-staging"}, ""},
+		{false, nil, stringList{"eks-prod"}, `points at cluster "eks-staging"`},
+		{true, stringList{"staging", "prod"}, stringList{"eks-prod", "eks-staging"}, ""},
+		{true, stringList{"staging", "prod"}, stringList{"eks-staging"}, `points at cluster "eks-prod"`},
+	}
+	for _, tt := range tests {
+		*clusterNameGuard, contextNames, confirmClusters = tt.guard, tt.contexts, tt.confirm
+		err := verifyConfirmedClusters()
+		if (tt.wantErr == "") != (err == nil) || (err != nil && !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("verifyConfirmedClusters() with guard %v, contexts %v, -confirm-cluster %v = %v, want %q", tt.guard, tt.contexts, tt.confirm, err, tt.wantErr)
+		}
+	}
+}
+
+func TestClusterChangingActions(t *testing.T) {
+	for name, action := range menuActions {
+		_, guarded := clusterChangingActions[action]
+		want := name == "patch" || name == "restart" || name == "restore"
+		if guarded != want {
+			t.Errorf("-action=%s guarded by the cluster confirmation = %v, want %v", name, guarded, want)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+)
+
+// customColumnEnabled reports whether both the custom column header and its command were supplied.
+func customColumnEnabled() bool {
+	return *customColumnName != "" && strings.TrimSpace(*customColumnCmd) != ""
+}
+
+// runCustomColumn executes the custom column command for a single deployment and returns its
+// trimmed stdout. Any failure (non-zero exit, timeout, missing binary) yields a blank cell.
+func runCustomColumn(deploymentName, namespace string) string {
+	args := strings.Fields(*customColumnCmd)
+	for i, arg := range args {
+		arg = strings.ReplaceAll(arg, "{name}", deploymentName)
+		args[i] = strings.ReplaceAll(arg, "{namespace}", namespace)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *customColumnTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, args[0], args[1:]...).Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		logger.Warn("custom column command failed", "column", *customColumnName, "namespace", namespace, "name", deploymentName, "err", err)
+		return ""
+	}
+
+	// Collapse multi-line output so the cell stays on a single CSV row.
+	return strings.Join(strings.Fields(string(output)), " ")
+}
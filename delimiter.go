@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// csvDelimiter returns the field separator of the CSV files the tool writes, from -delimiter.
+// "\t" and "tab" both mean a tab. The value must have been checked by validateDelimiter.
+func csvDelimiter() rune {
+	switch *delimiter {
+	case `\t`, "tab":
+		return '\t'
+	}
+	r, _ := utf8.DecodeRuneInString(*delimiter)
+	return r
+}
+
+// validateDelimiter rejects separators encoding/csv can't write unambiguously.
+func validateDelimiter() error {
+	if *delimiter != `\t` && *delimiter != "tab" && utf8.RuneCountInString(*delimiter) != 1 {
+		return fmt.Errorf("-delimiter must be a single character, got %q", *delimiter)
+	}
+	if r := csvDelimiter(); r == '"' || r == '\r' || r == '\n' || r == utf8.RuneError {
+		return fmt.Errorf("-delimiter %q can't be used as a CSV separator", *delimiter)
+	}
+	return nil
+}
+
+// sniffDelimiter detects the separator of a generated CSV from its header, whose first column is
+// always "No", so a file written with another -delimiter is still read correctly. It falls back to
+// -delimiter when the header doesn't start with "No".
+func sniffDelimiter(reader *bufio.Reader) (rune, error) {
+	line, err := reader.Peek(64)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return 0, err
+	}
+	if rest, ok := strings.CutPrefix(string(line), "No"); ok && rest != "" {
+		r, _ := utf8.DecodeRuneInString(rest)
+		if r != '\r' && r != '\n' {
+			return r, nil
+		}
+	}
+	return csvDelimiter(), nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestSniffDelimiter(t *testing.T) {
+	for _, tc := range []struct {
+		header string
+		want   rune
+	}{
+		{"No|Deployment Name|Namespace\n", '|'},
+		{"No,Deployment Name,Namespace\n", ','},
+		{"No\tDeployment Name\tNamespace\n", '\t'},
+		{"Name;Namespace\n", '|'}, // not a generated file, falls back to -delimiter
+	} {
+		got, err := sniffDelimiter(bufio.NewReader(strings.NewReader(tc.header)))
+		if err != nil {
+			t.Fatalf("sniffDelimiter(%q): %v", tc.header, err)
+		}
+		if got != tc.want {
+			t.Errorf("sniffDelimiter(%q) = %q, want %q", tc.header, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+)
+
+// defaultRollingUpdateValue is what the API server defaults maxSurge and maxUnavailable to.
+const defaultRollingUpdateValue = "25%"
+
+// rolloutImpact estimates what restarting a deployment would do.
+type rolloutImpact struct {
+	Deployment     string
+	PodsRecreated  int
+	MaxSurge       int
+	MaxUnavailable int
+	Waves          int
+	Duration       time.Duration
+	PDBName        string
+}
+
+// estimateRolloutImpact computes the impact of a restart from the gathered deployment data. Every
+// pod is recreated; a rolling update replaces up to maxSurge+maxUnavailable pods per wave, while
+// Recreate takes all pods down at once. Each wave is assumed to take -pod-ready-estimate plus the
+// deployment's minReadySeconds.
+func estimateRolloutImpact(deploy DeploymentInfo, podReady time.Duration) rolloutImpact {
+	replicas := int(deploy.Replicas)
+	impact := rolloutImpact{Deployment: deploy.Name, PodsRecreated: replicas, PDBName: deploy.PDBName}
+	if replicas == 0 {
+		return impact
+	}
+	perWave := podReady + time.Duration(deploy.MinReadySeconds)*time.Second
+
+	if deploy.Strategy == "Recreate" {
+		impact.MaxUnavailable = replicas
+		impact.Waves = 1
+		impact.Duration = perWave
+		return impact
+	}
+
+	impact.MaxSurge = scaledRollingValue(deploy.MaxSurge, replicas, true)
+	impact.MaxUnavailable = scaledRollingValue(deploy.MaxUnavailable, replicas, false)
+	if impact.MaxSurge == 0 && impact.MaxUnavailable == 0 {
+		impact.MaxUnavailable = 1 // The deployment controller never lets a rollout stall on 0/0.
+	}
+
+	impact.Waves = int(math.Ceil(float64(replicas) / float64(impact.MaxSurge+impact.MaxUnavailable)))
+	impact.Duration = time.Duration(impact.Waves) * perWave
+	return impact
+}
+
+// scaledRollingValue resolves a maxSurge (rounded up) or maxUnavailable (rounded down) value
+// against the replica count, applying the API server default when it is unset.
+func scaledRollingValue(value string, replicas int, roundUp bool) int {
+	if value == "" {
+		value = defaultRollingUpdateValue
+	}
+	scaled, err := intstr.GetScaledValueFromIntOrPercent(ptrIntOrString(value), replicas, roundUp)
+	if err != nil {
+		return 0
+	}
+	return scaled
+}
+
+func ptrIntOrString(value string) *intstr.IntOrString {
+	parsed := intstr.Parse(value)
+	return &parsed
+}
+
+// restartDryRun reports the impact a restart of target would have without touching the cluster.
+func restartDryRun(target string) error {
+	clientset, namespace, err := getKubeClient()
+	if err != nil {
+		return err
+	}
+	data, err := restartDryRunRows(clientset, namespace, target)
+	if err != nil {
+		return err
+	}
+
+	if target == "all" {
+		fmt.Printf("\n🔍 Dry run: restarting all deployments in namespace %s would\n", namespace)
+	} else {
+		fmt.Printf("\n🔍 Dry run: restarting deployment %s in namespace %s would\n", target, namespace)
+	}
+	header := []string{"Deployment", "Pods Recreated", "Max Surge", "Max Unavailable", "Waves", "Est. Duration", "PDB"}
+	totalPods := 0
+	printTable(header, func(row func([]string)) {
+		for _, deploy := range data {
+			impact := estimateRolloutImpact(deploy, *podReadyEstimate)
+			totalPods += impact.PodsRecreated
+
+			pdb := impact.PDBName
+			if pdb == "" {
+				pdb = "none"
+			}
+			row([]string{
+				impact.Deployment,
+				strconv.Itoa(impact.PodsRecreated),
+				strconv.Itoa(impact.MaxSurge),
+				strconv.Itoa(impact.MaxUnavailable),
+				strconv.Itoa(impact.Waves),
+				impact.Duration.String(),
+				pdb,
+			})
+		}
+	})
+
+	fmt.Printf("\n📋 %d pod(s) across %d deployment(s) would be recreated; no changes were made.\n", totalPods, len(data))
+	summary.Action, summary.Succeeded = "would restart", len(data)
+	summary.addNamespace(namespace)
+	fmt.Println("   Deployments guarded by a PDB roll normally, but node drains or evictions during the rollout are throttled by it.")
+	return nil
+}
+
+// restartDryRunRows builds the rows of the restart dry run from the same candidates a restart of
+// target acts on (see restartCandidates), so the preview lists exactly what would be restarted.
+// PDBs are only listed to name the guarding budget; a missing permission isn't fatal.
+func restartDryRunRows(clientset kubernetes.Interface, namespace, target string) ([]DeploymentInfo, error) {
+	candidates, err := restartCandidates(clientset, namespace, target)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := apiContext()
+	defer cancel()
+	var pdbList *policyv1.PodDisruptionBudgetList
+	err = retryTransient("list PodDisruptionBudgets", func() (err error) {
+		pdbList, err = clientset.PolicyV1().PodDisruptionBudgets(namespace).List(ctx, metav1.ListOptions{})
+		return err
+	})
+	if err != nil {
+		logger.Warn("failed to list PodDisruptionBudgets, PDB information will be missing", "err", err)
+		pdbList = &policyv1.PodDisruptionBudgetList{}
+	}
+
+	objects := workloadObjects{pdbs: pdbList.Items}
+	data := make([]DeploymentInfo, 0, len(candidates))
+	for _, deploy := range candidates {
+		data = append(data, objects.deploymentInfo(deploy))
+	}
+	return data, nil
+}
+
+// skipForDryRun reports whether a patch command printed just before must not be executed because
+// -dry-run is set.
+func skipForDryRun(out io.Writer) bool {
+	if *dryRun {
+		loggerTo(out).Info("dry run, command not executed")
+	}
+	return *dryRun
+}
+
+// dryRunAll returns the DryRun option for client-go writes: with -dry-run the API server validates
+// and admits the request without persisting it.
+func dryRunAll() []string {
+	if *dryRun {
+		return []string{metav1.DryRunAll}
+	}
+	return nil
+}
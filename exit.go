@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"net"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Process exit codes. Scripts and CI jobs can rely on these to react to the outcome of an action.
+const (
+	exitOK             = 0   // the action completed successfully
+	exitPartialFailure = 1   // the action ran but some rows/deployments failed (also used for unclassified errors)
+	exitUsage          = 2   // invalid input: unknown action, bad flags, unreadable or malformed CSV
+	exitConnectivity   = 3   // the cluster could not be reached or the credentials were rejected
+	exitNothingToDo    = 4   // the action had nothing to act on
+	exitInterrupted    = 130 // Ctrl-C/SIGTERM stopped the action before it was done, like a shell reports SIGINT
+)
+
+// exitError attaches an exit code to an error returned by an action.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+// withExitCode wraps err so that main exits with the given code.
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: code, err: err}
+}
+
+// exitCode maps the outcome of an action to the process exit code. Errors without an explicit
+// code are classified as connectivity errors when they come from the API server rejecting or
+// failing to serve the request, and as failures otherwise.
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+
+	var coded *exitError
+	if errors.As(err, &coded) {
+		return coded.code
+	}
+	if isConnectivityError(err) {
+		return exitConnectivity
+	}
+	return exitPartialFailure
+}
+
+// isConnectivityError reports whether err means the cluster couldn't be reached or refused our credentials.
+func isConnectivityError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	return apierrors.IsUnauthorized(err) || apierrors.IsForbidden(err) ||
+		apierrors.IsServiceUnavailable(err) || apierrors.IsServerTimeout(err) || apierrors.IsTimeout(err)
+}
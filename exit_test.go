@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"success", nil, exitOK},
+		{"partial failure", withExitCode(exitPartialFailure, errors.New("2 of 5 deployment(s) failed to patch")), exitPartialFailure},
+		{"usage", withExitCode(exitUsage, errors.New("invalid action \"9\"")), exitUsage},
+		{"nothing to do", withExitCode(exitNothingToDo, errors.New("nothing to patch")), exitNothingToDo},
+		{"wrapped code", fmt.Errorf("patch: %w", withExitCode(exitNothingToDo, errors.New("nothing to patch"))), exitNothingToDo},
+		{"unauthorized", fmt.Errorf("failed to list deployments: %w", apierrors.NewUnauthorized("bad token")), exitConnectivity},
+		{"forbidden", apierrors.NewForbidden(schema.GroupResource{Resource: "deployments"}, "", errors.New("rbac")), exitConnectivity},
+		{"unreachable", fmt.Errorf("failed to list deployments: %w", &net.OpError{Op: "dial", Err: errors.New("connection refused")}), exitConnectivity},
+		{"unclassified", errors.New("boom"), exitPartialFailure},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// inTempDir runs the test from an empty temporary directory, where the actions look for their files.
+func inTempDir(t *testing.T) {
+	t.Helper()
+	previous, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(previous) })
+}
+
+func TestPatchExitCodeWithoutCSV(t *testing.T) {
+	inTempDir(t)
+
+	if got := exitCode(patchKubeResourcesFromCSV()); got != exitUsage {
+		t.Errorf("exit code = %d, want %d", got, exitUsage)
+	}
+}
+
+func TestPatchExitCodeWithNothingToDo(t *testing.T) {
+	inTempDir(t)
+
+	csv := "No|Deployment Name|Namespace|Replicas|CPU Request|CPU Limit|Memory Request|Memory Limit|MaxUnavailable|MaxSurge|Min Replicas|Max Replicas|CPU Target Utilization|ScaleUp Stabilization|ScaleDown Stabilization|UpdateResourceAndHPA|UpdateHPAOnly\n" +
+		"1|web|default|2|100m|0m|128Mi|256Mi|25%|25%|2|5|80|0|300|false|false\n"
+	if err := os.WriteFile("deployment-info.csv", []byte(csv), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := exitCode(patchKubeResourcesFromCSV()); got != exitNothingToDo {
+		t.Errorf("exit code = %d, want %d", got, exitNothingToDo)
+	}
+}
+
+func TestPatchExitCodeWithMalformedCSV(t *testing.T) {
+	inTempDir(t)
+
+	csv := "No|Deployment Name|Namespace\n1|web|default|extra\n"
+	if err := os.WriteFile("deployment-info.csv", []byte(csv), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := exitCode(patchKubeResourcesFromCSV()); got != exitUsage {
+		t.Errorf("exit code = %d, want %d", got, exitUsage)
+	}
+}
+
+func TestPatchRefusesUnparsableReplicas(t *testing.T) {
+	inTempDir(t)
+
+	csv := "No|Deployment Name|Namespace|Replicas|CPU Request|CPU Limit|Memory Request|Memory Limit|MaxUnavailable|MaxSurge|Min Replicas|Max Replicas|CPU Target Utilization|ScaleUp Stabilization|ScaleDown Stabilization|UpdateResourceAndHPA|UpdateHPAOnly\n" +
+		"1|web|default|2|100m|0m|128Mi|256Mi|25%|25%|two|5|80|N/A|300|false|true\n" +
+		"2|api|default|2|100m|0m|128Mi|256Mi|25%|25%|0|5|80|0|300|false|true\n"
+	if err := os.WriteFile("deployment-info.csv", []byte(csv), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both rows are refused before the cluster is contacted.
+	if got := exitCode(patchKubeResourcesFromCSV()); got != exitPartialFailure {
+		t.Errorf("exit code = %d, want %d", got, exitPartialFailure)
+	}
+	if summary.Failed != 2 {
+		t.Errorf("failed rows = %d, want 2", summary.Failed)
+	}
+}
+
+func TestConfirmPromptRejectsInvalidAnswers(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    bool
+		wantErr bool
+	}{
+		{"y\n", true, false},
+		{"N\n", false, false},
+		{"", false, false},
+		{"maybe\n", false, true},
+	}
+
+	saved := stdinReader
+	defer func() { stdinReader = saved }()
+	for _, tt := range tests {
+		stdinReader = bufio.NewReader(strings.NewReader(tt.input))
+		got, err := confirmPrompt()
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("confirmPrompt() with %q = %v, %v; want %v, error %v", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestPatchReportsMissingInput(t *testing.T) {
+	inTempDir(t)
+	saved := *input
+	defer func() { *input = saved }()
+	*input = "reports/deploy-shop.csv"
+
+	err := patchKubeResourcesFromCSV()
+	if got := exitCode(err); got != exitUsage {
+		t.Errorf("exit code = %d, want %d", got, exitUsage)
+	}
+	if err == nil || !strings.Contains(err.Error(), "reports/deploy-shop.csv not found") {
+		t.Errorf("error = %v, want it to name the missing -input file", err)
+	}
+}
+
+func TestHandleInterruptsCancelsOnSignal(t *testing.T) {
+	stop := handleInterrupts()
+	defer stop()
+	if isInterrupted() {
+		t.Fatal("isInterrupted() = true before any signal")
+	}
+	if err := syscall.Kill(os.Getpid(), syscall.SIGINT); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-interrupted.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("SIGINT did not cancel the interrupted context")
+	}
+}
+
+func TestReadPatchRowsMapsColumnsByName(t *testing.T) {
+	inTempDir(t)
+	// Reordered columns, without the optional ones.
+	header := "Namespace|Deployment Name|UpdateHPAOnly|UpdateResourceAndHPA|Max Replicas|Min Replicas|CPU Target Utilization|" +
+		"ScaleUp Stabilization|ScaleDown Stabilization|Replicas|CPU Request|CPU Limit|Memory Request|Memory Limit|MaxUnavailable|MaxSurge\n"
+	row := "shop|web|true|false|10|2|70|N/A|300|2|100m|200m|128Mi|256Mi|25%|25%\n"
+	if err := os.WriteFile("deployment-info.csv", []byte(header+row), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := readPatchRows()
+	if err != nil || len(rows) != 1 {
+		t.Fatalf("readPatchRows() = %+v, %v, want one row", rows, err)
+	}
+	if got := rows[0]; got.Namespace != "shop" || got.DeploymentName != "web" || !got.UpdateHPAOnly || got.MinReplicas != 2 || got.MaxReplicas != 10 || got.CPURequest != "100m" {
+		t.Errorf("row = %+v, want the values of their named columns", got)
+	}
+	if got := rows[0]; got.ScaleUpStabilization != nil || got.ScaleDownStabilization == nil || *got.ScaleDownStabilization != 300 {
+		t.Errorf("stabilization windows = %v, %v; want nil for N/A and 300", got.ScaleUpStabilization, got.ScaleDownStabilization)
+	}
+
+	if err := os.WriteFile("deployment-info.csv", []byte(strings.Replace(header, "Max Replicas", "Maximum", 1)+row), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readPatchRows(); err == nil || !strings.Contains(err.Error(), `"Max Replicas"`) {
+		t.Errorf("readPatchRows() error = %v, want it to name the missing Max Replicas column", err)
+	}
+}
+
+func TestReadPatchRowsFromStdin(t *testing.T) {
+	savedInput, savedReader := *input, stdinReader
+	defer func() { *input, stdinReader = savedInput, savedReader }()
+	*input = stdinPath
+	// Semicolons, as written with -delimiter=";" or by a spreadsheet in a comma-decimal locale.
+	header := "No;Deployment Name;Namespace;Replicas;CPU Request;CPU Limit;Memory Request;Memory Limit;MaxUnavailable;MaxSurge;" +
+		"Min Replicas;Max Replicas;CPU Target Utilization;ScaleUp Stabilization;ScaleDown Stabilization;UpdateResourceAndHPA;UpdateHPAOnly\n"
+	stdinReader = bufio.NewReader(strings.NewReader(header + "1;web;shop;2;100m;0m;128Mi;0Mi;25%;25%;2;10;70;N/A;300;true;false\n"))
+
+	rows, err := readPatchRows()
+	if err != nil || len(rows) != 1 {
+		t.Fatalf("readPatchRows() = %+v, %v, want one row", rows, err)
+	}
+	if got := rows[0]; got.DeploymentName != "web" || got.Namespace != "shop" || got.MaxReplicas != 10 || !got.UpdateResourceAndHPA {
+		t.Errorf("row = %+v, want the values read from stdin", got)
+	}
+}
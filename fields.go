@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// patchFieldNames are the values -fields accepts, each naming the row columns it covers.
+var patchFieldNames = []string{
+	"cpu",               // CPU Request, CPU Limit
+	"memory",            // Memory Request, Memory Limit
+	"ephemeral-storage", // Ephemeral Storage Request, Ephemeral Storage Limit
+	"rollout",           // Max Unavailable, Max Surge
+	"replicas",          // Replicas of workloads without an HPA
+	"min-replicas",      // Min Replicas
+	"max-replicas",      // Max Replicas
+	"cpu-target",        // CPU Target Utilization
+	"memory-target",     // Memory Target Utilization
+	"behavior",          // the scale up and down stabilization windows and policies
+	"pdb",               // PDB Min Available, PDB Max Unavailable
+}
+
+// validateFields rejects -fields values the patch action doesn't know.
+func validateFields() error {
+	for _, field := range patchFields {
+		if !slices.Contains(patchFieldNames, field) {
+			return fmt.Errorf("unknown -fields value %q, valid: %s", field, strings.Join(patchFieldNames, ", "))
+		}
+	}
+	return nil
+}
+
+// restrictToFields replaces the columns of the row -fields doesn't name with the live values of the
+// workload, its HPA and its PDB, so the rest of the patch path only sees a difference, and only
+// changes, in the named fields. Without -fields the row is left as is.
+func restrictToFields(clientset kubernetes.Interface, row *patchRow) error {
+	if len(patchFields) == 0 {
+		return nil
+	}
+	live, err := liveRow(clientset, *row)
+	if err != nil {
+		return err
+	}
+	selected := func(field string) bool { return slices.Contains(patchFields, field) }
+
+	if !selected("cpu") {
+		row.CPURequest, row.CPULimit = live.CPURequest, live.CPULimit
+	}
+	if !selected("memory") {
+		row.MemoryRequest, row.MemoryLimit = live.MemoryRequest, live.MemoryLimit
+	}
+	if !selected("ephemeral-storage") {
+		row.EphemeralStorageRequest, row.EphemeralStorageLimit = live.EphemeralStorageRequest, live.EphemeralStorageLimit
+	}
+	for i, container := range row.Containers {
+		index := slices.IndexFunc(live.Containers, func(c ContainerResources) bool { return c.Name == container.Name })
+		if index < 0 {
+			continue // Reported as an unknown container when the resources are set.
+		}
+		liveContainer := live.Containers[index]
+		if !selected("cpu") {
+			row.Containers[i].CPURequest, row.Containers[i].CPULimit = liveContainer.CPURequest, liveContainer.CPULimit
+		}
+		if !selected("memory") {
+			row.Containers[i].MemoryRequest, row.Containers[i].MemoryLimit = liveContainer.MemoryRequest, liveContainer.MemoryLimit
+		}
+		if !selected("ephemeral-storage") {
+			row.Containers[i].EphemeralStorageRequest, row.Containers[i].EphemeralStorageLimit = liveContainer.EphemeralStorageRequest, liveContainer.EphemeralStorageLimit
+		}
+	}
+	if !selected("rollout") {
+		row.MaxUnavailable, row.MaxSurge = live.MaxUnavailable, live.MaxSurge
+	}
+	if !selected("replicas") {
+		row.Replicas = live.Replicas
+	}
+	if !selected("min-replicas") {
+		row.MinReplicas = live.MinReplicas
+	}
+	if !selected("max-replicas") {
+		row.MaxReplicas = live.MaxReplicas
+	}
+	if !selected("cpu-target") {
+		row.CPUTargetUtilization = live.CPUTargetUtilization
+	}
+	if !selected("memory-target") {
+		row.MemoryTargetUtilization = live.MemoryTargetUtilization
+	}
+	if !selected("behavior") {
+		row.ScaleUpStabilization, row.ScaleDownStabilization = live.ScaleUpStabilization, live.ScaleDownStabilization
+		row.ScaleUpPolicies, row.ScaleDownPolicies = live.ScaleUpPolicies, live.ScaleDownPolicies
+	}
+	if !selected("pdb") {
+		row.PDBMinAvailable, row.PDBMaxUnavailable = live.PDBMinAvailable, live.PDBMaxUnavailable
+	}
+	return nil
+}
+
+// liveRow reads the row's workload with its HPA and PDB and converts it into a patch row, the way
+// the generate action would have written it now.
+func liveRow(clientset kubernetes.Interface, row patchRow) (patchRow, error) {
+	ctx, cancel := apiContext()
+	defer cancel()
+
+	var hpas []autoscalingv2.HorizontalPodAutoscaler
+	err := retryTransient("get HPA "+row.DeploymentName, func() error {
+		hpa, err := getHPA(ctx, clientset, row.Namespace, row.DeploymentName)
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		hpas = []autoscalingv2.HorizontalPodAutoscaler{*hpa}
+		return nil
+	})
+	if err != nil {
+		return patchRow{}, fmt.Errorf("failed to get HPA %s: %w", row.DeploymentName, err)
+	}
+	var pdbList *policyv1.PodDisruptionBudgetList
+	err = retryTransient("list PodDisruptionBudgets", func() (err error) {
+		pdbList, err = clientset.PolicyV1().PodDisruptionBudgets(row.Namespace).List(ctx, metav1.ListOptions{})
+		return err
+	})
+	if err != nil {
+		return patchRow{}, fmt.Errorf("failed to list PodDisruptionBudgets: %w", err)
+	}
+	objects := workloadObjects{hpas: indexHPAs(hpas), pdbs: pdbList.Items}
+
+	var info DeploymentInfo
+	err = retryTransient("get "+kubectlKind(row.Kind)+" "+row.DeploymentName, func() error {
+		switch row.Kind {
+		case kindStatefulSet:
+			sts, err := clientset.AppsV1().StatefulSets(row.Namespace).Get(ctx, row.DeploymentName, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			info = objects.statefulSetInfo(*sts)
+		case kindDaemonSet:
+			ds, err := clientset.AppsV1().DaemonSets(row.Namespace).Get(ctx, row.DeploymentName, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			info = objects.daemonSetInfo(*ds)
+		default:
+			deploy, err := clientset.AppsV1().Deployments(row.Namespace).Get(ctx, row.DeploymentName, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			info = objects.deploymentInfo(*deploy)
+		}
+		return nil
+	})
+	if err != nil {
+		return patchRow{}, fmt.Errorf("failed to get %s %s: %w", kubectlKind(row.Kind), row.DeploymentName, err)
+	}
+	return patchRowFromInfo(info), nil
+}
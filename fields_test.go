@@ -0,0 +1,180 @@
+package main
+
+import (
+	"io"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRestrictToFields(t *testing.T) {
+	minReplicas := int32(2)
+	clientset := fake.NewSimpleClientset(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "prod"},
+			Spec: appsv1.DeploymentSpec{Template: v1.PodTemplateSpec{Spec: v1.PodSpec{Containers: []v1.Container{{
+				Name: "api",
+				Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("250m"), v1.ResourceMemory: resource.MustParse("256Mi")},
+					Limits:   v1.ResourceList{v1.ResourceCPU: resource.MustParse("500m"), v1.ResourceMemory: resource.MustParse("512Mi")},
+				},
+			}}}}},
+		},
+		&autoscalingv2.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "prod"},
+			Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: kindDeployment, Name: "api"},
+				MinReplicas:    &minReplicas,
+				MaxReplicas:    5,
+			},
+		},
+	)
+	csvRow := patchRow{
+		Kind: kindDeployment, Namespace: "prod", DeploymentName: "api",
+		CPURequest: "1", CPULimit: "2", MemoryRequest: "1Gi", MemoryLimit: "2Gi",
+		MinReplicas: 3, MaxReplicas: 10, UpdateResourceAndHPA: true,
+	}
+
+	old := patchFields
+	defer func() { patchFields = old }()
+
+	patchFields = nil
+	row := csvRow
+	if err := restrictToFields(clientset, &row); err != nil || row.CPURequest != "1" || row.MaxReplicas != 10 {
+		t.Errorf("restrictToFields() without -fields changed the row: %+v, %v", row, err)
+	}
+
+	patchFields = stringList{"max-replicas"}
+	row = csvRow
+	if err := restrictToFields(clientset, &row); err != nil {
+		t.Fatalf("restrictToFields() = %v", err)
+	}
+	if row.MaxReplicas != 10 {
+		t.Errorf("MaxReplicas = %d, want the CSV's 10", row.MaxReplicas)
+	}
+	if row.MinReplicas != 2 || row.CPURequest != "250m" || row.CPULimit != "500m" || row.MemoryRequest != "256Mi" || row.MemoryLimit != "512Mi" {
+		t.Errorf("fields not named kept CSV values: min %d, cpu %s/%s, memory %s/%s; want the live 2, 250m/500m, 256Mi/512Mi",
+			row.MinReplicas, row.CPURequest, row.CPULimit, row.MemoryRequest, row.MemoryLimit)
+	}
+
+	patchFields = stringList{"cpu", "maxreplicas"}
+	if err := validateFields(); err == nil {
+		t.Error("validateFields() accepted an unknown field")
+	}
+}
+
+func TestFieldsRowsAreSkippedOnResume(t *testing.T) {
+	inTempDir(t)
+	minReplicas := int32(2)
+	clientset := fake.NewSimpleClientset(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "prod"}},
+		&autoscalingv2.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "prod"},
+			Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: kindDeployment, Name: "api"},
+				MinReplicas:    &minReplicas,
+				MaxReplicas:    10,
+				Metrics:        []autoscalingv2.MetricSpec{utilizationMetric(v1.ResourceCPU, 70)},
+			},
+		},
+	)
+	// Only Max Replicas is applied, and the HPA already has it: the other cells differ from the
+	// cluster but are replaced by the live values.
+	csvRow := patchRow{
+		Kind: kindDeployment, Namespace: "prod", DeploymentName: "api",
+		CPURequest: "1", MemoryRequest: "1Gi", MinReplicas: 3, MaxReplicas: 10, CPUTargetUtilization: 50, UpdateHPAOnly: true,
+	}
+
+	old := patchFields
+	defer func() { patchFields = old }()
+	patchFields = stringList{"max-replicas"}
+
+	state := &patchState{Applied: make(map[string]string)}
+	run := &patchRun{clientset: clientset, metrics: &metricsPreflight{clientset: clientset}, limitRanges: newLimitRangeChecker(clientset), state: state}
+	if outcome := run.apply(io.Discard, csvRow); outcome != rowUpToDate {
+		t.Fatalf("apply() = %v, want rowUpToDate", outcome)
+	}
+
+	// The check of a resumed run compares the state with the row as read from the file.
+	resumed, err := loadPatchState(*stateFile)
+	if err != nil {
+		t.Fatalf("loadPatchState() = %v", err)
+	}
+	if checksum, ok := resumed.Applied[rowKey(csvRow)]; !ok || checksum != rowChecksum(csvRow) {
+		t.Errorf("recorded checksum = %q, %v; want the checksum of the CSV row so -resume skips it", checksum, ok)
+	}
+
+	patchFields = stringList{"max-replicas", "cpu"}
+	if resumed.Applied[rowKey(csvRow)] == rowChecksum(csvRow) {
+		t.Error("a resumed run with other -fields would skip the row")
+	}
+}
+
+func TestWideRowsAreSkippedOnResume(t *testing.T) {
+	inTempDir(t)
+	quantities := func(cpu, memory string) v1.ResourceList {
+		return v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu), v1.ResourceMemory: resource.MustParse(memory)}
+	}
+	clientset := fake.NewSimpleClientset(
+		&appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "prod"},
+			Spec: appsv1.StatefulSetSpec{Template: v1.PodTemplateSpec{Spec: v1.PodSpec{Containers: []v1.Container{{
+				Name:      "db",
+				Resources: v1.ResourceRequirements{Requests: quantities("500m", "128Mi"), Limits: quantities("500m", "128Mi")},
+			}}}}},
+		},
+		&v1.LimitRange{
+			ObjectMeta: metav1.ObjectMeta{Name: "limits", Namespace: "prod"},
+			Spec: v1.LimitRangeSpec{Limits: []v1.LimitRangeItem{{
+				Type: v1.LimitTypeContainer,
+				Max:  v1.ResourceList{v1.ResourceCPU: resource.MustParse("500m")},
+			}}},
+		},
+	)
+
+	oldFields, oldClamp := patchFields, *clampToLimitRange
+	defer func() { patchFields, *clampToLimitRange = oldFields, oldClamp }()
+
+	tests := []struct {
+		name   string
+		fields stringList
+		clamp  bool
+	}{
+		// The container cells are replaced by the live values.
+		{"fields", stringList{"max-replicas"}, false},
+		// The CPU cells are clamped to the LimitRange maximum, which the StatefulSet already has.
+		{"clamp", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patchFields, *clampToLimitRange = tt.fields, tt.clamp
+			csvRow := patchRow{
+				Kind: kindStatefulSet, Namespace: "prod", DeploymentName: "db", UpdateResourceAndHPA: true,
+				Containers: []ContainerResources{{Name: "db", CPURequest: "1", CPULimit: "1", MemoryRequest: "128Mi", MemoryLimit: "128Mi"}},
+			}
+			want := rowChecksum(csvRow)
+
+			state := &patchState{Applied: make(map[string]string)}
+			run := &patchRun{clientset: clientset, metrics: &metricsPreflight{clientset: clientset}, limitRanges: newLimitRangeChecker(clientset), state: state}
+			if outcome := run.apply(io.Discard, csvRow); outcome != rowUpToDate {
+				t.Fatalf("apply() = %v, want rowUpToDate", outcome)
+			}
+			if csvRow.Containers[0].CPURequest != "1" {
+				t.Errorf("apply() rewrote the CSV row's CPU request to %s", csvRow.Containers[0].CPURequest)
+			}
+
+			resumed, err := loadPatchState(*stateFile)
+			if err != nil {
+				t.Fatalf("loadPatchState() = %v", err)
+			}
+			if checksum := resumed.Applied[rowKey(csvRow)]; checksum != want {
+				t.Errorf("recorded checksum = %q, want the checksum of the CSV row so -resume skips it", checksum)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+// checkReplicaFighting flags HPA-managed deployments whose spec.replicas disagrees with the HPA.
+// The HPA rewrites spec.replicas on every sync, so a mismatch usually means something else (a
+// CI pipeline applying a manifest with replicas set, a manual scale, ...) keeps changing it and
+// the two are fighting.
+func checkReplicaFighting(deploy DeploymentInfo) *Finding {
+	if !deploy.HasHPA {
+		return nil
+	}
+	finding := &Finding{Namespace: deploy.Namespace, Deployment: deploy.Name, Check: "replica-fighting"}
+	switch {
+	case deploy.Replicas < deploy.MinReplicas || deploy.Replicas > deploy.MaxReplicas:
+		finding.Message = fmt.Sprintf("spec.replicas is %d, outside the HPA range %d-%d; something other than the HPA is setting replicas", deploy.Replicas, deploy.MinReplicas, deploy.MaxReplicas)
+	case deploy.HPADesiredReplicas > 0 && deploy.Replicas != deploy.HPADesiredReplicas:
+		finding.Message = fmt.Sprintf("spec.replicas is %d but the HPA wants %d; remove replicas from manifests applied to HPA-managed deployments", deploy.Replicas, deploy.HPADesiredReplicas)
+	default:
+		return nil
+	}
+	return finding
+}
+
+// warnReplicaEdit warns when the Replicas cell of an HPA-managed row was edited. The column is
+// never patched: scaling an HPA-managed deployment by hand only lasts until the next HPA sync,
+// so Min/Max Replicas are the values to change.
+func warnReplicaEdit(out io.Writer, clientset kubernetes.Interface, row patchRow) {
+	if row.Replicas == "" || row.MaxReplicas <= 0 {
+		return
+	}
+	replicas, err := strconv.Atoi(row.Replicas)
+	if err != nil {
+		return
+	}
+	live, err := getWorkload(clientset, row)
+	if err != nil || live.Replicas == nil || int(*live.Replicas) == replicas {
+		return
+	}
+	loggerTo(out).Warn("Replicas was changed in the CSV but is not applied: the HPA manages replicas, change Min/Max Replicas instead", "name", row.DeploymentName, "replicas", replicas)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+)
+
+// Finding is a read-only observation about a deployment reported by the linter.
+type Finding struct {
+	Namespace  string
+	Deployment string
+	Check      string
+	Message    string
+}
+
+// lintChecks are run against every deployment by lintDeployments. A check returns nil when the
+// deployment is fine.
+var lintChecks = []func(DeploymentInfo) *Finding{
+	checkSpotAvailability,
+	checkSecurityContext,
+	checkReplicaFighting,
+}
+
+// lintDeployments runs all lint checks over the gathered deployment data.
+func lintDeployments(data []DeploymentInfo) []Finding {
+	var findings []Finding
+	for _, deploy := range data {
+		for _, check := range lintChecks {
+			if finding := check(deploy); finding != nil {
+				findings = append(findings, *finding)
+			}
+		}
+	}
+	return findings
+}
+
+// writeFindings saves the findings into a CSV file and prints them.
+func writeFindings(findings []Finding, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create findings report: %w", err)
+	}
+	defer file.Close()
+
+	header := []string{"Namespace", "Deployment Name", "Check", "Finding"}
+	writer := csv.NewWriter(file)
+	writer.Comma = csvDelimiter()
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("failed to write findings header: %w", err)
+	}
+	for _, finding := range findings {
+		if err := writer.Write([]string{finding.Namespace, finding.Deployment, finding.Check, finding.Message}); err != nil {
+			return fmt.Errorf("failed to write finding: %w", err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write findings report: %w", err)
+	}
+
+	for _, finding := range findings {
+		logger.Warn(finding.Message, "check", finding.Check, "namespace", finding.Namespace, "name", finding.Deployment)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"flag"
+	"time"
+)
+
+// Command-line flags. Every flag is optional; without any the tool behaves
+// exactly like the interactive menu always has.
+var (
+	actionName = flag.String("action", "", "run this action without showing the menu: generate, patch, restart or restore")
+	assumeYes  = flag.Bool("yes", false, "skip the \"proceed with running the script?\" confirmation")
+
+	logLevelName = flag.String("log-level", "info", "minimum level of the log messages: debug, info, warn or error")
+	logFormat    = flag.String("log-format", "text", "format of the log messages: text or json")
+	quiet        = flag.Bool("quiet", false, "hide the progress spinner and log only warnings and errors, for pipelines")
+
+	summaryOnly = flag.Bool("summary-only", false, "print exactly one line describing the outcome to stdout; all other output goes to stderr")
+
+	inCluster  = flag.Bool("in-cluster", false, "use the service account of the pod the tool runs in instead of a kubeconfig (automatic in a pod unless -kubeconfig or -context is set)")
+	kubeconfig = flag.String("kubeconfig", "", "path to the kubeconfig file; defaults to $KUBECONFIG (colon-separated files are merged) and then $HOME/.kube/config")
+
+	namespaceOverride = flag.String("namespace", "", "namespace to generate and restart in instead of the current context's namespace (\"default\" when the context sets none)")
+
+	allNamespaces = flag.Bool("all-namespaces", false, "generate the inventory across every namespace instead of only the current context's namespace")
+
+	timeout = flag.Duration("timeout", 30*time.Second, "deadline of each Kubernetes API request and kubectl invocation")
+
+	maxRetries = flag.Int("max-retries", 3, "how often a Kubernetes API request failing transiently (throttled with 429, server unavailable, connection reset) is retried with exponential backoff; 0 disables retries")
+
+	qps   = flag.Float64("qps", 5, "maximum sustained rate of Kubernetes API requests and kubectl invocations per second")
+	burst = flag.Int("burst", 10, "number of API requests or kubectl invocations allowed in a burst above -qps")
+
+	concurrency = flag.Int("concurrency", 8, "number of workloads generated or rows patched in parallel")
+
+	expectCluster    = flag.String("expect-cluster", "", "refuse to run unless the current kubeconfig context points at this cluster (cluster name or API server URL)")
+	clusterNameGuard = flag.Bool("cluster-name-guard", false, "refuse to patch, restart or restore unless -confirm-cluster names the target cluster")
+
+	delimiter = flag.String("delimiter", "|", "single-character field separator of the CSV files written, e.g. \",\" for spreadsheets or \"\\t\"; the patch action detects the separator of the file it reads")
+
+	outputFormat = flag.String("format", "csv", "file format written by the generate action and read by the patch action: csv, json, grafana (flat JSON with numeric values) or markdown (GFM table)")
+	input        = flag.String("input", "", "file read by the patch action, - for stdin (default deployment-info.csv, or deployment-info.json with -format=json)")
+	output       = flag.String("output", "", "path of the file written by the generate action, - for stdout with -format=csv (default deployment-info.<format extension>); {namespace} or {context} write one file per namespace or context and {cluster} is replaced with the cluster name")
+
+	customColumnName    = flag.String("custom-column", "", "header of an extra column whose value is computed by -custom-column-cmd")
+	customColumnCmd     = flag.String("custom-column-cmd", "", "command template run per deployment; {name} and {namespace} are substituted, stdout becomes the cell value")
+	customColumnTimeout = flag.Duration("custom-column-timeout", 5*time.Second, "maximum time the -custom-column-cmd may run for a single deployment")
+
+	maskColumns  = flag.String("mask-columns", "", "comma-separated CSV column names whose values are replaced by \"***\" in the shared -masked-output file")
+	maskedOutput = flag.String("masked-output", "deployment-info.masked.csv", "path of the shareable CSV written when -mask-columns is set")
+
+	wide = flag.Bool("wide", false, "emit one group of resource columns per container instead of only the deployment aggregate")
+
+	dryRun           = flag.Bool("dry-run", false, "report what the restart would do (pods recreated, waves, estimated duration, PDBs), or print the patch commands and payloads, without changing the cluster")
+	podReadyEstimate = flag.Duration("pod-ready-estimate", 30*time.Second, "assumed time for a new pod to become ready, used by -dry-run to estimate rollout duration")
+
+	canary        = flag.Bool("canary", false, "restart deployments one at a time, pausing each rollout once one new pod is ready so it can be validated")
+	canaryTimeout = flag.Duration("canary-timeout", 5*time.Minute, "how long to wait for the canary pod to become ready before rolling back; the resume/rollback question itself waits until answered or interrupted")
+	canaryAnswer  = flag.String("canary-decision", "", "answer to the -canary question instead of asking: resume or rollback (required with -action and -canary)")
+
+	includeServices = flag.Bool("include-services", false, "add a column listing the Services whose selector matches each deployment's pods")
+	includeSecurity = flag.Bool("include-security", false, "add columns for whether the primary container runs as root, is privileged or allows privilege escalation")
+
+	stateFile = flag.String("state-file", "patch-state.json", "file recording which rows a patch run has applied")
+	resume    = flag.Bool("resume", false, "skip rows the state file records as already applied with the same values")
+
+	interactive = flag.Bool("interactive", false, "ask for confirmation (y/N) before patching each row, after showing the values it changes")
+
+	backupDir   = flag.String("backup-dir", "backups", "directory where the patch action snapshots each workload and HPA before changing them, read by the restore action")
+	restoreFrom = flag.String("restore-from", "", "backup file or glob restored by action 4; prompts when empty (required with -action=restore)")
+
+	clampToLimitRange = flag.Bool("clamp-to-limitrange", false, "move patched requests/limits into the range allowed by the namespace LimitRange instead of skipping violating rows")
+
+	checkResourceVersion = flag.Bool("check-resource-version", false, "refuse to patch a deployment/HPA whose resourceVersion changed since the CSV was generated")
+
+	strict = flag.Bool("strict", false, "abort the patch run (before anything is patched) when a row has requests above limits, min replicas above max replicas, or names a workload or HPA that doesn't exist, instead of skipping the row")
+
+	allowZeroMinReplicas = flag.Bool("allow-zero-min-replicas", false, "allow patching an HPA minReplicas to 0 (requires the HPAScaleToZero feature gate)")
+
+	maxReplicasMultiplier = flag.Float64("max-replicas-multiplier", 1, "multiply every patched HPA maxReplicas by this factor (rounded up), e.g. 1.2 for a sale event")
+	maxReplicasCap        = flag.Int("max-replicas-cap", 0, "cluster-wide ceiling for every patched HPA maxReplicas, applied after the multiplier (0 disables)")
+
+	labelSelector       = flag.String("selector", "", "label selector (e.g. app=frontend) limiting the workloads and HPAs listed when generating")
+	excludeSelectorFlag = flag.String("exclude-selector", "", "label selector (e.g. app.kubernetes.io/managed-by=addon-manager) of workloads to leave out when generating, matched against the workload's own labels")
+	skipOwned           = flag.Bool("skip-owned", false, "leave out workloads with owner references (managed by an operator or another controller) when generating")
+	nameFilterPattern   = flag.String("name-filter", "", "regular expression the workload names must match when generating, e.g. ^checkout-")
+
+	resourceType = flag.String("resource-type", "deployment", "comma-separated workload kinds listed when generating: deployment, statefulset, daemonset")
+
+	restartTarget = flag.String("deployment", "", "deployment restarted by action 3, or \"all\"; prompts when empty (required with -action=restart)")
+	since         = flag.Duration("since", 0, "restart action: skip deployments whose oldest pod started less than this long ago, e.g. 168h for a week; 0 restarts them all")
+
+	restartOrderAnnotation = flag.String("restart-order-annotation", "", "restart deployments in waves ordered by this integer annotation (lowest first), waiting for each wave to complete")
+	waveTimeout            = flag.Duration("wave-timeout", 10*time.Minute, "how long to wait for each deployment of a restart wave to finish rolling out")
+
+	configFile     = flag.String("config", "kubernetes-console.yaml", "config file defining named resource profiles; a missing file defines none")
+	defaultProfile = flag.String("profile", "", "profile applied when patching flagged rows whose Profile column is empty")
+
+	reconcileFile = flag.String("reconcile", "", "desired-state YAML to compare with the cluster when generating; the rows to patch are written to reconcile-plan.csv")
+
+	onlyWarningsFlag = flag.Bool("only-warnings", false, "only export the workloads with a non-empty Warnings column (missing CPU request, memory limit or HPA)")
+
+	noHPAFlag = flag.Bool("no-hpa", false, "only export the replicated workloads no HPA targets, to find the ones lacking autoscaling")
+
+	watch         = flag.Bool("watch", false, "keep the generate action running, re-listing the workloads every -watch-interval and redrawing them as a table in place (no files are written); stop with Ctrl-C")
+	watchInterval = flag.Duration("watch-interval", 5*time.Second, "how often -watch re-lists the workloads")
+
+	printTableFlag = flag.Bool("print-table", false, "also print the collected workloads as an aligned table with CPU/memory request totals to stdout when generating")
+
+	hpaReport = flag.Bool("hpa-report", false, "also write a per-namespace HPA coverage summary to hpa-coverage.csv when generating")
+
+	capacitySummary     = flag.Bool("capacity-summary", false, "also write per-namespace and total requests/limits multiplied by replicas to summary.csv when generating")
+	includeNodeCapacity = flag.Bool("include-node-capacity", false, "with -capacity-summary, also show the requests as a percentage of the CPU/memory allocatable on the cluster's nodes")
+
+	teamReport = flag.Bool("team-report", false, "also write per-team CPU/memory request subtotals to team-report.csv when generating")
+	teamLabel  = flag.String("team-label", "team", "deployment label (or annotation) holding the owning team for -team-report")
+
+	lint         = flag.Bool("lint", false, "also analyze the deployments and write the findings to deployment-findings.csv when generating")
+	spotNodeKeys = flag.String("spot-node-keys", defaultSpotNodeKeys, "comma-separated node label key or key=value pairs identifying spot/preemptible nodes")
+)
+
+func init() {
+	flag.StringVar(namespaceOverride, "n", "", "shorthand for -namespace")
+	flag.BoolVar(allNamespaces, "A", false, "shorthand for -all-namespaces")
+	flag.StringVar(output, "o", "", "shorthand for -output")
+	flag.Var(&confirmClusters, "confirm-cluster", "cluster name the patch, restart and restore actions are about to change; repeat (or comma-separate) for several -context clusters. Every target cluster must be listed, otherwise they abort")
+	flag.Var(&patchFields, "fields", "patch action: apply only these fields of each row and keep the live values of the others, e.g. cpu,memory or max-replicas (cpu, memory, ephemeral-storage, rollout, replicas, min-replicas, max-replicas, cpu-target, memory-target, behavior, pdb)")
+	flag.Var(&contextNames, "context", "kubeconfig context to use instead of the current one; repeat (or comma-separate) to generate or patch several clusters in one run")
+}
+
+// contextNames holds the -context values in the order given.
+var contextNames stringList
+
+// confirmClusters holds the -confirm-cluster values.
+var confirmClusters stringList
+
+// patchFields holds the -fields values, see patchFieldNames.
+var patchFields stringList
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// grafanaRow is one deployment in the Grafana snapshot: a flat object with numeric resource
+// values (millicores and MiB) instead of the display strings used in the CSV, so a table panel
+// fed by the JSON/Infinity datasource can sort, sum and threshold them directly.
+type grafanaRow struct {
+	Timestamp                     string `json:"timestamp"`
+	Namespace                     string `json:"namespace"`
+	Deployment                    string `json:"deployment"`
+	Replicas                      int32  `json:"replicas"`
+	ReadyReplicas                 int32  `json:"readyReplicas"`
+	AvailableReplicas             int32  `json:"availableReplicas"`
+	MissingReplicas               int32  `json:"missingReplicas"`
+	CPURequestMillicores          int64  `json:"cpuRequestMillicores"`
+	CPULimitMillicores            int64  `json:"cpuLimitMillicores"`
+	MemoryRequestMiB              int64  `json:"memoryRequestMiB"`
+	MemoryLimitMiB                int64  `json:"memoryLimitMiB"`
+	HasHPA                        bool   `json:"hasHPA"`
+	MinReplicas                   int32  `json:"minReplicas"`
+	MaxReplicas                   int32  `json:"maxReplicas"`
+	CPUTargetUtilization          int32  `json:"cpuTargetUtilization"`
+	ScaleUpStabilizationSeconds   *int32 `json:"scaleUpStabilizationSeconds"`
+	ScaleDownStabilizationSeconds *int32 `json:"scaleDownStabilizationSeconds"`
+}
+
+// writeGrafanaJSON saves the DeploymentInfo data as a flat JSON array for Grafana.
+func writeGrafanaJSON(data []DeploymentInfo, path string) error {
+	timestamp := time.Now().UTC().Format(time.RFC3339)
+	rows := make([]grafanaRow, 0, len(data))
+	for _, deploy := range data {
+		rows = append(rows, grafanaRow{
+			Timestamp:                     timestamp,
+			Namespace:                     deploy.Namespace,
+			Deployment:                    deploy.Name,
+			Replicas:                      deploy.Replicas,
+			ReadyReplicas:                 deploy.ReadyReplicas,
+			AvailableReplicas:             deploy.AvailableReplicas,
+			MissingReplicas:               deploy.missingReplicas(),
+			CPURequestMillicores:          milliCPU(deploy.CPURequest),
+			CPULimitMillicores:            milliCPU(deploy.CPULimit),
+			MemoryRequestMiB:              mebibytes(deploy.MemoryRequest),
+			MemoryLimitMiB:                mebibytes(deploy.MemoryLimit),
+			HasHPA:                        deploy.HasHPA,
+			MinReplicas:                   deploy.MinReplicas,
+			MaxReplicas:                   deploy.MaxReplicas,
+			CPUTargetUtilization:          deploy.CPUTargetUtilization,
+			ScaleUpStabilizationSeconds:   deploy.ScaleUpStabilization,
+			ScaleDownStabilizationSeconds: deploy.ScaleDownStabilization,
+		})
+	}
+
+	encoded, err := json.MarshalIndent(rows, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode Grafana snapshot: %w", err)
+	}
+	if err := os.WriteFile(path, encoded, 0o644); err != nil {
+		return fmt.Errorf("failed to write Grafana snapshot: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+)
+
+// scalingPolicies is the compact CSV encoding of the selectPolicy and policies of one HPA scaling
+// direction, e.g. {"selectPolicy":"Max","policies":[{"type":"Pods","value":4,"periodSeconds":15}]}.
+// The stabilization window keeps its own column.
+type scalingPolicies struct {
+	SelectPolicy *autoscalingv2.ScalingPolicySelect `json:"selectPolicy,omitempty"`
+	Policies     []autoscalingv2.HPAScalingPolicy   `json:"policies,omitempty"`
+}
+
+// encodeScalingPolicies renders the policies of the scaling rules for a CSV cell. Rules without a
+// selectPolicy or policies produce an empty cell.
+func encodeScalingPolicies(rules *autoscalingv2.HPAScalingRules) string {
+	if rules == nil || (rules.SelectPolicy == nil && len(rules.Policies) == 0) {
+		return ""
+	}
+
+	encoded, err := json.Marshal(scalingPolicies{SelectPolicy: rules.SelectPolicy, Policies: rules.Policies})
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+// decodeScalingPolicies parses a CSV cell written by encodeScalingPolicies (or edited by hand).
+func decodeScalingPolicies(cell string) (*scalingPolicies, error) {
+	var policies scalingPolicies
+	if err := json.Unmarshal([]byte(cell), &policies); err != nil {
+		return nil, fmt.Errorf("invalid scaling policies %q: %w", cell, err)
+	}
+
+	for _, policy := range policies.Policies {
+		if policy.Type != autoscalingv2.PodsScalingPolicy && policy.Type != autoscalingv2.PercentScalingPolicy {
+			return nil, fmt.Errorf("invalid scaling policy type %q (expected Pods or Percent)", policy.Type)
+		}
+		if policy.Value <= 0 || policy.PeriodSeconds <= 0 {
+			return nil, fmt.Errorf("scaling policy %s needs a positive value and periodSeconds", policy.Type)
+		}
+	}
+	return &policies, nil
+}
+
+// applyScalingRules sets the stabilization window of one scaling direction when the row has one
+// and, when the CSV cell is set, its selectPolicy and policies. A nil window (N/A) and an empty
+// cell keep what is tuned on the live HPA, or the controller defaults when it has no behavior.
+func applyScalingRules(rules **autoscalingv2.HPAScalingRules, stabilization *int, cell string) error {
+	if stabilization == nil && cell == "" {
+		return nil
+	}
+	if *rules == nil {
+		*rules = &autoscalingv2.HPAScalingRules{}
+	}
+	if stabilization != nil {
+		window := int32(*stabilization)
+		(*rules).StabilizationWindowSeconds = &window
+	}
+	if cell == "" {
+		return nil
+	}
+
+	policies, err := decodeScalingPolicies(cell)
+	if err != nil {
+		return err
+	}
+	(*rules).SelectPolicy = policies.SelectPolicy
+	(*rules).Policies = policies.Policies
+	return nil
+}
+
+// applyBehavior writes the behavior columns of a row into the HPA spec. An HPA without behavior
+// only gets one when the row sets a window or policies.
+func applyBehavior(spec *autoscalingv2.HorizontalPodAutoscalerSpec, scaleUpStabilization, scaleDownStabilization *int, scaleUpPolicies, scaleDownPolicies string) error {
+	if scaleUpStabilization == nil && scaleDownStabilization == nil && scaleUpPolicies == "" && scaleDownPolicies == "" {
+		return nil
+	}
+	if spec.Behavior == nil {
+		spec.Behavior = &autoscalingv2.HorizontalPodAutoscalerBehavior{}
+	}
+	if err := applyScalingRules(&spec.Behavior.ScaleUp, scaleUpStabilization, scaleUpPolicies); err != nil {
+		return fmt.Errorf("scaleUp: %w", err)
+	}
+	if err := applyScalingRules(&spec.Behavior.ScaleDown, scaleDownStabilization, scaleDownPolicies); err != nil {
+		return fmt.Errorf("scaleDown: %w", err)
+	}
+	return nil
+}
+
+// utilizationLabel renders the CPU utilization target of a row for the logs; 0 leaves it unchanged.
+func utilizationLabel(target int) string {
+	if target == 0 {
+		return "unchanged"
+	}
+	return fmt.Sprint(target)
+}
+
+// stabilizationLabel renders a stabilization window of a row for the logs.
+func stabilizationLabel(window *int) string {
+	if window == nil {
+		return "unchanged"
+	}
+	return fmt.Sprint(*window)
+}
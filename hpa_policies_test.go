@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"testing"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestBehaviorPoliciesRoundTrip(t *testing.T) {
+	scaleUpWindow := int32(0)
+	scaleDownWindow := int32(300)
+	maxPolicy := autoscalingv2.MaxChangePolicySelect
+	minPolicy := autoscalingv2.MinChangePolicySelect
+
+	original := autoscalingv2.HorizontalPodAutoscalerBehavior{
+		ScaleUp: &autoscalingv2.HPAScalingRules{
+			StabilizationWindowSeconds: &scaleUpWindow,
+			SelectPolicy:               &maxPolicy,
+			Policies: []autoscalingv2.HPAScalingPolicy{
+				{Type: autoscalingv2.PodsScalingPolicy, Value: 4, PeriodSeconds: 15},
+				{Type: autoscalingv2.PercentScalingPolicy, Value: 100, PeriodSeconds: 15},
+			},
+		},
+		ScaleDown: &autoscalingv2.HPAScalingRules{
+			StabilizationWindowSeconds: &scaleDownWindow,
+			SelectPolicy:               &minPolicy,
+			Policies: []autoscalingv2.HPAScalingPolicy{
+				{Type: autoscalingv2.PercentScalingPolicy, Value: 10, PeriodSeconds: 60},
+				{Type: autoscalingv2.PodsScalingPolicy, Value: 2, PeriodSeconds: 120},
+			},
+		},
+	}
+
+	// Export the behavior the way writeCSV does, then apply it to a new HPA the way patchHPA does.
+	var spec autoscalingv2.HorizontalPodAutoscalerSpec
+	scaleUp, scaleDown := int(*original.ScaleUp.StabilizationWindowSeconds), int(*original.ScaleDown.StabilizationWindowSeconds)
+	err := applyBehavior(&spec,
+		&scaleUp,
+		&scaleDown,
+		encodeScalingPolicies(original.ScaleUp),
+		encodeScalingPolicies(original.ScaleDown),
+	)
+	if err != nil {
+		t.Fatalf("applyBehavior: %v", err)
+	}
+
+	if !reflect.DeepEqual(original, *spec.Behavior) {
+		t.Errorf("behavior changed in round trip\noriginal: %+v\nrebuilt:  %+v", original, *spec.Behavior)
+	}
+}
+
+func TestApplyBehaviorEmptyPoliciesLeavesThemUntouched(t *testing.T) {
+	policies := []autoscalingv2.HPAScalingPolicy{{Type: autoscalingv2.PodsScalingPolicy, Value: 4, PeriodSeconds: 15}}
+	spec := autoscalingv2.HorizontalPodAutoscalerSpec{
+		Behavior: &autoscalingv2.HorizontalPodAutoscalerBehavior{
+			ScaleUp: &autoscalingv2.HPAScalingRules{Policies: policies},
+		},
+	}
+	scaleUp, scaleDown := 0, 300
+	if err := applyBehavior(&spec, &scaleUp, &scaleDown, "", ""); err != nil {
+		t.Fatalf("applyBehavior: %v", err)
+	}
+
+	if !reflect.DeepEqual(spec.Behavior.ScaleUp.Policies, policies) {
+		t.Errorf("scaleUp policies = %+v, want them untouched", spec.Behavior.ScaleUp.Policies)
+	}
+	if !int32Equals(spec.Behavior.ScaleDown.StabilizationWindowSeconds, 300) || spec.Behavior.ScaleDown.Policies != nil {
+		t.Errorf("scaleDown = %+v, want only the 300s window", spec.Behavior.ScaleDown)
+	}
+}
+
+func TestDecodeScalingPoliciesRejectsInvalidType(t *testing.T) {
+	if _, err := decodeScalingPolicies(`{"policies":[{"type":"Nodes","value":1,"periodSeconds":15}]}`); err == nil {
+		t.Error("expected an error for an unknown policy type")
+	}
+}
+
+func TestPatchHPAAppliesPoliciesPerDirection(t *testing.T) {
+	minReplicas := int32(1)
+	scaleUpPolicies := []autoscalingv2.HPAScalingPolicy{{Type: autoscalingv2.PodsScalingPolicy, Value: 4, PeriodSeconds: 15}}
+	scaleDownPolicies := []autoscalingv2.HPAScalingPolicy{{Type: autoscalingv2.PercentScalingPolicy, Value: 10, PeriodSeconds: 60}}
+	clientset := fake.NewSimpleClientset(&autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+			MinReplicas:    &minReplicas,
+			MaxReplicas:    5,
+			Metrics:        []autoscalingv2.MetricSpec{utilizationMetric(v1.ResourceCPU, 80)},
+			Behavior: &autoscalingv2.HorizontalPodAutoscalerBehavior{
+				ScaleUp:   &autoscalingv2.HPAScalingRules{Policies: scaleUpPolicies},
+				ScaleDown: &autoscalingv2.HPAScalingRules{Policies: scaleDownPolicies},
+			},
+		},
+	})
+	get := func() *autoscalingv2.HorizontalPodAutoscalerBehavior {
+		hpa, err := clientset.AutoscalingV2().HorizontalPodAutoscalers("shop").Get(context.TODO(), "web", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("get HPA: %v", err)
+		}
+		return hpa.Spec.Behavior
+	}
+
+	// Empty cells keep the live policies of both directions.
+	if err := patchHPA(io.Discard, clientset, "web", "shop", 1, 10, 80, nil, nil, nil, "", "", ""); err != nil {
+		t.Fatalf("patchHPA: %v", err)
+	}
+	if behavior := get(); !reflect.DeepEqual(behavior.ScaleUp.Policies, scaleUpPolicies) || !reflect.DeepEqual(behavior.ScaleDown.Policies, scaleDownPolicies) {
+		t.Errorf("policies after empty cells = %+v / %+v, want the live ones", behavior.ScaleUp.Policies, behavior.ScaleDown.Policies)
+	}
+
+	// A scale down cell only replaces the scale down policies.
+	cell := encodeScalingPolicies(&autoscalingv2.HPAScalingRules{
+		Policies: []autoscalingv2.HPAScalingPolicy{{Type: autoscalingv2.PodsScalingPolicy, Value: 1, PeriodSeconds: 120}},
+	})
+	if err := patchHPA(io.Discard, clientset, "web", "shop", 1, 10, 80, nil, nil, nil, "", cell, ""); err != nil {
+		t.Fatalf("patchHPA: %v", err)
+	}
+	behavior := get()
+	if !reflect.DeepEqual(behavior.ScaleUp.Policies, scaleUpPolicies) {
+		t.Errorf("scaleUp policies = %+v, want the live ones", behavior.ScaleUp.Policies)
+	}
+	if got := encodeScalingPolicies(behavior.ScaleDown); got != cell {
+		t.Errorf("scaleDown policies = %s, want %s", got, cell)
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func utilizationMetric(name v1.ResourceName, utilization int32) autoscalingv2.MetricSpec {
+	return autoscalingv2.MetricSpec{
+		Type: autoscalingv2.ResourceMetricSourceType,
+		Resource: &autoscalingv2.ResourceMetricSource{
+			Name:   name,
+			Target: autoscalingv2.MetricTarget{Type: autoscalingv2.UtilizationMetricType, AverageUtilization: &utilization},
+		},
+	}
+}
+
+func TestPatchHPAKeepsMemoryMetric(t *testing.T) {
+	minReplicas := int32(1)
+	clientset := fake.NewSimpleClientset(&autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+			MinReplicas:    &minReplicas,
+			MaxReplicas:    5,
+			Metrics: []autoscalingv2.MetricSpec{
+				utilizationMetric(v1.ResourceCPU, 80),
+				utilizationMetric(v1.ResourceMemory, 75),
+			},
+		},
+	})
+
+	scaleUp, scaleDown := 0, 300
+	if err := patchHPA(io.Discard, clientset, "web", "shop", 2, 10, 60, nil, &scaleUp, &scaleDown, "", "", ""); err != nil {
+		t.Fatalf("patchHPA: %v", err)
+	}
+
+	hpa, err := clientset.AutoscalingV2().HorizontalPodAutoscalers("shop").Get(context.TODO(), "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get HPA: %v", err)
+	}
+	if *hpa.Spec.MinReplicas != 2 || hpa.Spec.MaxReplicas != 10 {
+		t.Errorf("replicas = %d-%d, want 2-10", *hpa.Spec.MinReplicas, hpa.Spec.MaxReplicas)
+	}
+	if len(hpa.Spec.Metrics) != 2 {
+		t.Fatalf("metrics = %+v, want CPU and memory", hpa.Spec.Metrics)
+	}
+	if !utilizationTargetEquals(hpa.Spec.Metrics, v1.ResourceCPU, 60) {
+		t.Errorf("CPU target not updated to 60: %+v", hpa.Spec.Metrics[0].Resource.Target)
+	}
+	memory := hpa.Spec.Metrics[1].Resource
+	if memory.Name != v1.ResourceMemory || !int32Equals(memory.Target.AverageUtilization, 75) {
+		t.Errorf("memory metric changed: %+v", memory)
+	}
+}
+
+func TestOtherMetricsSummary(t *testing.T) {
+	rps := resource.MustParse("100")
+	queue := resource.MustParse("30")
+	metrics := []autoscalingv2.MetricSpec{
+		utilizationMetric(v1.ResourceCPU, 70),
+		{
+			Type: autoscalingv2.PodsMetricSourceType,
+			Pods: &autoscalingv2.PodsMetricSource{
+				Metric: autoscalingv2.MetricIdentifier{Name: "requests-per-second"},
+				Target: autoscalingv2.MetricTarget{Type: autoscalingv2.AverageValueMetricType, AverageValue: &rps},
+			},
+		},
+		{
+			Type: autoscalingv2.ExternalMetricSourceType,
+			External: &autoscalingv2.ExternalMetricSource{
+				Metric: autoscalingv2.MetricIdentifier{Name: "sqs-queue-length"},
+				Target: autoscalingv2.MetricTarget{Type: autoscalingv2.AverageValueMetricType, AverageValue: &queue},
+			},
+		},
+	}
+
+	want := "custom: requests-per-second=100; external: sqs-queue-length=30/pod"
+	if got := otherMetricsSummary(metrics); got != want {
+		t.Errorf("otherMetricsSummary() = %q, want %q", got, want)
+	}
+}
+
+func TestPatchHPAFallsBackToAutoscalingV1(t *testing.T) {
+	resetCache := func() { hpaV1Only = make(map[string]bool) }
+	resetCache()
+	defer resetCache()
+
+	minReplicas, target := int32(1), int32(80)
+	clientset := fake.NewSimpleClientset(&autoscalingv1.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+		Spec: autoscalingv1.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef:                 autoscalingv1.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+			MinReplicas:                    &minReplicas,
+			MaxReplicas:                    5,
+			TargetCPUUtilizationPercentage: &target,
+		},
+	})
+	clientset.Discovery().(*fakediscovery.FakeDiscovery).Resources = []*metav1.APIResourceList{{GroupVersion: "autoscaling/v1"}}
+
+	hpas, err := listHPAs(context.TODO(), clientset, "shop", metav1.ListOptions{})
+	if err != nil || len(hpas) != 1 || !utilizationTargetEquals(hpas[0].Spec.Metrics, v1.ResourceCPU, 80) {
+		t.Fatalf("listHPAs() = %+v, %v, want the v1 HPA with an 80%% CPU target", hpas, err)
+	}
+
+	if err := patchHPA(io.Discard, clientset, "web", "shop", 2, 10, 60, nil, nil, nil, "", "", ""); err != nil {
+		t.Fatalf("patchHPA: %v", err)
+	}
+	hpa, err := clientset.AutoscalingV1().HorizontalPodAutoscalers("shop").Get(context.TODO(), "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get HPA: %v", err)
+	}
+	if *hpa.Spec.MinReplicas != 2 || hpa.Spec.MaxReplicas != 10 || *hpa.Spec.TargetCPUUtilizationPercentage != 60 {
+		t.Errorf("HPA spec = %+v, want 2-10 replicas at 60%% CPU", hpa.Spec)
+	}
+}
+
+func TestPatchHPALeavesNAWindowsUnchanged(t *testing.T) {
+	minReplicas, scaleUpWindow := int32(1), int32(60)
+	clientset := fake.NewSimpleClientset(
+		// Exported with N/A windows: the controller defaults (300s scale down) apply.
+		&autoscalingv2.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+			Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "web"},
+				MinReplicas:    &minReplicas,
+				MaxReplicas:    5,
+				Metrics:        []autoscalingv2.MetricSpec{utilizationMetric(v1.ResourceCPU, 80)},
+			},
+		},
+		// Exported with a scale up window only.
+		&autoscalingv2.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "shop"},
+			Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "api"},
+				MinReplicas:    &minReplicas,
+				MaxReplicas:    5,
+				Metrics:        []autoscalingv2.MetricSpec{utilizationMetric(v1.ResourceCPU, 80)},
+				Behavior: &autoscalingv2.HorizontalPodAutoscalerBehavior{
+					ScaleUp: &autoscalingv2.HPAScalingRules{StabilizationWindowSeconds: &scaleUpWindow},
+				},
+			},
+		},
+	)
+
+	for _, name := range []string{"web", "api"} {
+		before, _ := clientset.AutoscalingV2().HorizontalPodAutoscalers("shop").Get(context.TODO(), name, metav1.GetOptions{})
+		row := patchRow{Kind: kindDeployment, DeploymentName: name, Namespace: "shop", MinReplicas: 1, MaxReplicas: 5, CPUTargetUtilization: 80}
+		if !hpaMatchesRow(before, row) {
+			t.Errorf("%s: hpaMatchesRow() = false for a row with N/A windows and the live bounds", name)
+		}
+
+		row.MaxReplicas = 10
+		if err := patchHPA(io.Discard, clientset, name, "shop", 1, 10, 80, nil, nil, nil, "", "", ""); err != nil {
+			t.Fatalf("%s: patchHPA: %v", name, err)
+		}
+		after, err := clientset.AutoscalingV2().HorizontalPodAutoscalers("shop").Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("%s: get HPA: %v", name, err)
+		}
+		if after.Spec.MaxReplicas != 10 || !reflect.DeepEqual(after.Spec.Behavior, before.Spec.Behavior) {
+			t.Errorf("%s: behavior = %+v, want it unchanged (%+v) with max replicas 10", name, after.Spec.Behavior, before.Spec.Behavior)
+		}
+		if !hpaMatchesRow(after, row) {
+			t.Errorf("%s: hpaMatchesRow() = false after the patch", name)
+		}
+	}
+}
+
+func TestPatchHPALeavesMemoryOnlyMetricsUnchanged(t *testing.T) {
+	minReplicas := int32(1)
+	clientset := fake.NewSimpleClientset(&autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "cache", Namespace: "shop"},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "cache"},
+			MinReplicas:    &minReplicas,
+			MaxReplicas:    5,
+			Metrics:        []autoscalingv2.MetricSpec{utilizationMetric(v1.ResourceMemory, 75)},
+		},
+	})
+	// Exported with a CPU target of 0 and the live memory target.
+	memory := 75
+	row := patchRow{Kind: kindDeployment, DeploymentName: "cache", Namespace: "shop", MinReplicas: 1, MaxReplicas: 5, MemoryTargetUtilization: &memory}
+
+	before, _ := clientset.AutoscalingV2().HorizontalPodAutoscalers("shop").Get(context.TODO(), "cache", metav1.GetOptions{})
+	if !hpaMatchesRow(before, row) {
+		t.Error("hpaMatchesRow() = false for an unedited memory-only HPA row")
+	}
+	if changes := hpaChanges(before, row); len(changes) != 0 {
+		t.Errorf("hpaChanges() = %+v, want none", changes)
+	}
+
+	if err := patchHPA(io.Discard, clientset, "cache", "shop", 1, 8, row.CPUTargetUtilization, &memory, nil, nil, "", "", ""); err != nil {
+		t.Fatalf("patchHPA: %v", err)
+	}
+	after, _ := clientset.AutoscalingV2().HorizontalPodAutoscalers("shop").Get(context.TODO(), "cache", metav1.GetOptions{})
+	if after.Spec.MaxReplicas != 8 || !reflect.DeepEqual(after.Spec.Metrics, before.Spec.Metrics) {
+		t.Errorf("metrics = %+v, want only the memory metric (%+v) with max replicas 8", after.Spec.Metrics, before.Spec.Metrics)
+	}
+}
+
+func TestMetricsWarningNamesTheHPAMetrics(t *testing.T) {
+	minReplicas := int32(1)
+	hpa := func(name string, metrics ...autoscalingv2.MetricSpec) *autoscalingv2.HorizontalPodAutoscaler {
+		return &autoscalingv2.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop"},
+			Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: name},
+				MinReplicas:    &minReplicas,
+				MaxReplicas:    5,
+				Metrics:        metrics,
+			},
+		}
+	}
+	queue := autoscalingv2.MetricSpec{Type: autoscalingv2.ExternalMetricSourceType, External: &autoscalingv2.ExternalMetricSource{Metric: autoscalingv2.MetricIdentifier{Name: "queue_depth"}}}
+	// No metrics.k8s.io in the fake discovery.
+	clientset := fake.NewSimpleClientset(
+		hpa("web", utilizationMetric(v1.ResourceCPU, 80)),
+		hpa("cache", utilizationMetric(v1.ResourceMemory, 75)),
+		hpa("worker", queue),
+	)
+
+	memory := 75
+	tests := []struct {
+		row  patchRow
+		want string
+	}{
+		{patchRow{Namespace: "shop", DeploymentName: "web", CPUTargetUtilization: 80}, "targets CPU utilization"},
+		{patchRow{Namespace: "shop", DeploymentName: "cache", MemoryTargetUtilization: &memory}, "targets memory utilization"},
+		{patchRow{Namespace: "shop", DeploymentName: "cache", CPUTargetUtilization: 70, MemoryTargetUtilization: &memory}, "targets CPU and memory utilization"},
+		{patchRow{Namespace: "shop", DeploymentName: "worker"}, ""},
+	}
+	for _, tt := range tests {
+		var out strings.Builder
+		preflight := &metricsPreflight{clientset: clientset}
+		preflight.warn(&out, tt.row)
+		if got := out.String(); (tt.want == "") != (got == "") || !strings.Contains(got, tt.want) {
+			t.Errorf("warn(%s, cpu %d) = %q, want it to contain %q", tt.row.DeploymentName, tt.row.CPUTargetUtilization, got, tt.want)
+		}
+	}
+}
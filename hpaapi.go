@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// hpaV1Only caches, per context, whether the cluster serves HPAs only as autoscaling/v1
+// (Kubernetes before 1.23). The HPA calls go through listHPAs, getHPA and updateHPA, which convert
+// v1 objects to and from autoscaling/v2 so the rest of the tool only deals with v2.
+var (
+	hpaV1OnlyMu sync.Mutex
+	hpaV1Only   = make(map[string]bool)
+)
+
+// usesHPAV1 reports whether the cluster of the active context lacks autoscaling/v2. It asks
+// discovery once per context; when the autoscaling group isn't listed at all or discovery fails,
+// v2 is assumed and the HPA calls report the actual problem.
+func usesHPAV1(clientset kubernetes.Interface) bool {
+	hpaV1OnlyMu.Lock()
+	defer hpaV1OnlyMu.Unlock()
+	if v1Only, ok := hpaV1Only[activeContext]; ok {
+		return v1Only
+	}
+	v1Only := false
+	if groups, err := clientset.Discovery().ServerGroups(); err == nil {
+		for _, group := range groups.Groups {
+			if group.Name != autoscalingv2.GroupName {
+				continue
+			}
+			v1Only = true
+			for _, version := range group.Versions {
+				if version.Version == "v2" {
+					v1Only = false
+				}
+			}
+		}
+	}
+	if v1Only {
+		logger.Warn("the cluster doesn't serve autoscaling/v2, using autoscaling/v1 HPAs: only CPU targets are supported and memory targets, other metrics and scaling behavior are not available")
+	}
+	hpaV1Only[activeContext] = v1Only
+	return v1Only
+}
+
+// listHPAs lists the HPAs of the namespace as autoscaling/v2 objects.
+func listHPAs(ctx context.Context, clientset kubernetes.Interface, namespace string, options metav1.ListOptions) ([]autoscalingv2.HorizontalPodAutoscaler, error) {
+	if !usesHPAV1(clientset) {
+		list, err := clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).List(ctx, options)
+		if err != nil {
+			return nil, err
+		}
+		return list.Items, nil
+	}
+	list, err := clientset.AutoscalingV1().HorizontalPodAutoscalers(namespace).List(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	hpas := make([]autoscalingv2.HorizontalPodAutoscaler, len(list.Items))
+	for i, hpa := range list.Items {
+		hpas[i] = hpaFromV1(hpa)
+	}
+	return hpas, nil
+}
+
+// getHPA reads an HPA as an autoscaling/v2 object.
+func getHPA(ctx context.Context, clientset kubernetes.Interface, namespace, name string) (*autoscalingv2.HorizontalPodAutoscaler, error) {
+	if !usesHPAV1(clientset) {
+		return clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).Get(ctx, name, metav1.GetOptions{})
+	}
+	hpa, err := clientset.AutoscalingV1().HorizontalPodAutoscalers(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	converted := hpaFromV1(*hpa)
+	return &converted, nil
+}
+
+// updateHPA writes an HPA read with getHPA back. On autoscaling/v1 clusters the settings v1 can't
+// express are dropped, and named in the returned list so the caller can warn about them.
+func updateHPA(ctx context.Context, clientset kubernetes.Interface, hpa *autoscalingv2.HorizontalPodAutoscaler, options metav1.UpdateOptions) ([]string, error) {
+	if !usesHPAV1(clientset) {
+		_, err := clientset.AutoscalingV2().HorizontalPodAutoscalers(hpa.Namespace).Update(ctx, hpa, options)
+		return nil, err
+	}
+	converted, dropped := hpaToV1(hpa)
+	_, err := clientset.AutoscalingV1().HorizontalPodAutoscalers(hpa.Namespace).Update(ctx, converted, options)
+	return dropped, err
+}
+
+// hpaFromV1 converts an autoscaling/v1 HPA. Its CPU utilization target and observed CPU
+// utilization become resource metrics; v1 has no behavior, so the stabilization windows stay unset.
+func hpaFromV1(hpa autoscalingv1.HorizontalPodAutoscaler) autoscalingv2.HorizontalPodAutoscaler {
+	converted := autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: hpa.ObjectMeta,
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+				Kind:       hpa.Spec.ScaleTargetRef.Kind,
+				Name:       hpa.Spec.ScaleTargetRef.Name,
+				APIVersion: hpa.Spec.ScaleTargetRef.APIVersion,
+			},
+			MinReplicas: hpa.Spec.MinReplicas,
+			MaxReplicas: hpa.Spec.MaxReplicas,
+		},
+		Status: autoscalingv2.HorizontalPodAutoscalerStatus{
+			ObservedGeneration: hpa.Status.ObservedGeneration,
+			LastScaleTime:      hpa.Status.LastScaleTime,
+			CurrentReplicas:    hpa.Status.CurrentReplicas,
+			DesiredReplicas:    hpa.Status.DesiredReplicas,
+		},
+	}
+	if target := hpa.Spec.TargetCPUUtilizationPercentage; target != nil {
+		converted.Spec.Metrics = []autoscalingv2.MetricSpec{{
+			Type: autoscalingv2.ResourceMetricSourceType,
+			Resource: &autoscalingv2.ResourceMetricSource{
+				Name:   v1.ResourceCPU,
+				Target: autoscalingv2.MetricTarget{Type: autoscalingv2.UtilizationMetricType, AverageUtilization: target},
+			},
+		}}
+	}
+	if current := hpa.Status.CurrentCPUUtilizationPercentage; current != nil {
+		converted.Status.CurrentMetrics = []autoscalingv2.MetricStatus{{
+			Type: autoscalingv2.ResourceMetricSourceType,
+			Resource: &autoscalingv2.ResourceMetricStatus{
+				Name:    v1.ResourceCPU,
+				Current: autoscalingv2.MetricValueStatus{AverageUtilization: current},
+			},
+		}}
+	}
+	return converted
+}
+
+// hpaToV1 converts an HPA back to autoscaling/v1, returning the settings v1 can't hold: metrics
+// other than the CPU utilization target, and the scaling behavior.
+func hpaToV1(hpa *autoscalingv2.HorizontalPodAutoscaler) (*autoscalingv1.HorizontalPodAutoscaler, []string) {
+	converted := &autoscalingv1.HorizontalPodAutoscaler{
+		ObjectMeta: hpa.ObjectMeta,
+		Spec: autoscalingv1.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv1.CrossVersionObjectReference{
+				Kind:       hpa.Spec.ScaleTargetRef.Kind,
+				Name:       hpa.Spec.ScaleTargetRef.Name,
+				APIVersion: hpa.Spec.ScaleTargetRef.APIVersion,
+			},
+			MinReplicas: hpa.Spec.MinReplicas,
+			MaxReplicas: hpa.Spec.MaxReplicas,
+		},
+	}
+	var dropped []string
+	for _, metric := range hpa.Spec.Metrics {
+		if metric.Type == autoscalingv2.ResourceMetricSourceType && metric.Resource != nil &&
+			metric.Resource.Name == v1.ResourceCPU && metric.Resource.Target.AverageUtilization != nil {
+			converted.Spec.TargetCPUUtilizationPercentage = metric.Resource.Target.AverageUtilization
+			continue
+		}
+		dropped = append(dropped, metricLabel(metric))
+	}
+	if behavior := hpa.Spec.Behavior; behavior != nil && (behavior.ScaleUp != nil || behavior.ScaleDown != nil) {
+		dropped = append(dropped, "scaling behavior")
+	}
+	return converted, dropped
+}
+
+// metricLabel names a metric in the warning about settings autoscaling/v1 can't hold.
+func metricLabel(metric autoscalingv2.MetricSpec) string {
+	if metric.Type == autoscalingv2.ResourceMetricSourceType && metric.Resource != nil {
+		return fmt.Sprintf("%s target", metric.Resource.Name)
+	}
+	return strings.ToLower(string(metric.Type)) + " metric"
+}
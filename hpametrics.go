@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	v1 "k8s.io/api/core/v1"
+)
+
+// otherMetricsSummary renders the Other Metrics column: every HPA metric besides the CPU and
+// memory utilization targets the CSV has columns for, e.g. "custom: requests-per-second=100;
+// external: sqs-queue-length=30/pod". The patch action never changes these metrics.
+func otherMetricsSummary(metrics []autoscalingv2.MetricSpec) string {
+	var parts []string
+	for _, metric := range metrics {
+		switch metric.Type {
+		case autoscalingv2.ResourceMetricSourceType:
+			if metric.Resource == nil || hasUtilizationColumn(metric.Resource.Name, metric.Resource.Target) {
+				continue
+			}
+			parts = append(parts, fmt.Sprintf("resource: %s=%s", metric.Resource.Name, metricTargetCell(metric.Resource.Target)))
+		case autoscalingv2.ContainerResourceMetricSourceType:
+			if metric.ContainerResource == nil {
+				continue
+			}
+			source := metric.ContainerResource
+			parts = append(parts, fmt.Sprintf("container %s: %s=%s", source.Container, source.Name, metricTargetCell(source.Target)))
+		case autoscalingv2.PodsMetricSourceType:
+			if metric.Pods == nil || metric.Pods.Target.AverageValue == nil {
+				continue
+			}
+			// Pods metrics are always averaged over the pods, so the target needs no /pod suffix.
+			parts = append(parts, fmt.Sprintf("custom: %s=%s", metric.Pods.Metric.Name, metric.Pods.Target.AverageValue.String()))
+		case autoscalingv2.ObjectMetricSourceType:
+			if metric.Object == nil {
+				continue
+			}
+			object := metric.Object.DescribedObject
+			parts = append(parts, fmt.Sprintf("object %s/%s: %s=%s", object.Kind, object.Name, metric.Object.Metric.Name, metricTargetCell(metric.Object.Target)))
+		case autoscalingv2.ExternalMetricSourceType:
+			if metric.External == nil {
+				continue
+			}
+			parts = append(parts, fmt.Sprintf("external: %s=%s", metric.External.Metric.Name, metricTargetCell(metric.External.Target)))
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// hasUtilizationColumn reports whether the resource target is already shown in the CPU or Memory
+// Target Utilization column.
+func hasUtilizationColumn(name v1.ResourceName, target autoscalingv2.MetricTarget) bool {
+	return (name == v1.ResourceCPU || name == v1.ResourceMemory) && target.AverageUtilization != nil
+}
+
+// metricTargetCell renders a metric target: "80%" for utilization, "100" for a value and
+// "100/pod" for an average value.
+func metricTargetCell(target autoscalingv2.MetricTarget) string {
+	switch {
+	case target.AverageUtilization != nil:
+		return fmt.Sprintf("%d%%", *target.AverageUtilization)
+	case target.AverageValue != nil:
+		return target.AverageValue.String() + "/pod"
+	case target.Value != nil:
+		return target.Value.String()
+	}
+	return "?"
+}
+
+// currentUtilization returns the average utilization of the resource the HPA last observed, nil
+// when metrics-server hasn't reported it (yet) or the HPA doesn't scale on the resource.
+func currentUtilization(metrics []autoscalingv2.MetricStatus, name v1.ResourceName) *int32 {
+	for _, metric := range metrics {
+		if metric.Type == autoscalingv2.ResourceMetricSourceType && metric.Resource != nil && metric.Resource.Name == name {
+			return metric.Resource.Current.AverageUtilization
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// serviceAccountNamespaceFile holds the namespace of the pod's service account when running in a cluster.
+const serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// inClusterName stands in for the context and cluster name of the in-cluster config, which has neither.
+const inClusterName = "in-cluster"
+
+var (
+	inClusterOnce    sync.Once
+	inClusterEnabled bool
+)
+
+// useInCluster reports whether the API is reached through the pod's service account instead of a
+// kubeconfig: always with -in-cluster, and automatically when running in a pod unless -kubeconfig
+// or -context asks for a kubeconfig.
+func useInCluster() bool {
+	inClusterOnce.Do(func() {
+		switch {
+		case *inCluster:
+			inClusterEnabled = true
+		case *kubeconfig != "" || len(contextNames) > 0:
+			inClusterEnabled = false
+		default:
+			_, err := rest.InClusterConfig()
+			inClusterEnabled = err == nil
+		}
+	})
+	return inClusterEnabled
+}
+
+// restConfig returns the client-go config: the in-cluster one when useInCluster, otherwise the
+// one of clientConfig, the kubeconfig resolved by kubeClientConfig.
+func restConfig(clientConfig clientcmd.ClientConfig) (*rest.Config, error) {
+	if useInCluster() {
+		return rest.InClusterConfig()
+	}
+	return clientConfig.ClientConfig()
+}
+
+// inClusterNamespace returns the namespace of the pod's service account, "default" if it can't be read.
+func inClusterNamespace() string {
+	data, err := os.ReadFile(serviceAccountNamespaceFile)
+	if namespace := strings.TrimSpace(string(data)); err == nil && namespace != "" {
+		return namespace
+	}
+	return metav1.NamespaceDefault
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// interrupted is cancelled by the first Ctrl-C (SIGINT) or SIGTERM received while an action runs
+// under handleInterrupts. Actions check it between deployments: the ones already being changed
+// are finished, the remaining ones are not started.
+var interrupted = context.Background()
+
+// handleInterrupts installs the signal handler of a long-running action and returns the function
+// removing it. After the first signal the default handling is restored, so a second Ctrl-C still
+// kills the process right away.
+func handleInterrupts() (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-signals:
+			signal.Stop(signals)
+			logger.Warn("interrupted, letting the operations in flight finish (press Ctrl-C again to abort immediately)", "signal", sig.String())
+			cancel()
+		case <-done:
+		}
+	}()
+	interrupted = ctx
+	return func() {
+		interrupted = context.Background()
+		signal.Stop(signals)
+		close(done)
+		cancel()
+	}
+}
+
+// isInterrupted reports whether the running action was asked to stop.
+func isInterrupted() bool {
+	return interrupted.Err() != nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// writeJSON saves the deployments as an indented JSON array. Stabilization windows the HPA leaves
+// unset are null rather than 0, and the file can be edited and read back by the patch action.
+func writeJSON(data []DeploymentInfo, path string) error {
+	encoded, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode deployments: %w", err)
+	}
+	if err := os.WriteFile(path, append(encoded, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write JSON file: %w", err)
+	}
+	return nil
+}
+
+// readJSONPatchRows loads a file written by writeJSON for the patch action.
+func readJSONPatchRows(path string) ([]patchRow, error) {
+	encoded, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open JSON file: %w", err)
+	}
+	return decodeJSONPatchRows(encoded, path)
+}
+
+// decodeJSONPatchRows parses the contents of a file written by writeJSON; name identifies it in
+// errors.
+func decodeJSONPatchRows(encoded []byte, name string) ([]patchRow, error) {
+	var data []DeploymentInfo
+	if err := json.Unmarshal(encoded, &data); err != nil {
+		return nil, fmt.Errorf("invalid JSON file %s: %w", name, err)
+	}
+
+	rows := make([]patchRow, len(data))
+	for i, deploy := range data {
+		rows[i] = patchRowFromInfo(deploy)
+	}
+	return rows, nil
+}
+
+// patchRowFromInfo converts a deployment read from JSON into a patch row. The per-container
+// entries are always present in JSON, so containers are patched individually like with -wide.
+func patchRowFromInfo(deploy DeploymentInfo) patchRow {
+	row := patchRow{
+		DeploymentName:          deploy.Name,
+		Namespace:               deploy.Namespace,
+		Replicas:                fmt.Sprint(deploy.Replicas),
+		CPURequest:              deploy.CPURequest,
+		CPULimit:                deploy.CPULimit,
+		MemoryRequest:           deploy.MemoryRequest,
+		MemoryLimit:             deploy.MemoryLimit,
+		MaxUnavailable:          deploy.MaxUnavailable,
+		MaxSurge:                deploy.MaxSurge,
+		MinReplicas:             int(deploy.MinReplicas),
+		MaxReplicas:             int(deploy.MaxReplicas),
+		CPUTargetUtilization:    int(deploy.CPUTargetUtilization),
+		ScaleUpPolicies:         deploy.ScaleUpPolicies,
+		ScaleDownPolicies:       deploy.ScaleDownPolicies,
+		ResourceVersion:         deploy.ResourceVersion,
+		HPAResourceVersion:      deploy.HPAResourceVersion,
+		UpdateResourceAndHPA:    deploy.UpdateResourceAndHPA,
+		UpdateHPAOnly:           deploy.UpdateHPAOnly,
+		UpdateReplicas:          deploy.UpdateReplicas,
+		Profile:                 deploy.Profile,
+		Containers:              deploy.Containers,
+		Context:                 deploy.Context,
+		Strategy:                deploy.Strategy,
+		EphemeralStorageRequest: deploy.EphemeralStorageRequest,
+		EphemeralStorageLimit:   deploy.EphemeralStorageLimit,
+		PDBName:                 deploy.PDBName,
+		PDBMinAvailable:         deploy.PDBMinAvailable,
+		PDBMaxUnavailable:       deploy.PDBMaxUnavailable,
+	}
+	validatePDBBudget(&row)
+	row.Kind = rowKind(deploy.Kind, &row)
+	if deploy.MemoryTargetUtilization != nil {
+		memoryTarget := int(*deploy.MemoryTargetUtilization)
+		row.MemoryTargetUtilization = &memoryTarget
+	}
+	if deploy.ScaleUpStabilization != nil {
+		scaleUp := int(*deploy.ScaleUpStabilization)
+		row.ScaleUpStabilization = &scaleUp
+	}
+	if deploy.ScaleDownStabilization != nil {
+		scaleDown := int(*deploy.ScaleDownStabilization)
+		row.ScaleDownStabilization = &scaleDown
+	}
+	return row
+}
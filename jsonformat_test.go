@@ -0,0 +1,37 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	window := int32(300)
+	data := []DeploymentInfo{{
+		Name: "web", Namespace: "shop", Replicas: 2,
+		CPURequest: "250m", MemoryRequest: "256Mi", MemoryLimit: "512Mi",
+		MaxUnavailable: "25%", MaxSurge: "1",
+		MinReplicas: 2, MaxReplicas: 10, CPUTargetUtilization: 70,
+		ScaleDownStabilization: &window,
+		UpdateHPAOnly:          true,
+		Containers:             []ContainerResources{{Name: "app", CPURequest: "250m", MemoryRequest: "256Mi", MemoryLimit: "512Mi"}},
+	}}
+
+	path := filepath.Join(t.TempDir(), "deployment-info.json")
+	if err := writeJSON(data, path); err != nil {
+		t.Fatalf("writeJSON: %v", err)
+	}
+	rows, err := readJSONPatchRows(path)
+	if err != nil {
+		t.Fatalf("readJSONPatchRows: %v", err)
+	}
+
+	want := patchRowFromInfo(data[0])
+	if len(rows) != 1 || !reflect.DeepEqual(rows[0], want) {
+		t.Errorf("rows = %+v, want %+v", rows, want)
+	}
+	if want.ScaleUpStabilization != nil || want.ScaleDownStabilization == nil || *want.ScaleDownStabilization != 300 || !want.UpdateHPAOnly {
+		t.Errorf("unexpected row values %+v", want)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"os/exec"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// kubeClientConfig loads the kubeconfig like kubectl does: the -kubeconfig file if given,
+// otherwise the files listed in KUBECONFIG (merged in order), otherwise $HOME/.kube/config.
+func kubeClientConfig() clientcmd.ClientConfig {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = *kubeconfig
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{CurrentContext: activeContext})
+}
+
+// rawKubeconfig returns the merged kubeconfig with its contexts and clusters.
+func rawKubeconfig() (clientcmdapi.Config, error) {
+	return kubeClientConfig().RawConfig()
+}
+
+// kubectlCommand builds a kubectl invocation that talks to the same cluster as the client-go
+// calls, with the same -timeout. KUBECONFIG is inherited from the environment, so only an
+// explicit -kubeconfig has to be passed on.
+func kubectlCommand(args ...string) *exec.Cmd {
+	args = append([]string{"--request-timeout=" + timeout.String()}, args...)
+	if activeContext != "" {
+		args = append([]string{"--context=" + activeContext}, args...)
+	}
+	if *kubeconfig != "" {
+		args = append([]string{"--kubeconfig=" + *kubeconfig}, args...)
+	}
+	return exec.Command("kubectl", args...)
+}
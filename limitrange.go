@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// limitRangeChecker validates patch rows against the Container constraints of the namespace
+// LimitRanges before anything is patched, instead of letting the API server reject the change
+// halfway through the run with a cryptic admission error.
+type limitRangeChecker struct {
+	clientset kubernetes.Interface
+	mu        sync.Mutex                     // rows are patched concurrently
+	cache     map[string][]v1.LimitRangeItem // namespace -> Container items
+}
+
+func newLimitRangeChecker(clientset kubernetes.Interface) *limitRangeChecker {
+	return &limitRangeChecker{clientset: clientset, cache: make(map[string][]v1.LimitRangeItem)}
+}
+
+// resourceCells points at the request and limit cells of one container (or of the whole row),
+// so clamping can rewrite them in place.
+type resourceCells struct {
+	label    string
+	requests map[v1.ResourceName]*string
+	limits   map[v1.ResourceName]*string
+}
+
+// rowResourceCells returns the cells patched for the row: one entry per -wide container, or the
+// aggregate cells otherwise.
+func rowResourceCells(row *patchRow) []resourceCells {
+	if len(row.Containers) == 0 {
+		return []resourceCells{{
+			label:    row.DeploymentName,
+			requests: map[v1.ResourceName]*string{v1.ResourceCPU: &row.CPURequest, v1.ResourceMemory: &row.MemoryRequest},
+			limits:   map[v1.ResourceName]*string{v1.ResourceCPU: &row.CPULimit, v1.ResourceMemory: &row.MemoryLimit},
+		}}
+	}
+
+	var cells []resourceCells
+	for i := range row.Containers {
+		container := &row.Containers[i]
+		cells = append(cells, resourceCells{
+			label:    row.DeploymentName + "/" + container.Name,
+			requests: map[v1.ResourceName]*string{v1.ResourceCPU: &container.CPURequest, v1.ResourceMemory: &container.MemoryRequest},
+			limits:   map[v1.ResourceName]*string{v1.ResourceCPU: &container.CPULimit, v1.ResourceMemory: &container.MemoryLimit},
+		})
+	}
+	return cells
+}
+
+// containerItems returns the Container constraints of the namespace, fetching them once.
+func (c *limitRangeChecker) containerItems(out io.Writer, namespace string) []v1.LimitRangeItem {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if items, ok := c.cache[namespace]; ok {
+		return items
+	}
+
+	ctx, cancel := apiContext()
+	defer cancel()
+
+	var items []v1.LimitRangeItem
+	list, err := c.clientset.CoreV1().LimitRanges(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		loggerTo(out).Warn("failed to list LimitRanges, skipping the LimitRange check", "namespace", namespace, "err", err)
+	} else {
+		for _, limitRange := range list.Items {
+			for _, item := range limitRange.Spec.Limits {
+				if item.Type == v1.LimitTypeContainer {
+					items = append(items, item)
+				}
+			}
+		}
+	}
+	c.cache[namespace] = items
+	return items
+}
+
+// check validates the row against the namespace LimitRanges. With clamp the offending values are
+// moved into the allowed range (and logged); otherwise every violated constraint is returned.
+// Rows that only update the HPA don't touch resources and always pass.
+func (c *limitRangeChecker) check(out io.Writer, row *patchRow, clamp bool) []string {
+	if !row.UpdateResourceAndHPA {
+		return nil
+	}
+	items := c.containerItems(out, row.Namespace)
+	if len(items) == 0 {
+		return nil
+	}
+
+	var violations []string
+	for _, cells := range rowResourceCells(row) {
+		for _, item := range items {
+			for _, name := range []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory} {
+				violations = append(violations, checkLimitRangeItem(out, item, name, cells, clamp)...)
+			}
+		}
+	}
+	return violations
+}
+
+func checkLimitRangeItem(out io.Writer, item v1.LimitRangeItem, name v1.ResourceName, cells resourceCells, clamp bool) []string {
+	var violations []string
+	report := func(cell *string, kind, problem string, allowed resource.Quantity) {
+		if clamp {
+			loggerTo(out).Info(fmt.Sprintf("%s %s %s %s %s, clamped to %s", cells.label, name, kind, *cell, problem, allowed.String()))
+			*cell = allowed.String()
+			return
+		}
+		violations = append(violations, fmt.Sprintf("%s %s %s %s %s %s", cells.label, name, kind, *cell, problem, allowed.String()))
+	}
+
+	for _, entry := range []struct {
+		kind string
+		cell *string
+	}{{"request", cells.requests[name]}, {"limit", cells.limits[name]}} {
+		kind, cell := entry.kind, entry.cell
+		value, ok := parseCell(cell)
+		if !ok || (kind == "limit" && value.IsZero()) {
+			continue // a zero limit means no limit and is not sent
+		}
+		if min, ok := item.Min[name]; ok && value.Cmp(min) < 0 {
+			report(cell, kind, "is below the LimitRange minimum", min)
+		}
+		if max, ok := item.Max[name]; ok && value.Cmp(max) > 0 {
+			report(cell, kind, "is above the LimitRange maximum", max)
+		}
+	}
+
+	// maxLimitRequestRatio: limit / request must not exceed the ratio. Clamping raises the request.
+	ratio, hasRatio := item.MaxLimitRequestRatio[name]
+	request, hasRequest := parseCell(cells.requests[name])
+	limit, limitSet := parseCell(cells.limits[name])
+	if hasRatio && hasRequest && limitSet && !limit.IsZero() && request.MilliValue() > 0 {
+		actual := float64(limit.MilliValue()) / float64(request.MilliValue())
+		allowed := float64(ratio.MilliValue()) / 1000
+		if actual > allowed {
+			minRequest := *resource.NewMilliQuantity(int64(math.Ceil(float64(limit.MilliValue())/allowed)), request.Format)
+			report(cells.requests[name], "request", fmt.Sprintf("gives a limit/request ratio of %.2f, above the LimitRange maxLimitRequestRatio %s; the minimum request is", actual, ratio.String()), minRequest)
+		}
+	}
+	return violations
+}
+
+// parseCell parses a quantity cell, reporting false for missing, empty or invalid cells.
+func parseCell(cell *string) (resource.Quantity, bool) {
+	if cell == nil || *cell == "" {
+		return resource.Quantity{}, false
+	}
+	quantity, err := resource.ParseQuantity(*cell)
+	if err != nil {
+		return resource.Quantity{}, false
+	}
+	return quantity, true
+}
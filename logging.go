@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// logLevel is the minimum level logged, shared by every logger so per-row loggers of the patch
+// action follow -log-level too.
+var logLevel = new(slog.LevelVar)
+
+// logger receives the tool's diagnostics. setupLogging configures it from -log-level,
+// -log-format and -quiet; prompts, tables and the generated files are not logged.
+var logger = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: logLevel}))
+
+// setupLogging validates the logging flags and points logger at the (possibly redirected) stdout.
+func setupLogging() error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(*logLevelName)); err != nil {
+		return fmt.Errorf("invalid -log-level %q: must be debug, info, warn or error", *logLevelName)
+	}
+	if *logFormat != "text" && *logFormat != "json" {
+		return fmt.Errorf("invalid -log-format %q: must be text or json", *logFormat)
+	}
+	// -quiet keeps only what needs attention.
+	if *quiet && level < slog.LevelWarn {
+		level = slog.LevelWarn
+	}
+	logLevel.Set(level)
+	logger = loggerTo(os.Stdout)
+	return nil
+}
+
+// loggerTo returns a logger with the configured format writing to w, e.g. the buffer collecting
+// the output of one patch row.
+func loggerTo(w io.Writer) *slog.Logger {
+	options := &slog.HandlerOptions{Level: logLevel}
+	if strings.EqualFold(*logFormat, "json") {
+		return slog.New(slog.NewJSONHandler(w, options))
+	}
+	return slog.New(slog.NewTextHandler(w, options))
+}
@@ -2,242 +2,418 @@ package main
 
 import (
 	"bufio"
-	"context"
+	"bytes"
 	"encoding/csv"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"io"
 	"os"
-	"os/exec"
-	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
-	"time"
+	"sync"
 
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1" // For metadata API
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
-	autoscalingv2 "k8s.io/api/autoscaling/v2"
-	v1 "k8s.io/api/core/v1"
 )
 
 type DeploymentInfo struct {
-	Name                   string
-	Namespace              string
-	Replicas               int32
-	MinReplicas            int32
-	MaxReplicas            int32
-	CPURequest             string
-	CPULimit               string
-	MemoryRequest          string
-	MemoryLimit            string
-	MaxUnavailable		   string
-	MaxSurge			   string
-	CPUTargetUtilization   int32
-	ScaleUpStabilization   *int32
-	ScaleDownStabilization *int32
-	UpdateResourceAndHPA   string
-	UpdateHPAOnly          string
-}
-
-// initializes a Kubernetes client using the default kubeconfig.
-func getKubeClient() (*kubernetes.Clientset, string) {
-	home := os.Getenv("HOME")
-	kubeconfig := filepath.Join(home, ".kube", "config")
-
-	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	Context                 string               `json:"context,omitempty"` // kubeconfig context of the row, set with -context
+	Kind                    string               `json:"kind"`
+	Name                    string               `json:"name"`
+	Namespace               string               `json:"namespace"`
+	Replicas                int32                `json:"replicas"`
+	HasHPA                  bool                 `json:"hasHPA"` // an HPA targets the workload; the HPA columns are N/A otherwise
+	MinReplicas             int32                `json:"minReplicas"`
+	MaxReplicas             int32                `json:"maxReplicas"`
+	CPURequest              string               `json:"cpuRequest"`
+	CPULimit                string               `json:"cpuLimit"`
+	MemoryRequest           string               `json:"memoryRequest"`
+	MemoryLimit             string               `json:"memoryLimit"`
+	EphemeralStorageRequest string               `json:"ephemeralStorageRequest"` // "0" when unset, see ephemeralStorageCell
+	EphemeralStorageLimit   string               `json:"ephemeralStorageLimit"`
+	ExtendedResources       string               `json:"extendedResources,omitempty"` // see extendedResources
+	MaxUnavailable          string               `json:"maxUnavailable"`
+	MaxSurge                string               `json:"maxSurge"`
+	Strategy                string               `json:"strategy"` // RollingUpdate or Recreate
+	MinReadySeconds         int32                `json:"minReadySeconds"`
+	CPUTargetUtilization    int32                `json:"cpuTargetUtilization"`
+	MemoryTargetUtilization *int32               `json:"memoryTargetUtilization"` // nil when the HPA doesn't scale on memory
+	CPUCurrentUtilization   *int32               `json:"cpuCurrentUtilization"`   // observed by the HPA, nil until metrics-server reports it
+	ScaleUpStabilization    *int32               `json:"scaleUpStabilization"`
+	ScaleDownStabilization  *int32               `json:"scaleDownStabilization"`
+	ScaleUpPolicies         string               `json:"scaleUpPolicies,omitempty"` // JSON-encoded selectPolicy and policies, see encodeScalingPolicies
+	ScaleDownPolicies       string               `json:"scaleDownPolicies,omitempty"`
+	Warnings                string               `json:"warnings,omitempty"`     // see workloadWarnings
+	Image                   string               `json:"image"`                  // see containerImages
+	OtherMetrics            string               `json:"otherMetrics,omitempty"` // HPA metrics besides CPU/memory utilization, see otherMetricsSummary
+	ResourceVersion         string               `json:"resourceVersion"`        // deployment resourceVersion when the CSV was generated
+	HPAResourceVersion      string               `json:"hpaResourceVersion,omitempty"`
+	HPACurrentReplicas      int32                `json:"hpaCurrentReplicas"` // replica count the HPA last observed
+	HPADesiredReplicas      int32                `json:"hpaDesiredReplicas"` // replica count the HPA last computed
+	ReadyReplicas           int32                `json:"readyReplicas"`
+	AvailableReplicas       int32                `json:"availableReplicas"`
+	ReplicaIssue            string               `json:"replicaIssue,omitempty"` // failing condition explaining missing replicas, if any
+	Conditions              string               `json:"conditions,omitempty"`   // most relevant failing condition, "Healthy" when none fails
+	LastRollout             string               `json:"lastRollout"`            // RFC 3339, see lastRollout
+	Age                     string               `json:"age"`                    // since creation, e.g. "45d"
+	UpdateResourceAndHPA    bool                 `json:"updateResourceAndHPA"`
+	UpdateHPAOnly           bool                 `json:"updateHPAOnly"`
+	UpdateReplicas          bool                 `json:"updateReplicas"`    // scale a workload without an HPA to Replicas, see replicaTarget
+	Profile                 string               `json:"profile,omitempty"` // named profile to patch with, see applyProfile
+	CustomColumn            string               `json:"customColumn,omitempty"`
+	Containers              []ContainerResources `json:"containers"`
+	SpotOnly                bool                 `json:"spotOnly"`          // pods can only be scheduled on spot/preemptible nodes
+	PDBName                 string               `json:"pdbName,omitempty"` // PodDisruptionBudget selecting the pods, if any
+	PDBMinAvailable         string               `json:"pdbMinAvailable,omitempty"`
+	PDBMaxUnavailable       string               `json:"pdbMaxUnavailable,omitempty"`
+	Security                SecurityPosture      `json:"security"`
+	Services                string               `json:"services,omitempty"` // comma-separated Services selecting the pods (-include-services)
+	Labels                  map[string]string    `json:"-"`
+	Annotations             map[string]string    `json:"-"`
+}
+
+// ContainerResources holds the requests and limits of a single container of a deployment.
+type ContainerResources struct {
+	Name                    string `json:"name"`
+	CPURequest              string `json:"cpuRequest"`
+	CPULimit                string `json:"cpuLimit"`
+	MemoryRequest           string `json:"memoryRequest"`
+	MemoryLimit             string `json:"memoryLimit"`
+	EphemeralStorageRequest string `json:"ephemeralStorageRequest"`
+	EphemeralStorageLimit   string `json:"ephemeralStorageLimit"`
+}
+
+// initializes a Kubernetes client using the in-cluster config or the kubeconfig (see restConfig).
+// The kubeconfig is loaded once; the clientset and the namespace both come from it. Errors carry
+// exitConnectivity, so the actions can simply return them.
+func getKubeClient() (*kubernetes.Clientset, string, error) {
+	clientConfig := kubeClientConfig()
+	config, err := restConfig(clientConfig)
 	if err != nil {
-		log.Fatalf("💢 Failed to load kubeconfig: %v", err)
+		return nil, "", withExitCode(exitConnectivity, fmt.Errorf("failed to load the cluster config: %w", err))
 	}
+	applyRateLimits(config)
 
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
-		log.Fatalf("💢 Failed to create Kubernetes client: %v", err)
+		return nil, "", withExitCode(exitConnectivity, fmt.Errorf("failed to create Kubernetes client: %w", err))
+	}
+
+	namespace, err := namespaceFrom(clientConfig)
+	if err != nil {
+		return nil, "", withExitCode(exitConnectivity, err)
 	}
+	return clientset, namespace, nil
+}
 
-	// Get the current namespace from the context
-	namespace := getActiveNamespace(kubeconfig)
-	return clientset, namespace
+// getActiveNamespace returns the namespace to work in, see namespaceFrom.
+func getActiveNamespace() (string, error) {
+	return namespaceFrom(kubeClientConfig())
 }
 
-// getActiveNamespace fetches the current namespace from kubeconfig.
-func getActiveNamespace(kubeconfig string) string {
-	config, err := clientcmd.LoadFromFile(kubeconfig)
+// namespaceFrom returns the namespace to work in: -namespace if given, otherwise the namespace of
+// the service account in a pod or of the selected context of clientConfig, otherwise "default"
+// like kubectl.
+func namespaceFrom(clientConfig clientcmd.ClientConfig) (string, error) {
+	if *namespaceOverride != "" {
+		return *namespaceOverride, nil
+	}
+	if useInCluster() {
+		return inClusterNamespace(), nil
+	}
+	namespace, _, err := clientConfig.Namespace()
 	if err != nil {
-		log.Fatalf("💢 Failed to load kubeconfig: %v", err)
+		return "", fmt.Errorf("failed to read the namespace from the kubeconfig: %w", err)
 	}
+	return namespace, nil
+}
 
-	currentContext := config.CurrentContext
-	contextConfig, exists := config.Contexts[currentContext]
-	if !exists {
-		log.Fatalf("💢 Context %s not found in kubeconfig", currentContext)
+func getDeploymentInfo(clientset kubernetes.Interface, namespace string) ([]DeploymentInfo, error) {
+	kinds, err := selectedKinds()
+	if err != nil {
+		return nil, withExitCode(exitUsage, err)
 	}
 
-	return contextConfig.Namespace
-}
+	ctx, cancel := apiContext()
+	defer cancel()
 
-func getDeploymentInfo(clientset *kubernetes.Clientset, namespace string) ([]DeploymentInfo, error) {
-	var results []DeploymentInfo
-
-	// List all Deployments in the namespace.
-	deployments, err := clientset.AppsV1().Deployments(namespace).List(context.TODO(), metav1.ListOptions{})
+	// List all HPAs in the namespace (matching -selector).
+	var hpas []autoscalingv2.HorizontalPodAutoscaler
+	err = retryTransient("list HPAs", func() (err error) {
+		hpas, err = listHPAs(ctx, clientset, namespace, selectorListOptions())
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("💢 failed to list deployments: %w", err)
+		return nil, fmt.Errorf("failed to list HPAs: %w", err)
 	}
 
-	// List all HPAs in the namespace.
-	hpaList, err := clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).List(context.TODO(), metav1.ListOptions{})
+	// List all PDBs in the namespace. They only feed the findings, so a missing permission isn't fatal.
+	var pdbList *policyv1.PodDisruptionBudgetList
+	err = retryTransient("list PodDisruptionBudgets", func() (err error) {
+		pdbList, err = clientset.PolicyV1().PodDisruptionBudgets(namespace).List(ctx, metav1.ListOptions{})
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("💢 failed to list HPAs: %w", err)
+		logger.Warn("failed to list PodDisruptionBudgets, PDB information will be missing", "err", err)
+		pdbList = &policyv1.PodDisruptionBudgetList{}
+	}
+	objects := workloadObjects{hpas: indexHPAs(hpas), pdbs: pdbList.Items, spotIndicators: parseNodeIndicators(*spotNodeKeys)}
+
+	// List all Services in the namespace to map them to the workloads they expose.
+	if *includeServices {
+		var serviceList *v1.ServiceList
+		err := retryTransient("list services", func() (err error) {
+			serviceList, err = clientset.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list services: %w", err)
+		}
+		objects.services = serviceList.Items
 	}
 
-	// Iterate over Deployments and collect relevant data.
-	for _, deploy := range deployments.Items {
-		var info DeploymentInfo
-		info.Name = deploy.Name
-		info.Namespace = deploy.Namespace
-		info.Replicas = *deploy.Spec.Replicas
-
-		var totalCPURequest, totalCPULimit, totalMemoryRequest, totalMemoryLimit int64
+	// Each workload becomes a row on its own, so the rows (and their custom column commands) are
+	// built by a bounded worker pool once everything is listed.
+	var build []func() DeploymentInfo
+	if kinds[kindDeployment] {
+		// List all Deployments in the namespace.
+		var deployments *appsv1.DeploymentList
+		err := retryTransient("list deployments", func() (err error) {
+			deployments, err = clientset.AppsV1().Deployments(namespace).List(ctx, selectorListOptions())
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list deployments: %w", err)
+		}
+		for _, deploy := range deployments.Items {
+			if !keepWorkload(deploy.ObjectMeta) {
+				continue
+			}
+			build = append(build, func() DeploymentInfo { return objects.deploymentInfo(deploy) })
+		}
+	}
 
-		// Aggregate resource requests and limits from all containers in the deployment.
-		for _, container := range deploy.Spec.Template.Spec.Containers {
-			resources := container.Resources
-			totalCPURequest += resources.Requests.Cpu().MilliValue()
-			totalCPULimit += resources.Limits.Cpu().MilliValue()
-			totalMemoryRequest += resources.Requests.Memory().Value() / (1024 * 1024) // Convert bytes to MiB
-			totalMemoryLimit += resources.Limits.Memory().Value() / (1024 * 1024)     // Convert bytes to MiB
+	if kinds[kindStatefulSet] {
+		var statefulSets *appsv1.StatefulSetList
+		err := retryTransient("list statefulsets", func() (err error) {
+			statefulSets, err = clientset.AppsV1().StatefulSets(namespace).List(ctx, selectorListOptions())
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list statefulsets: %w", err)
 		}
+		for _, sts := range statefulSets.Items {
+			if !keepWorkload(sts.ObjectMeta) {
+				continue
+			}
+			build = append(build, func() DeploymentInfo { return objects.statefulSetInfo(sts) })
+		}
+	}
 
-		info.CPURequest = fmt.Sprintf("%dm", totalCPURequest)
-		info.CPULimit = fmt.Sprintf("%dm", totalCPULimit)
-		info.MemoryRequest = fmt.Sprintf("%dMi", totalMemoryRequest)
-		info.MemoryLimit = fmt.Sprintf("%dMi", totalMemoryLimit)
+	if kinds[kindDaemonSet] {
+		var daemonSets *appsv1.DaemonSetList
+		err := retryTransient("list daemonsets", func() (err error) {
+			daemonSets, err = clientset.AppsV1().DaemonSets(namespace).List(ctx, selectorListOptions())
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list daemonsets: %w", err)
+		}
+		for _, ds := range daemonSets.Items {
+			if !keepWorkload(ds.ObjectMeta) {
+				continue
+			}
+			build = append(build, func() DeploymentInfo { return objects.daemonSetInfo(ds) })
+		}
+	}
 
+	results := make([]DeploymentInfo, len(build))
+	bar := newProgress("Collecting", len(build))
+	forEachConcurrently(*concurrency, len(build), func(i int) {
+		info := build[i]()
+		// Compute the user-defined column from the external command hook (if configured).
+		if customColumnEnabled() {
+			info.CustomColumn = runCustomColumn(info.Name, info.Namespace)
+		}
+		info.Warnings = workloadWarnings(info)
+		results[i] = info
+		bar.step()
+	})
+	sortDeploymentInfo(results)
+	return results, nil
+}
 
-		// Get `maxUnavailable` dan `maxSurge` dari RollingUpdate Strategy
-		if deploy.Spec.Strategy.Type == "RollingUpdate" {
-			if deploy.Spec.Strategy.RollingUpdate != nil {
-				if deploy.Spec.Strategy.RollingUpdate.MaxUnavailable != nil {
-					info.MaxUnavailable = deploy.Spec.Strategy.RollingUpdate.MaxUnavailable.String()
-				}
+// csvHeader returns the column names of the generated CSV, including any optional columns enabled by
+// flags. containers lists the per-container column groups of the -wide output (nil otherwise).
+func csvHeader(containers []string) []string {
+	// Write the CSV header with a new "Number" column.
+	header := []string{
+		"No", "Deployment Name", "Namespace", "Replicas",
+		"CPU Request", "CPU Limit", "Memory Request", "Memory Limit",
+		"MaxUnavailable", "MaxSurge", "Min Replicas", "Max Replicas", "CPU Target Utilization", "ScaleUp Stabilization",
+		"ScaleDown Stabilization", "UpdateResourceAndHPA", "UpdateHPAOnly",
+		"ScaleUp Policies", "ScaleDown Policies", "Memory Target Utilization", "Resource Version", "HPA Resource Version",
+		"Missing Replicas", "Replica Issue", "Conditions", "Profile", "Kind",
+		"Ready Replicas", "Available Replicas", "Other Metrics",
+		"PDB", "PDB Min Available", "PDB Max Unavailable", "Image", "Context", "Warnings", "Strategy",
+		"Current Replicas", "Desired Replicas", "CPU Current Utilization",
+		"Ephemeral Storage Request", "Ephemeral Storage Limit", "Extended Resources", "UpdateReplicas",
+		"Last Rollout", "Age",
+	}
+	if customColumnEnabled() {
+		header = append(header, *customColumnName)
+	}
+	if *includeServices {
+		header = append(header, "Services")
+	}
+	header = append(header, securityHeader()...)
+	header = append(header, wideHeader(containers)...)
+	return header
+}
 
-				if deploy.Spec.Strategy.RollingUpdate.MaxSurge != nil {
-					info.MaxSurge = deploy.Spec.Strategy.RollingUpdate.MaxSurge.String()
-				} 
-			}
+// csvRecord renders the i-th DeploymentInfo as a CSV row matching csvHeader.
+func csvRecord(i int, deploy DeploymentInfo, containers []string) []string {
+	// Kinds without a replica count (DaemonSets) have N/A in the replica and HPA columns.
+	replicaCell := func(value int32) string {
+		if !hasReplicaCount(deploy.Kind) {
+			return "N/A"
 		}
-
-		// Match HPA with the deployment (if available).
-		for _, hpa := range hpaList.Items {
-			if hpa.Spec.ScaleTargetRef.Name == deploy.Name && hpa.Spec.ScaleTargetRef.Kind == "Deployment" {
-				if hpa.Spec.MinReplicas != nil {
-					info.MinReplicas = *hpa.Spec.MinReplicas
-				} else {
-					info.MinReplicas = 1 // Default to 1 if MinReplicas is not set.
-				}
-				info.MaxReplicas = hpa.Spec.MaxReplicas
-
-				// Extract CPU target utilization
-				for _, metric := range hpa.Spec.Metrics {
-					if metric.Type == autoscalingv2.ResourceMetricSourceType && metric.Resource != nil {
-						if metric.Resource.Name == v1.ResourceCPU && metric.Resource.Target.AverageUtilization != nil {
-							info.CPUTargetUtilization = *metric.Resource.Target.AverageUtilization
-						}
-					}
-				}
-
-				// Extract ScaleUp and ScaleDown behaviors
-				if hpa.Spec.Behavior != nil {
-					// ScaleUp
-					if hpa.Spec.Behavior.ScaleUp != nil {
-						info.ScaleUpStabilization = hpa.Spec.Behavior.ScaleUp.StabilizationWindowSeconds
-					}
-
-					// ScaleDown
-					if hpa.Spec.Behavior.ScaleDown != nil {
-						info.ScaleDownStabilization = hpa.Spec.Behavior.ScaleDown.StabilizationWindowSeconds
-					}
-				}
-				break
-			}
+		return strconv.Itoa(int(value))
+	}
+	// Workloads without an HPA have N/A in the HPA columns, so a missing HPA isn't mistaken for 0.
+	hpaCell := func(value int32) string {
+		if !deploy.HasHPA {
+			return "N/A"
 		}
+		return replicaCell(value)
+	}
+	maxUnavailable, maxSurge := rollingUpdateCells(deploy)
+	record := []string{
+		strconv.Itoa(i + 1), // Row number (starting from 1)
+		deploy.Name,
+		deploy.Namespace,
+		replicaCell(deploy.Replicas),
+		deploy.CPURequest,
+		deploy.CPULimit,
+		deploy.MemoryRequest,
+		deploy.MemoryLimit,
+		maxUnavailable,
+		maxSurge,
+		hpaCell(deploy.MinReplicas),
+		hpaCell(deploy.MaxReplicas),
+		hpaCell(deploy.CPUTargetUtilization),
+
+		// Check if ScaleUpStabilization is nil before converting it to a string
+		func() string {
+			if deploy.ScaleUpStabilization != nil {
+				return strconv.Itoa(int(*deploy.ScaleUpStabilization))
+			}
+			return "N/A" // Default value if nil
+		}(),
 
-		results = append(results, info)
+		// Check if ScaleDownStabilization is nil before converting it to a string
+		func() string {
+			if deploy.ScaleDownStabilization != nil {
+				return strconv.Itoa(int(*deploy.ScaleDownStabilization))
+			}
+			return "N/A"
+		}(),
+
+		strconv.FormatBool(deploy.UpdateResourceAndHPA),
+		strconv.FormatBool(deploy.UpdateHPAOnly),
+		deploy.ScaleUpPolicies,
+		deploy.ScaleDownPolicies,
+		func() string {
+			if deploy.MemoryTargetUtilization != nil {
+				return strconv.Itoa(int(*deploy.MemoryTargetUtilization))
+			}
+			return "N/A"
+		}(),
+		deploy.ResourceVersion,
+		deploy.HPAResourceVersion,
+		replicaCell(deploy.missingReplicas()),
+		deploy.ReplicaIssue,
+		deploy.Conditions,
+		deploy.Profile,
+		deploy.Kind,
+		replicaCell(deploy.ReadyReplicas),
+		replicaCell(deploy.AvailableReplicas),
+		deploy.OtherMetrics,
 	}
-	return results, nil
+	record = append(record, pdbCells(deploy)...)
+	record = append(record, deploy.Image, deploy.Context, deploy.Warnings, deploy.Strategy)
+	record = append(record, hpaCell(deploy.HPACurrentReplicas), hpaCell(deploy.HPADesiredReplicas))
+	// N/A without an HPA and until metrics-server reports the CPU usage.
+	if deploy.CPUCurrentUtilization != nil {
+		record = append(record, strconv.Itoa(int(*deploy.CPUCurrentUtilization)))
+	} else {
+		record = append(record, "N/A")
+	}
+	record = append(record, deploy.EphemeralStorageRequest, deploy.EphemeralStorageLimit, deploy.ExtendedResources, strconv.FormatBool(deploy.UpdateReplicas))
+	record = append(record, deploy.LastRollout, deploy.Age)
+	if customColumnEnabled() {
+		record = append(record, deploy.CustomColumn)
+	}
+	if *includeServices {
+		record = append(record, deploy.Services)
+	}
+	record = append(record, securityRecord(deploy)...)
+	record = append(record, wideRecord(deploy, containers)...)
+	return record
 }
 
 // writeCSV saves the DeploymentInfo data into a CSV file with progress animation.
-func writeCSV(data []DeploymentInfo) error {
-	file, err := os.Create("deployment-info.csv")
-	if err != nil {
-		return fmt.Errorf("failed to create CSV file: %w", err)
+func writeCSV(data []DeploymentInfo, path string) error {
+	out := pipeOutput
+	if path != stdoutPath {
+		file, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("failed to create CSV file: %w", err)
+		}
+		defer file.Close()
+		out = file
 	}
-	defer file.Close()
 
-	writer := csv.NewWriter(file)
-	writer.Comma = '|'
+	writer := csv.NewWriter(out)
+	writer.Comma = csvDelimiter()
 	defer writer.Flush()
 
-	// Write the CSV header with a new "Number" column.
-	if err := writer.Write([]string{
-		"No", "Deployment Name", "Namespace", "Replicas",
-		"CPU Request", "CPU Limit", "Memory Request", "Memory Limit",
-		"MaxUnavailable", "MaxSurge", "Min Replicas", "Max Replicas", "CPU Target Utilization", "ScaleUp Stabilization", 
-		"ScaleDown Stabilization", "UpdateResourceAndHPA", "UpdateHPAOnly",
-	}); err != nil {
+	containers := wideContainerNames(data)
+	if err := writer.Write(csvHeader(containers)); err != nil {
 		return fmt.Errorf("failed to write CSV header: %w", err)
 	}
 
-	// Write each DeploymentInfo as a row in the CSV with progress messages.
+	// Write each DeploymentInfo as a row in the CSV.
 	for i, deploy := range data {
-		record := []string{
-			strconv.Itoa(i + 1), // Row number (starting from 1)
-			deploy.Name,
-			deploy.Namespace,
-			strconv.Itoa(int(deploy.Replicas)),
-			deploy.CPURequest,
-			deploy.CPULimit,
-			deploy.MemoryRequest,
-			deploy.MemoryLimit,
-			deploy.MaxUnavailable,
-			deploy.MaxSurge,
-			strconv.Itoa(int(deploy.MinReplicas)),
-			strconv.Itoa(int(deploy.MaxReplicas)),
-			strconv.Itoa(int(deploy.CPUTargetUtilization)),
-		
-			// Check if ScaleUpStabilization is nil before converting it to a string
-			func() string {
-				if deploy.ScaleUpStabilization != nil {
-					return strconv.Itoa(int(*deploy.ScaleUpStabilization))
-				}
-				return "N/A" // Default value if nil
-			}(),
-
-			// Check if ScaleDownStabilization is nil before converting it to a string
-			func() string {
-				if deploy.ScaleDownStabilization != nil {
-					return strconv.Itoa(int(*deploy.ScaleDownStabilization))
-				}
-				return "N/A"
-			}(),
-
-			"false",
-			"false",
-		}
-
-		if err := writer.Write(record); err != nil {
+		if err := writer.Write(csvRecord(i, deploy, containers)); err != nil {
 			return fmt.Errorf("failed to write CSV record: %w", err)
 		}
-
-		// Show progress animation with progress bar.
-		showSpinner(i+1, len(data), deploy.Name)
 	}
 	return nil
 }
 
-// showSpinner displays an animated progress bar with percentage and progress indicator.
-func showSpinner(current, total int, name string) {
+// showSpinner displays an animated progress bar with percentage and progress indicator. It is
+// redrawn as work items finish, so it moves as fast as the API and custom column commands answer.
+// When stdout is not a terminal (CI logs, pipes, files) the \r animation would garble the output,
+// so plain progress lines are logged instead, one per 10%.
+func showSpinner(current, total int, verb string) {
+	if *quiet || *watch || *output == stdoutPath || total == 0 {
+		return
+	}
+	if !stdoutIsTerminal() {
+		if current == total || (current*10)/total != ((current-1)*10)/total {
+			logger.Info(strings.ToLower(verb)+" workloads", "done", current, "total", total)
+		}
+		return
+	}
 	// Spinner frames for smooth animation.
 	frames := []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
 	frame := frames[current%len(frames)]
@@ -251,242 +427,1112 @@ func showSpinner(current, total int, name string) {
 	bar := strings.Repeat("█", progress) + strings.Repeat(" ", barWidth-progress)
 
 	// Print the spinner, progress bar, percentage, and current task.
-	fmt.Printf("\r%s [%s] %d%% - Writing %d/%d", frame, bar, percentage, current, total)
+	fmt.Printf("\r%s [%s] %d%% - %s %d/%d", frame, bar, percentage, verb, current, total)
 
-	// Flush and wait a bit to slow down the animation (simulate real-time).
-	time.Sleep(100 * time.Millisecond)
-
-	// Ensure the output is flushed immediately.
 	if current == total {
 		fmt.Println() // Move to the next line when done.
 	}
 }
 
+// stdinReader is shared by all prompts so answers piped in on consecutive lines aren't lost to
+// another reader's buffer.
+var stdinReader = bufio.NewReader(os.Stdin)
+
 // confirmPrompt displays a confirmation prompt to the user.
-func confirmPrompt() bool {
+// An empty answer (or closed stdin) declines; anything but Y or N is invalid input.
+func confirmPrompt() (bool, error) {
 	fmt.Print("🎯 visit https://github.com/hendralw for the latest version")
+	printTargets()
 	fmt.Print("\n\nDo you want to proceed with running the script? (Y/N): ")
-	reader := bufio.NewReader(os.Stdin)
-	input, _ := reader.ReadString('\n')
-	input = strings.TrimSpace(strings.ToUpper(input))
-	return input == "Y"
+	input, _ := stdinReader.ReadString('\n')
+	switch input = strings.TrimSpace(strings.ToUpper(input)); input {
+	case "Y":
+		return true, nil
+	case "N", "":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid answer %q, expected Y or N", input)
 }
 
 func actionPrompt() string {
 	fmt.Println("\nSelect an action:")
 	fmt.Println("1: Generate Kubernetes Deployment to CSV")
 	fmt.Println("2: Patch Kubernetes Spec from CSV")
-	fmt.Println("3: Restart All Deployment")
-	fmt.Println("4: Exit")
-	reader := bufio.NewReader(os.Stdin)
-	input, _ := reader.ReadString('\n')
+	fmt.Println("3: Restart Deployment")
+	fmt.Println("4: Restore from backup")
+	fmt.Println("5: Create a CSV template")
+	fmt.Println("6: Exit")
+	input, _ := stdinReader.ReadString('\n')
 	return strings.TrimSpace(input)
 }
 
-func generateDeploymentInfo() {
-	fmt.Println("\n💥 Running the script...\n")
+// restartTargetPrompt asks which deployment action 3 restarts; an empty answer means all of them.
+func restartTargetPrompt() string {
+	fmt.Print("\nDeployment to restart (name or \"all\") [all]: ")
+	input, _ := stdinReader.ReadString('\n')
+	if input = strings.TrimSpace(input); input == "" {
+		return "all"
+	}
+	return input
+}
+
+// collectDeploymentInfo lists the workloads of every -context (the active one by default), tagged
+// with their context and sorted. The namespace listed is returned for messages about empty results.
+func collectDeploymentInfo() ([]DeploymentInfo, string, error) {
+	// With -context the rows of every listed context go into the same file, tagged with their context.
+	contexts := []string{activeContext}
+	if len(contextNames) > 0 {
+		contexts = contextNames
+	}
+	var data []DeploymentInfo
+	var namespace string
+	for _, context := range contexts {
+		activeContext = context
+		clientset, contextNamespace, err := getKubeClient()
+		if err != nil {
+			activeContext = contexts[0]
+			return nil, contextNamespace, err
+		}
+		namespace = contextNamespace
+		if *allNamespaces {
+			namespace = metav1.NamespaceAll
+		}
+		rows, err := getDeploymentInfo(clientset, namespace)
+		if err != nil {
+			activeContext = contexts[0]
+			return nil, namespace, fmt.Errorf("error fetching deployment info from context %q: %w", context, err)
+		}
+		for i := range rows {
+			rows[i].Context = context
+		}
+		data = append(data, rows...)
+	}
+	activeContext = contexts[0]
+	sortDeploymentInfo(data)
+	return data, namespace, nil
+}
+
+func generateDeploymentInfo() error {
+	if *outputFormat != "csv" && *outputFormat != "json" && *outputFormat != "grafana" && *outputFormat != "markdown" {
+		return withExitCode(exitUsage, fmt.Errorf("unknown -format %q (expected csv, json, grafana or markdown)", *outputFormat))
+	}
+
+	if *watch {
+		return watchDeploymentInfo()
+	}
+
+	logger.Info("running the script")
+
+	data, namespace, err := collectDeploymentInfo()
+	if err != nil {
+		return err
+	}
+	for _, deploy := range data {
+		summary.addNamespace(deploy.Namespace)
+	}
+	if len(data) == 0 {
+		if *allNamespaces {
+			return withExitCode(exitNothingToDo, fmt.Errorf("no deployments found in any namespace"))
+		}
+		summary.addNamespace(namespace)
+		return withExitCode(exitNothingToDo, fmt.Errorf("no deployments found in namespace %s", namespace))
+	}
+	if *onlyWarningsFlag {
+		if data = onlyWarnings(data); len(data) == 0 {
+			return withExitCode(exitNothingToDo, fmt.Errorf("no workloads with warnings"))
+		}
+	}
+	if *noHPAFlag {
+		if data = withoutHPA(data); len(data) == 0 {
+			return withExitCode(exitNothingToDo, fmt.Errorf("every workload has an HPA"))
+		}
+	}
+
+	// Resolve the -wide container columns of -mask-columns before any file is written.
+	if *maskColumns != "" {
+		if _, err := maskedColumnIndexes(csvHeader(wideContainerNames(data)), *maskColumns); err != nil {
+			return withExitCode(exitUsage, err)
+		}
+	}
 
-	clientset, namespace := getKubeClient()
-	data, err := getDeploymentInfo(clientset, namespace)
+	paths, files, err := outputFiles(data)
 	if err != nil {
-		log.Fatalf("💢 Error fetching deployment info: %v", err)
+		return err
+	}
+	for _, path := range paths {
+		rows := files[path]
+		switch *outputFormat {
+		case "grafana":
+			if err := writeGrafanaJSON(rows, path); err != nil {
+				return fmt.Errorf("error writing Grafana snapshot: %w", err)
+			}
+			logger.Info("Grafana snapshot created", "path", path)
+		case "json":
+			if err := writeJSON(rows, path); err != nil {
+				return fmt.Errorf("error writing JSON: %w", err)
+			}
+			logger.Info("JSON file created", "path", path)
+		case "markdown":
+			if err := writeMarkdownTable(rows, path); err != nil {
+				return fmt.Errorf("error writing Markdown table: %w", err)
+			}
+			logger.Info("Markdown table created", "path", path)
+		default:
+			if err := writeCSV(rows, path); err != nil {
+				return fmt.Errorf("error writing CSV: %w", err)
+			}
+			if path == stdoutPath {
+				logger.Info("CSV written to stdout")
+				continue
+			}
+			logger.Info("CSV file created", "path", path)
+		}
+	}
+	if count := multiContainerCount(data); *outputFormat == "csv" && count > 0 && !*wide {
+		logger.Info(fmt.Sprintf("found %d %s with more than one container; their rows hold summed resources and can only be patched from a CSV generated with -wide", count, plural(count, "deployment")))
+	}
+	summary.Action, summary.Succeeded = "generated", len(data)
+
+	if *printTableFlag {
+		printDeploymentTable(data)
+	}
+
+	if *maskColumns != "" {
+		if err := writeMaskedCSV(data, *maskedOutput); err != nil {
+			return fmt.Errorf("error writing masked CSV: %w", err)
+		}
+		logger.Info("masked CSV file created, keep the unmasked CSV for patching", "path", *maskedOutput)
+	}
+
+	if *reconcileFile != "" {
+		if err := writeReconcilePlan(data, *reconcileFile, "reconcile-plan.csv"); err != nil {
+			return fmt.Errorf("error writing reconciliation plan: %w", err)
+		}
+		logger.Info("reconciliation plan created, review it and use it as deployment-info.csv to apply it with the patch action", "path", "reconcile-plan.csv")
+	}
+
+	if *hpaReport {
+		if err := writeHPACoverageReport(data, "hpa-coverage.csv"); err != nil {
+			return fmt.Errorf("error writing HPA coverage report: %w", err)
+		}
+		logger.Info("HPA coverage report created", "path", "hpa-coverage.csv")
+	}
+
+	if *capacitySummary {
+		var capacity *nodeCapacity
+		if *includeNodeCapacity {
+			clientset, _, err := getKubeClient()
+			if err == nil {
+				capacity, err = clusterCapacity(clientset)
+			}
+			if err != nil {
+				logger.Warn("the capacity summary has no allocatable percentages", "err", err)
+			} else {
+				logger.Info("cluster allocatable", "nodes", capacity.Nodes, "cpu", fmt.Sprintf("%dm", capacity.CPU), "memory", fmt.Sprintf("%dMi", capacity.Memory))
+			}
+		}
+		if err := writeCapacitySummary(data, "summary.csv", capacity); err != nil {
+			return fmt.Errorf("error writing capacity summary: %w", err)
+		}
+		logger.Info("capacity summary created", "path", "summary.csv")
 	}
 
-	if err := writeCSV(data); err != nil {
-		log.Fatalf("💢 Error writing CSV: %v", err)
+	if *teamReport {
+		if err := writeTeamReport(data, *teamLabel, "team-report.csv"); err != nil {
+			return fmt.Errorf("error writing team report: %w", err)
+		}
+		logger.Info("team report created", "path", "team-report.csv")
 	}
 
-	fmt.Println("\n✅ CSV file 'deployment-info.csv' created successfully.")
+	if *lint {
+		findings := lintDeployments(data)
+		if err := writeFindings(findings, "deployment-findings.csv"); err != nil {
+			return fmt.Errorf("error writing findings report: %w", err)
+		}
+		logger.Info("findings report created", "path", "deployment-findings.csv", "findings", len(findings))
+	}
+	return nil
+}
+
+// restartCandidates returns the deployments a restart of target acts on: the named deployment,
+// checked to exist, or with "all" every deployment of the namespace, narrowed by -since. Every
+// restart mode (plain, -dry-run, -canary and -restart-order-annotation) restarts exactly these.
+func restartCandidates(clientset kubernetes.Interface, namespace, target string) ([]appsv1.Deployment, error) {
+	ctx, cancel := apiContext()
+	defer cancel()
+
+	var candidates []appsv1.Deployment
+	if target != "all" {
+		var deploy *appsv1.Deployment
+		err := retryTransient("get deployment "+target, func() (err error) {
+			deploy, err = clientset.AppsV1().Deployments(namespace).Get(ctx, target, metav1.GetOptions{})
+			return err
+		})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, withExitCode(exitUsage, fmt.Errorf("deployment %s not found in namespace %s", target, namespace))
+			}
+			return nil, fmt.Errorf("failed to get deployment %s: %w", target, err)
+		}
+		candidates = []appsv1.Deployment{*deploy}
+	} else {
+		var deployments *appsv1.DeploymentList
+		err := retryTransient("list deployments", func() (err error) {
+			deployments, err = clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list deployments: %w", err)
+		}
+		if len(deployments.Items) == 0 {
+			return nil, withExitCode(exitNothingToDo, fmt.Errorf("no deployments found in namespace %s", namespace))
+		}
+		candidates = deployments.Items
+	}
+	candidates, err := filterSince(clientset, candidates)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, withExitCode(exitNothingToDo, fmt.Errorf("no deployment in namespace %s has pods running for more than %s", namespace, *since))
+	}
+	return candidates, nil
 }
 
-// restarts a specific deployment or all deployments in the specified namespace.
+// restarts a specific deployment or all deployments in the specified namespace. Like kubectl
+// rollout restart it stamps the restartedAt annotation on the pod template, but through the API so
+// kubectl doesn't have to be installed. A failing deployment doesn't stop the others; the error
+// reports how many were restarted and names the failed ones, e.g. "Restarted 12/15, 3 failed: [a b c]".
 func restartDeployment(deploymentName string) error {
-	_, namespace := getKubeClient()
-	var cmd *exec.Cmd
-
-	// Restart all deployments in the namespace.
-	cmd = exec.Command(
-		"kubectl", "rollout", "restart", "deployment", "--all",
-		"-n", namespace,
-	)
-
-	//if deploymentName == "all" {
-	//	// Restart all deployments in the namespace.
-	//	cmd = exec.Command(
-	//		"kubectl", "rollout", "restart", "deployment", "--all",
-	//		"-n", namespace,
-	//	)
-	//} else {
-	//	// Restart a specific deployment.
-	//	cmd = exec.Command(
-	//		"kubectl", "rollout", "restart", "deployment", deploymentName,
-	//		"-n", namespace,
-	//	)
-	//}
-
-	// Print the command to debug.
-	fmt.Println("\n💻 Executing command:", strings.Join(cmd.Args, " "))
+	clientset, namespace, err := getKubeClient()
+	if err != nil {
+		return err
+	}
+	summary.Action = "restarted"
+	summary.addNamespace(namespace)
 
-	output, err := cmd.CombinedOutput()
+	candidates, err := restartCandidates(clientset, namespace, deploymentName)
 	if err != nil {
-		return fmt.Errorf("💢 kubectl rollout restart error: %v\n%s", err, string(output))
+		return err
+	}
+	names := make([]string, len(candidates))
+	for i, deploy := range candidates {
+		names[i] = deploy.Name
+	}
+
+	defer handleInterrupts()()
+	var errs []error
+	var failed []string
+	for i, name := range names {
+		if isInterrupted() {
+			report := fmt.Sprintf("Restarted %d/%d, %d failed, %d not restarted: %v", summary.Succeeded, len(names), len(failed), len(names)-i, names[i:])
+			return withExitCode(exitInterrupted, fmt.Errorf("interrupted: %s", report))
+		}
+		if err := triggerRollout(clientset, namespace, name); err != nil {
+			logger.Error("restart failed", "err", err)
+			errs = append(errs, err)
+			failed = append(failed, name)
+			summary.Failed++
+			continue
+		}
+		logger.Info("deployment restarted", "namespace", namespace, "name", name)
+		summary.Succeeded++
+	}
+	if len(errs) > 0 {
+		report := fmt.Sprintf("Restarted %d/%d, %d failed: %v", len(names)-len(failed), len(names), len(failed), failed)
+		return withExitCode(exitPartialFailure, fmt.Errorf("%s: %w", report, errors.Join(errs...)))
 	}
 
 	if deploymentName == "all" {
-		fmt.Printf("✅ All deployments restarted in namespace %s\n", namespace)
+		logger.Info(fmt.Sprintf("Restarted %d/%d", len(names), len(names)), "namespace", namespace)
 	} else {
-		fmt.Printf("✅ Rollout restarted for deployment %s in namespace %s\n", deploymentName, namespace)
+		logger.Info("rollout restarted", "namespace", namespace, "name", deploymentName)
 	}
 	return nil
 }
 
-// PATCH: Function for action 2 - Update Kubernetes specs from CSV
-func patchKubeResourcesFromCSV() error {
-	file, err := os.Open("deployment-info.csv")
+// patchRow holds the values of a single CSV row that the patch action applies.
+type patchRow struct {
+	Kind                    string // Deployment, StatefulSet or DaemonSet
+	Context                 string // kubeconfig context to patch through, "" for the active one
+	Strategy                string // Deployment strategy at generate time, "" in files without the column
+	DeploymentName          string
+	Namespace               string
+	CPURequest              string
+	CPULimit                string
+	MemoryRequest           string
+	MemoryLimit             string
+	EphemeralStorageRequest string // "" or "0" leaves ephemeral-storage unchanged
+	EphemeralStorageLimit   string
+	MaxUnavailable          string
+	MaxSurge                string
+	Replicas                string // applied to workloads without an HPA, see replicaTarget
+	MinReplicas             int
+	MaxReplicas             int
+	CPUTargetUtilization    int
+	MemoryTargetUtilization *int // nil leaves the memory metric of the HPA unchanged
+	ScaleUpStabilization    *int // nil (N/A) leaves the stabilization window of the HPA unchanged
+	ScaleDownStabilization  *int
+	ScaleUpPolicies         string // empty leaves the live policies untouched
+	ScaleDownPolicies       string
+	ResourceVersion         string // recorded at generate time, used by -check-resource-version
+	HPAResourceVersion      string
+	PDBName                 string // "" when the workload has no PodDisruptionBudget
+	PDBMinAvailable         string // at most one of the two budgets is set
+	PDBMaxUnavailable       string
+	UpdateResourceAndHPA    bool
+	UpdateHPAOnly           bool
+	UpdateReplicas          bool
+	Profile                 string               // named profile from the config file, resolved by applyProfile
+	ParseErrors             []string             `json:"-"` // cells that could not be parsed, the row is not applied
+	Containers              []ContainerResources // per-container values from -wide columns
+}
+
+// resources returns the aggregate resource columns of the row, which apply to the workload's only
+// container when the row has no per-container values (see aggregateRowContainer).
+func (row patchRow) resources() ContainerResources {
+	return ContainerResources{
+		CPURequest:              row.CPURequest,
+		CPULimit:                row.CPULimit,
+		MemoryRequest:           row.MemoryRequest,
+		MemoryLimit:             row.MemoryLimit,
+		EphemeralStorageRequest: row.EphemeralStorageRequest,
+		EphemeralStorageLimit:   row.EphemeralStorageLimit,
+	}
+}
+
+// csvLayout maps the column names of a CSV file to their position, based on its header, so
+// columns may be reordered. Optional columns added over time are simply missing from files
+// written by older versions.
+type csvLayout struct {
+	columns map[string]int
+	wide    []wideColumns
+}
+
+// requiredColumns are the columns every CSV read by the patch action must have.
+var requiredColumns = []string{
+	"Deployment Name", "Namespace", "Replicas", "CPU Request", "CPU Limit", "Memory Request", "Memory Limit",
+	"MaxUnavailable", "MaxSurge", "Min Replicas", "Max Replicas", "CPU Target Utilization",
+	"ScaleUp Stabilization", "ScaleDown Stabilization", "UpdateResourceAndHPA", "UpdateHPAOnly",
+}
+
+func parseCSVLayout(header []string) csvLayout {
+	layout := csvLayout{columns: make(map[string]int), wide: parseWideColumns(header)}
+	for i, name := range header {
+		layout.columns[strings.TrimSpace(name)] = i
+	}
+	return layout
+}
+
+// missingColumns lists the required columns the header lacks, e.g. after a column was renamed
+// in a spreadsheet.
+func (l csvLayout) missingColumns() []string {
+	var missing []string
+	for _, name := range requiredColumns {
+		if _, ok := l.columns[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// cell returns the trimmed value of the named column, or "" when the file has no such column.
+func (l csvLayout) cell(record []string, name string) string {
+	index, ok := l.columns[name]
+	if !ok || index >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[index])
+}
+
+// parsePatchRow extracts the patchable values from a CSV record.
+func parsePatchRow(record []string, layout csvLayout) patchRow {
+	row := patchRow{
+		DeploymentName:       layout.cell(record, "Deployment Name"),
+		Namespace:            layout.cell(record, "Namespace"),
+		Replicas:             layout.cell(record, "Replicas"),
+		CPURequest:           layout.cell(record, "CPU Request"),
+		CPULimit:             layout.cell(record, "CPU Limit"),
+		MemoryRequest:        layout.cell(record, "Memory Request"),
+		MemoryLimit:          layout.cell(record, "Memory Limit"),
+		MaxUnavailable:       rollingUpdateCell(layout.cell(record, "MaxUnavailable")),
+		MaxSurge:             rollingUpdateCell(layout.cell(record, "MaxSurge")),
+		UpdateResourceAndHPA: strings.ToLower(layout.cell(record, "UpdateResourceAndHPA")) == "true",
+		UpdateHPAOnly:        strings.ToLower(layout.cell(record, "UpdateHPAOnly")) == "true",
+		UpdateReplicas:       strings.ToLower(layout.cell(record, "UpdateReplicas")) == "true",
+	}
+	row.Kind = rowKind(layout.cell(record, "Kind"), &row)
+	// A typo must not silently become 0 (e.g. minReplicas "two"), so the row remembers what failed
+	// and the patch loop refuses it. Stabilization windows are exported as N/A when unset, and so
+	// are the HPA columns of workloads without an HPA (always for kinds without a replica count).
+	parseInt := func(field *int, column string, cell string, optional bool) {
+		cell = strings.TrimSpace(cell)
+		if optional && (cell == "" || cell == "N/A") {
+			return
+		}
+		value, err := strconv.Atoi(cell)
+		if err != nil {
+			row.ParseErrors = append(row.ParseErrors, fmt.Sprintf("column %q: %q is not a whole number", column, cell))
+			return
+		}
+		*field = value
+	}
+	parseInt(&row.MinReplicas, "Min Replicas", layout.cell(record, "Min Replicas"), true)
+	parseInt(&row.MaxReplicas, "Max Replicas", layout.cell(record, "Max Replicas"), true)
+	parseInt(&row.CPUTargetUtilization, "CPU Target Utilization", layout.cell(record, "CPU Target Utilization"), true)
+	// Optional settings are nil when N/A or empty, leaving the live value of the HPA unchanged.
+	parseOptionalInt := func(field **int, column string) {
+		if cell := strings.TrimSpace(layout.cell(record, column)); cell != "" && cell != "N/A" {
+			var value int
+			parseInt(&value, column, cell, false)
+			*field = &value
+		}
+	}
+	parseOptionalInt(&row.ScaleUpStabilization, "ScaleUp Stabilization")
+	parseOptionalInt(&row.ScaleDownStabilization, "ScaleDown Stabilization")
+	parseOptionalInt(&row.MemoryTargetUtilization, "Memory Target Utilization")
+	row.ScaleUpPolicies = layout.cell(record, "ScaleUp Policies")
+	row.ScaleDownPolicies = layout.cell(record, "ScaleDown Policies")
+	row.ResourceVersion = layout.cell(record, "Resource Version")
+	row.HPAResourceVersion = layout.cell(record, "HPA Resource Version")
+	row.Profile = layout.cell(record, "Profile")
+	row.Context = layout.cell(record, "Context")
+	row.Strategy = layout.cell(record, "Strategy")
+	row.EphemeralStorageRequest = layout.cell(record, "Ephemeral Storage Request")
+	row.EphemeralStorageLimit = layout.cell(record, "Ephemeral Storage Limit")
+	row.Containers = wideRowContainers(record, layout.wide)
+	parsePDBCells(record, layout, &row)
+	return row
+}
+
+// readPatchRows loads the rows of the file written by the generate action: -input, or by default
+// deployment-info.json with -format=json and deployment-info.csv otherwise. With -input=- the
+// file is read from stdin.
+func readPatchRows() ([]patchRow, error) {
+	path := inputPath()
+	if path == stdinPath {
+		if *outputFormat == "json" {
+			encoded, err := io.ReadAll(stdinReader)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read JSON from stdin: %w", err)
+			}
+			return decodeJSONPatchRows(encoded, "on stdin")
+		}
+		return readCSVPatchRows(stdinReader, "stdin")
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%s not found: run the generate action first or point -input at the file to patch from", path)
+	}
+	if *outputFormat == "json" {
+		return readJSONPatchRows(path)
+	}
+
+	file, err := os.Open(path)
 	if err != nil {
-		return fmt.Errorf("failed to open CSV file: %w", err)
+		return nil, fmt.Errorf("failed to open CSV file: %w", err)
 	}
 	defer file.Close()
+	return readCSVPatchRows(bufio.NewReader(file), path)
+}
 
-	reader := csv.NewReader(file)
-	reader.Comma = '|'
-	_, err = reader.Read() // Skip header row
+// readCSVPatchRows parses a CSV file written by the generate action, detecting its delimiter from
+// the header; name identifies the file in errors.
+func readCSVPatchRows(buffered *bufio.Reader, name string) ([]patchRow, error) {
+	comma, err := sniffDelimiter(buffered)
 	if err != nil {
-		return fmt.Errorf("failed to read CSV header: %w", err)
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	reader := csv.NewReader(buffered)
+	reader.Comma = comma
+	if comma != csvComment {
+		reader.Comment = csvComment // e.g. the example row of the init action's template
+	}
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	layout := parseCSVLayout(header)
+	if missing := layout.missingColumns(); len(missing) > 0 {
+		return nil, fmt.Errorf("%s is missing the required column(s) %q; regenerate it or fix the header (columns may be in any order)", name, missing)
 	}
 
+	var rows []patchRow
 	for {
 		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading CSV: %w", err)
+		}
+		rows = append(rows, parsePatchRow(record, layout))
+	}
+}
+
+// validateReplicaBounds refuses HPA replica bounds that would take the deployment down: a
+// maxReplicas below 1, a minReplicas of 0 unless -allow-zero-min-replicas is set (scale to zero
+// needs the HPAScaleToZero feature gate anyway), or a minReplicas above maxReplicas.
+func validateReplicaBounds(row patchRow) error {
+	switch {
+	case row.MinReplicas == 0 && row.MaxReplicas == 0:
+		return nil // exported without an HPA
+	case row.MaxReplicas < 1:
+		return fmt.Errorf("Max Replicas must be at least 1, got %d", row.MaxReplicas)
+	case row.MinReplicas < 0 || (row.MinReplicas == 0 && !*allowZeroMinReplicas):
+		return fmt.Errorf("Min Replicas must be at least 1, got %d (use -allow-zero-min-replicas to scale to zero)", row.MinReplicas)
+	case row.MinReplicas > row.MaxReplicas:
+		return fmt.Errorf("Min Replicas %d is above Max Replicas %d", row.MinReplicas, row.MaxReplicas)
+	}
+	return nil
+}
+
+// PATCH: Function for action 2 - Update Kubernetes specs from CSV
+func patchKubeResourcesFromCSV() error {
+	if err := validateMaxReplicasAdjustment(); err != nil {
+		return withExitCode(exitUsage, err)
+	}
+
+	rows, err := readPatchRows()
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
+
+	config, err := loadConsoleConfig(*configFile)
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
+	if _, ok := config.Profiles[*defaultProfile]; *defaultProfile != "" && !ok {
+		return withExitCode(exitUsage, fmt.Errorf("unknown profile %q (defined in %s: %v)", *defaultProfile, *configFile, config.profileNames()))
+	}
+
+	// Progress is recorded in the state file so an interrupted run can continue with -resume.
+	state := &patchState{Applied: make(map[string]string)}
+	if *resume {
+		state, err = loadPatchState(*stateFile)
 		if err != nil {
-			if err.Error() == "EOF" {
-				break // End of file reached
+			return withExitCode(exitUsage, err)
+		}
+	} else if *dryRun {
+		// A dry run changes nothing, so it neither resets nor records progress (see markApplied).
+	} else if err := os.Remove(*stateFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to reset state file: %w", err)
+	}
+
+	// Rows failing the checks that need no cluster are reported right away; the cluster is only
+	// contacted once a row actually asks for a change.
+	var pending []patchRow
+	var patched, skipped, declined, failed int
+	var failedRows []string
+	fail := func(row patchRow) {
+		failed++
+		name := row.Namespace + "/" + row.DeploymentName
+		if row.Context != "" {
+			name = row.Context + ": " + name
+		}
+		failedRows = append(failedRows, name)
+	}
+
+	for i, row := range rows {
+		rowNumber := i + 1
+		if !row.UpdateResourceAndHPA && !row.UpdateHPAOnly && !row.UpdateReplicas {
+			continue
+		}
+		summary.addNamespace(row.Namespace)
+		if len(row.ParseErrors) > 0 {
+			logger.Error("row has invalid values, skipping", "row", rowNumber, "name", row.DeploymentName, "problems", row.ParseErrors)
+			fail(row)
+			continue
+		}
+		if err := applyProfile(&row, config); err != nil {
+			logger.Error("invalid profile, skipping", "row", rowNumber, "name", row.DeploymentName, "err", err)
+			fail(row)
+			continue
+		}
+		adjustMaxReplicas(&row)
+		normalizeRowMemory(&row)
+		if err := validateRowQuantities(row); err != nil {
+			logger.Error("row has invalid quantities, skipping", "row", rowNumber, "name", row.DeploymentName, "err", err)
+			fail(row)
+			continue
+		}
+		if !hasReplicaCount(row.Kind) && !row.UpdateResourceAndHPA {
+			logger.Error("DaemonSets have no HPA, set UpdateResourceAndHPA to patch their resources, skipping", "row", rowNumber, "kind", row.Kind, "name", row.DeploymentName)
+			fail(row)
+			continue
+		}
+		// Requests above limits and inverted replica bounds would be rejected by the API server; they
+		// skip the row, or with -strict abort the run before anything is patched.
+		if err := validateRequestsWithinLimits(row); err != nil {
+			if *strict {
+				return withExitCode(exitUsage, fmt.Errorf("row %d (%s): %w", rowNumber, row.DeploymentName, err))
 			}
-			return fmt.Errorf("error reading CSV: %w", err)
-		}
-
-		deploymentName := record[1]
-		namespace := record[2]
-		cpuRequest := record[4]
-		//cpuLimit := record[5]
-		memoryRequest := record[6]
-		memoryLimit := record[7]
-		maxUnavailable := record[8]
-		maxSurge := record[9]
-		minReplicas, _ := strconv.Atoi(record[10])
-		maxReplicas, _ := strconv.Atoi(record[11])
-		cpuTargetUtilization, _ := strconv.Atoi(record[12])
-		scaleUpStabilization, _ := strconv.Atoi(record[13])
-		scaleDownStabilization, _ := strconv.Atoi(record[14])
-
-		// Extract data from CSV row
-		if strings.ToLower(record[15]) == "true" { // UpdateResourceAndHPA 
-			//Run kubectl commands to update deployment resources
-			err = setDeploymentResources(namespace, deploymentName, cpuRequest, memoryRequest, memoryLimit, maxUnavailable, maxSurge)
-			if err != nil {
-				fmt.Printf("\n💢 failed to set resources for deployment %s: %v\n", deploymentName, err)
+			logger.Warn("requests exceed limits, skipping", "row", rowNumber, "name", row.DeploymentName, "err", err)
+			fail(row)
+			continue
+		}
+		if err := validateReplicaBounds(row); err != nil {
+			if *strict {
+				return withExitCode(exitUsage, fmt.Errorf("row %d (%s): %w", rowNumber, row.DeploymentName, err))
 			}
-
-			// Run kubectl command to patch HPA
-			err = patchHPA(deploymentName, namespace, minReplicas, maxReplicas, cpuTargetUtilization, scaleUpStabilization, scaleDownStabilization)
-			if err != nil {
-				fmt.Printf("\n💢 failed to patch HPA for %s: %v\n", deploymentName, err)
+			logger.Warn("invalid replica bounds, skipping", "row", rowNumber, "name", row.DeploymentName, "err", err)
+			fail(row)
+			continue
+		}
+		if err := validateReplicas(row); err != nil {
+			if *strict {
+				return withExitCode(exitUsage, fmt.Errorf("row %d (%s): %w", rowNumber, row.DeploymentName, err))
 			}
-		} else if strings.ToLower(record[16]) == "true" { // UpdateHPAOnly
-			// Run kubectl command to patch HPA
-			err = patchHPA(deploymentName, namespace, minReplicas, maxReplicas, cpuTargetUtilization, scaleUpStabilization, scaleDownStabilization)
-			if err != nil {
-				fmt.Printf("\n💢 failed to patch HPA for %s: %v\n", deploymentName, err)
+			logger.Warn("invalid replica count, skipping", "row", rowNumber, "name", row.DeploymentName, "err", err)
+			fail(row)
+			continue
+		}
+
+		if checksum, ok := state.Applied[rowKey(row)]; ok {
+			if checksum == rowChecksum(row) {
+				logger.Info("already applied by the interrupted run, skipping", "namespace", row.Namespace, "name", row.DeploymentName)
+				skipped++
+				continue
 			}
+			logger.Warn("changed in the CSV since the interrupted run, applying again", "namespace", row.Namespace, "name", row.DeploymentName)
 		}
 
+		pending = append(pending, row)
 	}
 
-	fmt.Println("✅ Kubernetes specs updated successfully!")
+	pending, err = preflightReferences(pending, fail)
+	if err != nil {
+		return err
+	}
+
+	// The remaining steps talk to the cluster, so the rows are applied by -concurrency workers.
+	// Each row's output is collected and printed in one piece once the row is done. Rows of
+	// different contexts are applied one context after the other. Ctrl-C lets the rows being
+	// applied finish and leaves the others untouched.
+	defer handleInterrupts()()
+	var notApplied []string
+	initialContext := activeContext
+	contexts, groups := rowContexts(pending)
+	for _, context := range contexts {
+		pending := groups[context]
+		activeContext = context
+		clientset, _, err := getKubeClient()
+		if err != nil {
+			logger.Error("can't connect to the cluster, rows not applied", "context", context, "err", err)
+			for _, row := range pending {
+				fail(row)
+			}
+			continue
+		}
+		run := &patchRun{
+			clientset:   clientset,
+			metrics:     &metricsPreflight{clientset: clientset},
+			limitRanges: newLimitRangeChecker(clientset),
+			state:       state,
+		}
+		workers := *concurrency
+		if *interactive {
+			workers = 1 // The confirmations read stdin one row at a time.
+		}
+		var mu sync.Mutex
+		forEachConcurrently(workers, len(pending), func(i int) {
+			if isInterrupted() {
+				mu.Lock()
+				defer mu.Unlock()
+				notApplied = append(notApplied, pending[i].Namespace+"/"+pending[i].DeploymentName)
+				return
+			}
+			// A single worker prints directly, so -interactive shows the changes before asking.
+			var buffer bytes.Buffer
+			var out io.Writer = &buffer
+			if workers == 1 {
+				out = os.Stdout
+			}
+			outcome := run.apply(out, pending[i])
+
+			mu.Lock()
+			defer mu.Unlock()
+			os.Stdout.Write(buffer.Bytes())
+			switch outcome {
+			case rowPatched:
+				patched++
+			case rowUpToDate:
+				skipped++
+			case rowDeclined:
+				declined++
+			default:
+				fail(pending[i])
+			}
+		})
+	}
+	activeContext = initialContext
+
+	sort.Strings(failedRows)
+	logger.Info("patch finished", "patched", patched, "skipped", skipped, "declined", declined, "failed", failed)
+	if len(failedRows) > 0 {
+		logger.Error("rows failed to patch", "deployments", failedRows)
+	}
+	action := "patched"
+	if *dryRun {
+		action = "would patch"
+	}
+	summary = runSummary{Action: action, Succeeded: patched, Skipped: skipped + declined, Failed: failed, namespaces: summary.namespaces}
+	if isInterrupted() {
+		sort.Strings(notApplied)
+		if len(notApplied) > 0 {
+			logger.Warn("rows not applied because of the interrupt", "deployments", notApplied)
+		}
+		return withExitCode(exitInterrupted, fmt.Errorf("interrupted: %d patched, %d failed, %d not applied; rerun with -resume to apply the rest", patched, failed, len(notApplied)))
+	}
+	if failed > 0 {
+		return withExitCode(exitPartialFailure, fmt.Errorf("%d of %d deployment(s) failed to patch", failed, patched+failed))
+	}
+	if patched == 0 {
+		return withExitCode(exitNothingToDo, fmt.Errorf("nothing to patch: no row needed a change"))
+	}
+	logger.Info("Kubernetes specs updated successfully")
 	return nil
 }
 
-// Helper function to set deployment resources using kubectl
-func setDeploymentResources(namespace, deploymentName, cpuReq, memReq, memLim, maxUnavailable, maxSurge string) error {
-	cmd := exec.Command(
-		"kubectl", "set", "resources", "deployment", deploymentName,
-		"--namespace="+namespace,
-		fmt.Sprintf("--requests=cpu=%s,memory=%s", cpuReq, memReq),
-		fmt.Sprintf("--limits=memory=%s", memLim),
-	)
+// Helper function to set the resources of the row's container using kubectl. The row's resourceVersion
+// is used as a precondition; the rolling update patch goes first because kubectl set resources can't
+// carry one. StatefulSets and Recreate Deployments only get their resources set (see patchesRollingUpdate).
+func setDeploymentResources(out io.Writer, row patchRow, container string) error {
+	if patchesRollingUpdate(out, row) {
+		if err := patchRollingUpdate(out, row.Namespace, row.DeploymentName, row.MaxUnavailable, row.MaxSurge, precondition(row.ResourceVersion)); err != nil {
+			return err
+		}
+	}
+	return setContainerResources(out, row.Kind, row.Namespace, row.DeploymentName, container, row.resources())
+}
+
+// setContainerResources runs kubectl set resources for a single container, or for every container
+// of the workload when container is empty.
+func setContainerResources(out io.Writer, kind, namespace, deploymentName, container string, want ContainerResources) error {
+	args := []string{
+		"set", "resources", kubectlKind(kind), deploymentName,
+		"--namespace=" + namespace,
+	}
+	if container != "" {
+		args = append(args, "--containers="+container)
+	}
+	// Unset requests are exported as 0 and only sent when set, so re-running an unedited row of a
+	// container without requests doesn't patch an explicit zero request (and roll the pods).
+	var requests []string
+	if hasLimit(want.CPURequest) {
+		requests = append(requests, "cpu="+want.CPURequest)
+	}
+	if hasLimit(want.MemoryRequest) {
+		requests = append(requests, "memory="+want.MemoryRequest)
+	}
+	if hasLimit(want.EphemeralStorageRequest) {
+		requests = append(requests, "ephemeral-storage="+want.EphemeralStorageRequest)
+	}
+	if len(requests) > 0 {
+		args = append(args, "--requests="+strings.Join(requests, ","))
+	}
+	// Unset limits are exported as 0 and must not be sent: a limit of 0 is below the request.
+	var limits []string
+	if hasLimit(want.CPULimit) {
+		limits = append(limits, "cpu="+want.CPULimit)
+	}
+	if hasLimit(want.MemoryLimit) {
+		limits = append(limits, "memory="+want.MemoryLimit)
+	}
+	if hasLimit(want.EphemeralStorageLimit) {
+		limits = append(limits, "ephemeral-storage="+want.EphemeralStorageLimit)
+	}
+	if len(limits) > 0 {
+		args = append(args, "--limits="+strings.Join(limits, ","))
+	}
+	if len(requests) == 0 && len(limits) == 0 {
+		loggerTo(out).Info("no requests or limits to set", "namespace", namespace, "kind", kind, "name", deploymentName, "container", container)
+		return nil
+	}
+	cmd := kubectlCommand(args...)
 
-	fmt.Println("\n💻 Executing command: ", cmd.String())
+	log := loggerTo(out)
+	log.Info("executing command", "cmd", cmd.String())
+	if skipForDryRun(out) {
+		return nil
+	}
 
+	throttleKubectl()
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("kubectl set resources error: %v\n%s", err, string(output))
 	}
-	fmt.Printf("✅ Resources updated for deployment %s\n", deploymentName)
+	log.Info("resources updated", "namespace", namespace, "kind", kind, "name", deploymentName, "container", container)
+	return nil
+}
 
-	// Update rolling update strategy
-	patchData := fmt.Sprintf(`{"spec":{"strategy":{"type":"RollingUpdate","rollingUpdate":{"maxUnavailable":"%s","maxSurge":"%s"}}}}`, maxUnavailable, maxSurge)
+// patchRollingUpdate updates the rolling update strategy of the deployment.
+func patchRollingUpdate(out io.Writer, namespace, deploymentName, maxUnavailable, maxSurge, resourceVersion string) error {
+	patchData := fmt.Sprintf(`{%s"spec":{"strategy":{"type":"RollingUpdate","rollingUpdate":{"maxUnavailable":"%s","maxSurge":"%s"}}}}`, metadataPrecondition(resourceVersion), maxUnavailable, maxSurge)
 
-	cmd = exec.Command(
-		"kubectl", "patch", "deployment", deploymentName, 
-		"--namespace="+namespace, 
+	cmd := kubectlCommand(
+		"patch", "deployment", deploymentName,
+		"--namespace="+namespace,
 		"--type=merge", "-p", patchData)
 
-	fmt.Println("\n💻 Executing command: ", cmd.String())	
+	log := loggerTo(out)
+	log.Info("executing command", "cmd", cmd.String())
+	if skipForDryRun(out) {
+		return nil
+	}
 
-	output, err = cmd.CombinedOutput()
+	throttleKubectl()
+	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("kubectl patch rolling update error: %v\n%s", err, string(output))
 	}
-	fmt.Printf("✅ Rolling updated for deployment %s\n", deploymentName)
+	log.Info("rolling update updated", "namespace", namespace, "name", deploymentName)
 
 	return nil
 }
 
-// Helper function to patch HPA using kubectl
-func patchHPA(hpaName, namespace string, minReplicas, maxReplicas, cpuTargetUtilization, scaleUpStabilization, scaleDownStabilization int) error {
-	// Create JSON patch data
-	patchData := fmt.Sprintf(`{"spec":{"minReplicas":%d,"maxReplicas":%d,"metrics":[{"type":"Resource","resource":{"name":"cpu","target":{"type":"Utilization","averageUtilization":%d}}}],"behavior":{"scaleUp":{"stabilizationWindowSeconds":%d},"scaleDown":{"stabilizationWindowSeconds":%d}}}}`, minReplicas, maxReplicas, cpuTargetUtilization, scaleUpStabilization, scaleDownStabilization)
+// patchHPA updates the HPA's replica bounds, CPU utilization target and behavior. The HPA is read
+// and written back through the API so metrics other than CPU (memory, custom, external) are kept
+// instead of being replaced by a merge patch. A CPU target of 0, exported for HPAs that don't scale
+// on CPU, leaves the metrics unchanged. A non-empty resourceVersion makes the update fail
+// with a conflict if the HPA changed since the CSV was generated.
+func patchHPA(out io.Writer, clientset kubernetes.Interface, hpaName, namespace string, minReplicas, maxReplicas, cpuTargetUtilization int, memoryTargetUtilization *int, scaleUpStabilization, scaleDownStabilization *int, scaleUpPolicies, scaleDownPolicies, resourceVersion string) error {
+	ctx, cancel := apiContext()
+	defer cancel()
+
+	var hpa *autoscalingv2.HorizontalPodAutoscaler
+	err := retryTransient("get HPA "+hpaName, func() (err error) {
+		hpa, err = getHPA(ctx, clientset, namespace, hpaName)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get HPA %s: %w", hpaName, err)
+	}
 
-	cmd := exec.Command(
-		"kubectl", "patch", "hpa", hpaName,
-		"--namespace="+namespace,
-		"--type=merge", "-p", patchData)
-	
-	fmt.Println("\n💻 Executing command: ", cmd.String())
+	// Windows left N/A and empty policy cells keep the live behavior, so HPAs without one (and
+	// autoscaling/v1 HPAs, which have none) keep the controller defaults.
+	if err := applyBehavior(&hpa.Spec, scaleUpStabilization, scaleDownStabilization, scaleUpPolicies, scaleDownPolicies); err != nil {
+		return fmt.Errorf("invalid HPA behavior for %s: %w", hpaName, err)
+	}
+	minReplicas32 := int32(minReplicas)
+	hpa.Spec.MinReplicas = &minReplicas32
+	hpa.Spec.MaxReplicas = int32(maxReplicas)
+	if cpuTargetUtilization > 0 {
+		setUtilizationTarget(&hpa.Spec, v1.ResourceCPU, int32(cpuTargetUtilization))
+	}
+	if memoryTargetUtilization != nil {
+		setUtilizationTarget(&hpa.Spec, v1.ResourceMemory, int32(*memoryTargetUtilization))
+	}
+	if resourceVersion != "" {
+		hpa.ResourceVersion = resourceVersion
+	}
 
-	output, err := cmd.CombinedOutput()
+	log := loggerTo(out)
+	log.Info("updating HPA", "namespace", namespace, "name", hpaName, "minReplicas", minReplicas, "maxReplicas", maxReplicas, "cpuTargetUtilization", utilizationLabel(cpuTargetUtilization), "scaleUpStabilization", stabilizationLabel(scaleUpStabilization), "scaleDownStabilization", stabilizationLabel(scaleDownStabilization))
+	var dropped []string
+	err = retryTransient("update HPA "+hpaName, func() (err error) {
+		dropped, err = updateHPA(ctx, clientset, hpa, metav1.UpdateOptions{DryRun: dryRunAll()})
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("💢 kubectl patch hpa error: %v\n%s", err, string(output))
+		return fmt.Errorf("failed to update HPA %s: %w", hpaName, err)
+	}
+	if len(dropped) > 0 {
+		log.Warn("autoscaling/v1 HPA, settings not applied", "namespace", namespace, "name", hpaName, "ignored", dropped)
 	}
-	fmt.Printf("✅ HPA patched for %s\n", hpaName)
+	if *dryRun {
+		log.Info("dry run, HPA update validated by the API server but not persisted", "namespace", namespace, "name", hpaName)
+		return nil
+	}
+	log.Info("HPA patched", "namespace", namespace, "name", hpaName)
 
 	return nil
 }
 
+// setUtilizationTarget sets the averageUtilization of the HPA's resource metric, adding the metric
+// if the HPA doesn't scale on that resource yet. Other metrics are left as they are.
+func setUtilizationTarget(spec *autoscalingv2.HorizontalPodAutoscalerSpec, name v1.ResourceName, utilization int32) {
+	target := autoscalingv2.MetricTarget{Type: autoscalingv2.UtilizationMetricType, AverageUtilization: &utilization}
+	for i, metric := range spec.Metrics {
+		if metric.Type == autoscalingv2.ResourceMetricSourceType && metric.Resource != nil && metric.Resource.Name == name {
+			spec.Metrics[i].Resource.Target = target
+			return
+		}
+	}
+	spec.Metrics = append(spec.Metrics, autoscalingv2.MetricSpec{
+		Type:     autoscalingv2.ResourceMetricSourceType,
+		Resource: &autoscalingv2.ResourceMetricSource{Name: name, Target: target},
+	})
+}
+
 func main() {
-	if !confirmPrompt() {
-		fmt.Println("\n💢 Operation cancelled.")
-		return
+	flag.Parse()
+
+	// With -summary-only everything the actions print goes to stderr and stdout only gets the summary
+	// line; with -output=- it only gets the CSV (see pipeOutput).
+	stdout := os.Stdout
+	if *summaryOnly || *output == stdoutPath {
+		os.Stdout = os.Stderr
+	}
+
+	err := run()
+	explainTimeout(err)
+
+	if *summaryOnly {
+		_, cluster, _, _ := currentCluster()
+		fmt.Fprintln(stdout, summary.line(err, cluster))
+	}
+	os.Exit(exitCode(err))
+}
+
+// run drives the interactive menu and returns the outcome of the selected action, which main
+// translates into the process exit code.
+func run() error {
+	// The flags are checked up front, in order, so a bad value fails the run with a usage error
+	// before anything is asked or sent to the cluster.
+	validators := []func() error{
+		setupLogging,
+		validateDelimiter,
+		validateAction,
+		validateConcurrency,
+		validateMaxRetries,
+		validateRateLimits,
+		validateSelector,
+		validateExcludeSelector,
+		validateNameFilter,
+		validateWatch,
+		validateContexts,
+		validateFields,
+		validateCanaryDecision,
+		validateSince,
+		validateNodeCapacity,
+		validateOutput,
+		validateInput,
+		validateMaskColumns,
+	}
+	for _, validate := range validators {
+		if err := validate(); err != nil {
+			logger.Error("invalid flags", "err", err)
+			return withExitCode(exitUsage, err)
+		}
+	}
+	if *expectCluster != "" {
+		if err := verifyExpectedCluster(*expectCluster); err != nil {
+			logger.Error("unexpected cluster", "err", err)
+			return withExitCode(exitUsage, err)
+		}
 	}
 
-	action := actionPrompt()
+	if !*assumeYes {
+		proceed, err := confirmPrompt()
+		if err != nil {
+			logger.Error("invalid input", "err", err)
+			return withExitCode(exitUsage, err)
+		}
+		if !proceed {
+			logger.Info("operation cancelled")
+			return nil
+		}
+	}
+
+	action := selectedAction()
+	if multipleContexts() && (action == "3" || action == "4") {
+		err := withExitCode(exitUsage, fmt.Errorf("restart and restore work on one cluster at a time, pass a single -context"))
+		logger.Error("invalid flags", "err", err)
+		return err
+	}
+	if *watch && action != "1" {
+		err := withExitCode(exitUsage, fmt.Errorf("-watch only applies to the generate action"))
+		logger.Error("invalid flags", "err", err)
+		return err
+	}
+	// Patching, restarting and restoring touch many workloads at once, so the operator names the
+	// cluster, either with -confirm-cluster or by typing it.
+	verb, changesCluster := clusterChangingActions[action]
+	if changesCluster && !*dryRun {
+		if err := verifyConfirmedClusters(); err != nil {
+			logger.Error("unconfirmed cluster", "err", err)
+			return withExitCode(exitUsage, err)
+		}
+	}
+	if changesCluster && !*assumeYes && !*dryRun && len(confirmClusters) == 0 {
+		proceed, err := confirmClusterPrompt(verb)
+		if err != nil {
+			logger.Error("failed to resolve the target cluster", "err", err)
+			return withExitCode(exitConnectivity, err)
+		}
+		if !proceed {
+			logger.Info("operation cancelled")
+			return nil
+		}
+	}
 
 	switch action {
 	case "1":
-		generateDeploymentInfo()
+		err := generateDeploymentInfo()
+		if err != nil {
+			logger.Error("generating deployment info failed", "err", err)
+		}
+		return err
 	case "2":
 		err := patchKubeResourcesFromCSV()
 		if err != nil {
-			fmt.Printf("💢 Error updating Kubernetes specs: %v\n", err)
+			logger.Error("updating Kubernetes specs failed", "err", err)
 		}
+		return err
 	case "3":
-		err := restartDeployment("all")
+		// The target is resolved once, so every restart mode acts on the same deployments.
+		target := *restartTarget
+		if target == "" && nonInteractive() {
+			err := withExitCode(exitUsage, fmt.Errorf("-action=restart needs -deployment (a name or \"all\")"))
+			logger.Error("invalid flags", "err", err)
+			return err
+		}
+		if target == "" {
+			target = restartTargetPrompt()
+		}
+		if *dryRun {
+			err := restartDryRun(target)
+			if err != nil {
+				logger.Error("restart dry run failed", "err", err)
+			}
+			return err
+		}
+		if *canary {
+			err := restartCanaryDeployments(target)
+			if err != nil {
+				logger.Error("canary restart failed", "err", err)
+			}
+			return err
+		}
+		if *restartOrderAnnotation != "" {
+			err := restartInWaves(*restartOrderAnnotation, target)
+			if err != nil {
+				logger.Error("ordered restart failed", "err", err)
+			}
+			return err
+		}
+		err := restartDeployment(target)
 		if err != nil {
-			return
+			logger.Error("restart failed", "err", err)
 		}
+		return err
 	case "4":
-		fmt.Println("\n💢 Exiting the script.")
+		err := restoreFromBackup()
+		if err != nil {
+			logger.Error("restoring from backup failed", "err", err)
+		}
+		return err
+	case "5":
+		err := scaffoldCSV()
+		if err != nil {
+			logger.Error("creating the CSV template failed", "err", err)
+		}
+		return err
+	case "6":
+		logger.Info("exiting the script")
+		return nil
 	default:
-		fmt.Println("💢 Invalid choice, please select a valid action.")
+		logger.Error("invalid choice, please select a valid action", "choice", action)
+		return withExitCode(exitUsage, fmt.Errorf("invalid action %q", action))
 	}
-	os.Exit(0)
 }
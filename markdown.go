@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// markdownSkippedColumns are patch bookkeeping columns that only add noise to a PR description.
+var markdownSkippedColumns = map[string]bool{
+	"UpdateResourceAndHPA": true,
+	"UpdateHPAOnly":        true,
+	"Resource Version":     true,
+	"HPA Resource Version": true,
+	"Profile":              true,
+}
+
+// writeMarkdownTable saves the deployments as a GitHub-flavored Markdown table with the same
+// columns (including the ones enabled by flags) as the CSV, ready to paste into a PR or issue.
+func writeMarkdownTable(data []DeploymentInfo, path string) error {
+	containers := wideContainerNames(data)
+	header := csvHeader(containers)
+	var keep []int
+	for i, name := range header {
+		if !markdownSkippedColumns[name] {
+			keep = append(keep, i)
+		}
+	}
+
+	pick := func(record []string) []string {
+		cells := make([]string, len(keep))
+		for i, index := range keep {
+			cells[i] = record[index]
+		}
+		return cells
+	}
+	rows := make([][]string, len(data))
+	for i, deploy := range data {
+		rows[i] = pick(csvRecord(i, deploy, containers))
+	}
+
+	if err := os.WriteFile(path, []byte(markdownTable(pick(header), rows)), 0644); err != nil {
+		return fmt.Errorf("failed to write Markdown table: %w", err)
+	}
+	return nil
+}
+
+// markdownTable renders a GFM table with every column padded to its widest cell.
+func markdownTable(header []string, rows [][]string) string {
+	escaped := make([][]string, 0, len(rows)+1)
+	for _, row := range append([][]string{header}, rows...) {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = escapeMarkdownCell(cell)
+		}
+		escaped = append(escaped, cells)
+	}
+
+	widths := make([]int, len(header))
+	for _, row := range escaped {
+		for i, cell := range row {
+			if n := utf8.RuneCountInString(cell); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+	for i := range widths {
+		if widths[i] < 3 {
+			widths[i] = 3 // a delimiter cell needs at least "---"
+		}
+	}
+
+	var b strings.Builder
+	line := func(cells []string, pad string) {
+		b.WriteString("|")
+		for i, cell := range cells {
+			b.WriteString(" " + cell + strings.Repeat(pad, widths[i]-utf8.RuneCountInString(cell)) + " |")
+		}
+		b.WriteString("\n")
+	}
+	line(escaped[0], " ")
+	delimiter := make([]string, len(header))
+	line(delimiter, "-")
+	for _, row := range escaped[1:] {
+		line(row, " ")
+	}
+	return b.String()
+}
+
+// escapeMarkdownCell keeps a value inside its cell: pipes would start a new column and newlines
+// would end the table.
+func escapeMarkdownCell(value string) string {
+	value = strings.ReplaceAll(value, "|", `\|`)
+	value = strings.ReplaceAll(value, "\r\n", " ")
+	return strings.ReplaceAll(value, "\n", " ")
+}
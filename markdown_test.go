@@ -0,0 +1,18 @@
+package main
+
+import "testing"
+
+func TestMarkdownTableEscapesAndAligns(t *testing.T) {
+	got := markdownTable([]string{"Name", "Issue"}, [][]string{
+		{"web", "a|b"},
+		{"api", "line1\nline2"},
+	})
+	want := "" +
+		"| Name | Issue       |\n" +
+		"| ---- | ----------- |\n" +
+		"| web  | a\\|b        |\n" +
+		"| api  | line1 line2 |\n"
+	if got != want {
+		t.Errorf("markdownTable() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// maskValue replaces the content of every masked cell in the shared CSV.
+const maskValue = "***"
+
+// maskedColumnIndexes resolves the comma-separated -mask-columns list against the CSV header.
+// Column names are matched case-insensitively; unknown names are rejected so a typo never
+// results in an unmasked file being shared.
+func maskedColumnIndexes(header []string, columns string) (map[int]bool, error) {
+	indexes := make(map[int]bool)
+	for _, column := range strings.Split(columns, ",") {
+		column = strings.TrimSpace(column)
+		if column == "" {
+			continue
+		}
+
+		found := false
+		for i, name := range header {
+			if strings.EqualFold(name, column) {
+				indexes[i] = true
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown column %q in -mask-columns (available: %s)", column, strings.Join(header, ", "))
+		}
+	}
+	return indexes, nil
+}
+
+// validateMaskColumns checks -mask-columns against the CSV header before anything is generated, so
+// a typo fails the run instead of surfacing after the unmasked output is written. With -wide the
+// per-container columns ("<container> CPU Request") depend on the workloads listed and are
+// resolved when the CSV is written.
+func validateMaskColumns() error {
+	if *maskColumns == "" {
+		return nil
+	}
+	header := csvHeader(nil)
+	for _, column := range strings.Split(*maskColumns, ",") {
+		column = strings.TrimSpace(column)
+		if column == "" || slices.ContainsFunc(header, func(name string) bool { return strings.EqualFold(name, column) }) {
+			continue
+		}
+		if *wide && slices.ContainsFunc(wideFields, func(field string) bool {
+			return len(column) > len(field)+1 && strings.EqualFold(column[len(column)-len(field)-1:], " "+field)
+		}) {
+			continue
+		}
+		return fmt.Errorf("unknown column %q in -mask-columns (available: %s)", column, strings.Join(header, ", "))
+	}
+	return nil
+}
+
+// writeMaskedCSV writes a shareable copy of the generated CSV in which the -mask-columns cells are
+// replaced by "***". The unmasked deployment-info.csv remains the source for patching.
+func writeMaskedCSV(data []DeploymentInfo, path string) error {
+	containers := wideContainerNames(data)
+	header := csvHeader(containers)
+	masked, err := maskedColumnIndexes(header, *maskColumns)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create masked CSV file: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Comma = csvDelimiter()
+	defer writer.Flush()
+
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	for i, deploy := range data {
+		record := csvRecord(i, deploy, containers)
+		for index := range masked {
+			record[index] = maskValue
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write CSV record: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// metricsAPIGroup is the API group served by metrics-server. Resource-metric (CPU/memory) HPAs
+// read their utilization from it and report "unable to fetch metrics" when it is missing.
+const metricsAPIGroup = "metrics.k8s.io"
+
+// metricsAPIAvailable reports whether the resource metrics API is registered in the cluster.
+func metricsAPIAvailable(clientset kubernetes.Interface) (bool, error) {
+	groups, err := clientset.Discovery().ServerGroups()
+	if err != nil {
+		return false, fmt.Errorf("failed to discover API groups: %w", err)
+	}
+
+	for _, group := range groups.Groups {
+		if group.Name == metricsAPIGroup && len(group.Versions) > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// metricsPreflight lazily checks for the resource metrics API the first time an HPA is about to be
+// patched and prints a warning for every resource-metric HPA patched into a cluster without it.
+// It never blocks the patch itself.
+type metricsPreflight struct {
+	clientset kubernetes.Interface
+	mu        sync.Mutex // rows are patched concurrently
+	checked   bool
+	available bool
+}
+
+// warn prints the warning for the HPA of the row if the metrics API is missing and the HPA scales on
+// resource metrics once patched, naming those resources. HPAs on custom or external metrics only
+// don't read from metrics.k8s.io and get no warning.
+func (p *metricsPreflight) warn(out io.Writer, row patchRow) {
+	p.mu.Lock()
+	if !p.checked {
+		p.checked = true
+		available, err := metricsAPIAvailable(p.clientset)
+		if err != nil {
+			loggerTo(out).Warn("could not verify the metrics API availability", "group", metricsAPIGroup, "err", err)
+			available = true // Unknown, don't spam a warning per HPA.
+		}
+		p.available = available
+	}
+	available := p.available
+	p.mu.Unlock()
+
+	if available {
+		return
+	}
+	resources, err := p.resourceMetrics(row)
+	if err != nil {
+		loggerTo(out).Warn(fmt.Sprintf("%s is not available in this cluster: a resource-metric HPA will report \"unable to fetch metrics\" until metrics-server is installed", metricsAPIGroup), "name", row.DeploymentName, "err", err)
+		return
+	}
+	if len(resources) > 0 {
+		loggerTo(out).Warn(fmt.Sprintf("%s is not available in this cluster: the HPA targets %s utilization and will report \"unable to fetch metrics\" until metrics-server is installed", metricsAPIGroup, strings.Join(resources, " and ")), "name", row.DeploymentName)
+	}
+}
+
+// resourceMetrics returns the resources (cpu, memory) the HPA of the row scales on once the row is
+// applied: the resource metrics of the live HPA plus the targets the row sets.
+func (p *metricsPreflight) resourceMetrics(row patchRow) ([]string, error) {
+	ctx, cancel := apiContext()
+	defer cancel()
+	hpa, err := getHPA(ctx, p.clientset, row.Namespace, row.DeploymentName)
+	if err != nil {
+		return nil, err
+	}
+
+	set := make(map[v1.ResourceName]bool)
+	for _, metric := range hpa.Spec.Metrics {
+		switch {
+		case metric.Type == autoscalingv2.ResourceMetricSourceType && metric.Resource != nil:
+			set[metric.Resource.Name] = true
+		case metric.Type == autoscalingv2.ContainerResourceMetricSourceType && metric.ContainerResource != nil:
+			set[metric.ContainerResource.Name] = true
+		}
+	}
+	if row.CPUTargetUtilization > 0 {
+		set[v1.ResourceCPU] = true
+	}
+	if row.MemoryTargetUtilization != nil {
+		set[v1.ResourceMemory] = true
+	}
+
+	resources := make([]string, 0, len(set))
+	for name := range set {
+		label := string(name)
+		if name == v1.ResourceCPU {
+			label = "CPU"
+		}
+		resources = append(resources, label)
+	}
+	sort.Strings(resources)
+	return resources, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// nodeCapacity is the allocatable CPU and memory summed over the nodes of the cluster, what the
+// scheduler can hand out to pods after system and kubelet reservations.
+type nodeCapacity struct {
+	Nodes  int
+	CPU    int64 // millicores
+	Memory int64 // MiB
+}
+
+// validateNodeCapacity checks that -include-node-capacity has a capacity summary of a single
+// cluster to add to.
+func validateNodeCapacity() error {
+	if !*includeNodeCapacity {
+		return nil
+	}
+	if !*capacitySummary {
+		return fmt.Errorf("-include-node-capacity only applies to -capacity-summary")
+	}
+	if multipleContexts() {
+		return fmt.Errorf("-include-node-capacity compares with the nodes of one cluster, pass a single -context")
+	}
+	return nil
+}
+
+// clusterCapacity lists the nodes and sums their allocatable CPU and memory. Cordoned nodes are
+// counted too, their capacity is only temporarily unavailable.
+func clusterCapacity(clientset kubernetes.Interface) (*nodeCapacity, error) {
+	ctx, cancel := apiContext()
+	defer cancel()
+
+	var nodes *v1.NodeList
+	err := retryTransient("list nodes", func() (err error) {
+		nodes, err = clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	capacity := &nodeCapacity{Nodes: len(nodes.Items)}
+	for _, node := range nodes.Items {
+		capacity.CPU += node.Status.Allocatable.Cpu().MilliValue()
+		capacity.Memory += node.Status.Allocatable.Memory().Value() / (1024 * 1024)
+	}
+	return capacity, nil
+}
+
+// percentOf renders requested as a share of allocatable, e.g. "42.5%", or N/A without capacity.
+func percentOf(requested, allocatable int64) string {
+	if allocatable <= 0 {
+		return "N/A"
+	}
+	return fmt.Sprintf("%.1f%%", float64(requested)*100/float64(allocatable))
+}
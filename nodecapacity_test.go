@@ -0,0 +1,40 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCapacitySummaryShareOfAllocatable(t *testing.T) {
+	node := func(name, cpu, memory string) *v1.Node {
+		return &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: v1.NodeStatus{Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse(cpu),
+				v1.ResourceMemory: resource.MustParse(memory),
+			}},
+		}
+	}
+	clientset := fake.NewSimpleClientset(node("a", "3920m", "14Gi"), node("b", "3920m", "14Gi"))
+	capacity, err := clusterCapacity(clientset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *capacity != (nodeCapacity{Nodes: 2, CPU: 7840, Memory: 28672}) {
+		t.Fatalf("clusterCapacity() = %+v, want 2 nodes with 7840m and 28672Mi", *capacity)
+	}
+
+	totals := buildCapacitySummary([]DeploymentInfo{{Kind: kindDeployment, Namespace: "shop", Replicas: 4, CPURequest: "980m", MemoryRequest: "1792Mi"}})
+	got := totals[len(totals)-1].record(capacity)
+	if want := []string{"50.0%", "25.0%"}; !reflect.DeepEqual(got[len(got)-2:], want) {
+		t.Errorf("TOTAL row ends with %v, want %v", got[len(got)-2:], want)
+	}
+	if got := totals[0].record(nil); len(got) != len(capacitySummaryHeader) {
+		t.Errorf("record(nil) has %d cells, want %d without node capacity", len(got), len(capacitySummaryHeader))
+	}
+}
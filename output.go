@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// defaultOutputPath is the file the generate action writes in the given -format when -output is
+// not set, and the one the patch action reads back.
+func defaultOutputPath(format string) string {
+	switch format {
+	case "json":
+		return "deployment-info.json"
+	case "grafana":
+		return "deployment-info.grafana.json"
+	case "markdown":
+		return "deployment-info.md"
+	default:
+		return "deployment-info.csv"
+	}
+}
+
+// outputPath returns -output, or the default file of -format.
+func outputPath() string {
+	if *output != "" {
+		return *output
+	}
+	return defaultOutputPath(*outputFormat)
+}
+
+// stdoutPath as -output makes the generate action write the CSV to standard output, e.g. to pipe
+// it into another command. main then sends everything else it prints to stderr.
+const stdoutPath = "-"
+
+// pipeOutput is the original stdout, where -output=- writes the CSV.
+var pipeOutput io.Writer = os.Stdout
+
+// validateOutput checks that -output=- is only used for a CSV, and not together with -summary-only,
+// which prints its line to stdout as well.
+func validateOutput() error {
+	if *output != stdoutPath {
+		return nil
+	}
+	switch {
+	case *outputFormat != "csv":
+		return fmt.Errorf("-output=- only writes CSV, got -format=%s", *outputFormat)
+	case *summaryOnly:
+		return fmt.Errorf("-output=- can't be combined with -summary-only, both write to stdout")
+	}
+	return nil
+}
+
+// stdinPath as -input makes the patch action read the file from standard input, e.g. at the end of
+// a pipeline.
+const stdinPath = "-"
+
+// validateInput checks that -input=- is used where nothing else reads stdin: with -action=patch
+// (the menu reads its choice from stdin), -yes (the confirmation prompt) and without -interactive.
+func validateInput() error {
+	if *input != stdinPath {
+		return nil
+	}
+	switch {
+	case !nonInteractive() || menuActions[strings.ToLower(*actionName)] != "2":
+		return fmt.Errorf("-input=- only applies to -action=patch")
+	case !*assumeYes:
+		return fmt.Errorf("-input=- needs -yes, stdin holds the file and can't answer the confirmation prompt")
+	case *interactive:
+		return fmt.Errorf("-input=- can't be combined with -interactive, stdin holds the file and can't answer the prompts")
+	}
+	return nil
+}
+
+// inputPath returns -input, or the file the generate action writes by default: deployment-info.json
+// with -format=json and deployment-info.csv otherwise (Grafana and Markdown files can't be patched from).
+func inputPath() string {
+	if *input != "" {
+		return *input
+	}
+	if *outputFormat == "json" {
+		return defaultOutputPath("json")
+	}
+	return defaultOutputPath("csv")
+}
+
+// outputFiles splits the rows over the files named by the -output template: "{namespace}" writes
+// one file per namespace (e.g. with -all-namespaces), "{context}" one per -context, and "{cluster}"
+// is replaced with the cluster name of the row's context, so runs against several clusters don't
+// overwrite each other. The paths are sorted.
+func outputFiles(data []DeploymentInfo) ([]string, map[string][]DeploymentInfo, error) {
+	template := outputPath()
+	clusters := make(map[string][2]string) // row context -> resolved context and cluster name
+	files := make(map[string][]DeploymentInfo)
+	for _, deploy := range data {
+		path := strings.ReplaceAll(template, "{namespace}", deploy.Namespace)
+		if strings.Contains(path, "{context}") || strings.Contains(path, "{cluster}") {
+			names, ok := clusters[deploy.Context]
+			if !ok {
+				previous := activeContext
+				activeContext = deploy.Context
+				contextName, clusterName, _, err := currentCluster()
+				activeContext = previous
+				if err != nil {
+					return nil, nil, err
+				}
+				names = [2]string{contextName, clusterName}
+				clusters[deploy.Context] = names
+			}
+			path = strings.ReplaceAll(path, "{context}", names[0])
+			path = strings.ReplaceAll(path, "{cluster}", names[1])
+		}
+		files[path] = append(files[path], deploy)
+	}
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		if path == stdoutPath {
+			paths = append(paths, path)
+			continue
+		}
+		if err := prepareOutputDir(path); err != nil {
+			return nil, nil, err
+		}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths, files, nil
+}
+
+// prepareOutputDir creates the parent directories of path and reports a clear error when they
+// can't be created or written to.
+func prepareOutputDir(path string) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return withExitCode(exitUsage, fmt.Errorf("-output %s: cannot create directory %s: %w", path, dir, err))
+	}
+	probe, err := os.CreateTemp(dir, ".write-test-*")
+	if err != nil {
+		return withExitCode(exitUsage, fmt.Errorf("-output %s: directory %s is not writable: %w", path, dir, err))
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOutputFilesPerNamespace(t *testing.T) {
+	inTempDir(t)
+	saved := *output
+	defer func() { *output = saved }()
+	*output = "reports/deploy-{namespace}.csv"
+
+	data := []DeploymentInfo{{Name: "web", Namespace: "shop"}, {Name: "api", Namespace: "billing"}, {Name: "cart", Namespace: "shop"}}
+	paths, files, err := outputFiles(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"reports/deploy-billing.csv", "reports/deploy-shop.csv"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("paths = %v, want %v", paths, want)
+	}
+	if got := len(files["reports/deploy-shop.csv"]); got != 2 {
+		t.Errorf("deploy-shop.csv holds %d rows, want 2", got)
+	}
+	if info, err := os.Stat("reports"); err != nil || !info.IsDir() {
+		t.Errorf("reports directory was not created: %v", err)
+	}
+}
+
+func TestWriteCSVToStdout(t *testing.T) {
+	inTempDir(t)
+	savedOutput, savedPipe := *output, pipeOutput
+	defer func() { *output, pipeOutput = savedOutput, savedPipe }()
+	var piped bytes.Buffer
+	*output, pipeOutput = stdoutPath, &piped
+
+	paths, files, err := outputFiles([]DeploymentInfo{{Name: "web", Namespace: "shop"}})
+	if err != nil || !reflect.DeepEqual(paths, []string{stdoutPath}) {
+		t.Fatalf("outputFiles() = %v, %v, want [-]", paths, err)
+	}
+	if err := writeCSV(files[stdoutPath], stdoutPath); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(piped.String()), "\n"); len(lines) != 2 || !strings.HasPrefix(lines[1], strings.Join([]string{"1", "web", "shop"}, string(csvDelimiter()))) {
+		t.Errorf("stdout = %q, want the header and the web row", piped.String())
+	}
+	if entries, _ := os.ReadDir("."); len(entries) != 0 {
+		t.Errorf("files written: %v, want none", entries)
+	}
+}
+
+func TestValidateMaskColumns(t *testing.T) {
+	savedColumns, savedWide := *maskColumns, *wide
+	defer func() { *maskColumns, *wide = savedColumns, savedWide }()
+
+	tests := []struct {
+		columns string
+		wide    bool
+		wantErr bool
+	}{
+		{"", false, false},
+		{"namespace, Image", false, false},
+		{"Namespcae", false, true},
+		{"app CPU Request", false, true},
+		{"app CPU Request", true, false}, // resolved against the listed containers later
+		{"app CPU Requests", true, true},
+	}
+	for _, tt := range tests {
+		*maskColumns, *wide = tt.columns, tt.wide
+		if err := validateMaskColumns(); (err != nil) != tt.wantErr {
+			t.Errorf("validateMaskColumns() with -mask-columns=%q, -wide=%v = %v, want error %v", tt.columns, tt.wide, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"io"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+// rowOutcome is how applying one row of the patch action ended.
+type rowOutcome int
+
+const (
+	rowPatched  rowOutcome = iota
+	rowUpToDate            // the cluster already had the values of the row
+	rowDeclined            // the operator answered no to the -interactive confirmation
+	rowFailed
+)
+
+// patchRun holds what the rows of one patch run share. Every field is safe for concurrent use,
+// so rows can be applied by several workers.
+type patchRun struct {
+	clientset   kubernetes.Interface
+	metrics     *metricsPreflight
+	limitRanges *limitRangeChecker
+	state       *patchState
+}
+
+// apply runs the cluster side of the patch action for one row, writing its progress to out.
+func (r *patchRun) apply(out io.Writer, row patchRow) rowOutcome {
+	clientset := r.clientset
+	log := loggerTo(out).With("namespace", row.Namespace, "name", row.DeploymentName)
+
+	// With -fields, the columns not named keep their live values. Progress is recorded for the row
+	// as read from the file, which is what -resume compares with; the -wide cells are copied because
+	// -fields and -clamp-to-limitrange rewrite them in place.
+	csvRow := row
+	row.Containers = append([]ContainerResources(nil), row.Containers...)
+	if err := restrictToFields(clientset, &row); err != nil {
+		log.Error("failed to read the live values for -fields, skipping", "err", err)
+		return rowFailed
+	}
+
+	// Validate the row against the namespace LimitRange before touching anything.
+	if violations := r.limitRanges.check(out, &row, *clampToLimitRange); len(violations) > 0 {
+		log.Error("violates the namespace LimitRange, skipping", "violations", violations)
+		return rowFailed
+	}
+
+	// Refuse to overwrite resources someone else changed since the CSV was generated.
+	if err := checkResourceVersions(clientset, row); err != nil {
+		log.Error("resource version check failed", "err", err)
+		return rowFailed
+	}
+
+	warnReplicaEdit(out, clientset, row)
+
+	// Compare the live state with the CSV first so repeated runs don't issue no-op patches.
+	resourcesCurrent := !row.UpdateResourceAndHPA || deploymentUpToDate(clientset, row)
+	// DaemonSets only get their resources set, there is no HPA to compare or patch.
+	hpaCurrent := !hasReplicaCount(row.Kind) || !row.hasHPA() || (!row.UpdateResourceAndHPA && !row.UpdateHPAOnly) || hpaUpToDate(clientset, row)
+	// The PDB budgets are patched together with the resources.
+	pdbCurrent := !row.UpdateResourceAndHPA || pdbUpToDate(clientset, row)
+	// Workloads without an HPA are scaled to the Replicas column.
+	replicas, scales := row.replicaTarget()
+	replicasCurrent := !scales || replicasUpToDate(clientset, row, replicas)
+	if resourcesCurrent && hpaCurrent && pdbCurrent && replicasCurrent {
+		log.Info("already up to date, skipping")
+		r.state.markApplied(*stateFile, csvRow)
+		return rowUpToDate
+	}
+	// Show what is about to change and, with -interactive, let the operator skip the row.
+	if !previewRow(out, clientset, row, row.UpdateResourceAndHPA && !resourcesCurrent, !hpaCurrent, !pdbCurrent, !replicasCurrent) {
+		log.Info("skipped at your request")
+		return rowDeclined
+	}
+	// Snapshot what is about to change so the restore action can undo the run.
+	if !*dryRun {
+		path, err := takeBackup(clientset, row, time.Now())
+		if err != nil {
+			log.Error("failed to back up, skipping", "err", err)
+			return rowFailed
+		}
+		log.Info("backup written", "path", path)
+	}
+	failed := false
+
+	if row.UpdateResourceAndHPA && !resourcesCurrent {
+		//Run kubectl commands to update deployment resources
+		var err error
+		if len(row.Containers) > 0 {
+			err = setWideDeploymentResources(out, row)
+		} else {
+			var container string
+			container, err = aggregateRowContainer(clientset, row)
+			if err == nil {
+				err = setDeploymentResources(out, row, container)
+			}
+		}
+		if err != nil {
+			log.Error("failed to set resources", "err", err)
+			failed = true
+		}
+	}
+
+	if !replicasCurrent {
+		if err := scaleWorkload(out, clientset, row, replicas); err != nil {
+			log.Error("failed to scale", "err", err)
+			failed = true
+		}
+	}
+
+	if !hpaCurrent {
+		// Run kubectl command to patch HPA
+		r.metrics.warn(out, row)
+		err := patchHPA(out, clientset, row.DeploymentName, row.Namespace, row.MinReplicas, row.MaxReplicas, row.CPUTargetUtilization, row.MemoryTargetUtilization, row.ScaleUpStabilization, row.ScaleDownStabilization, row.ScaleUpPolicies, row.ScaleDownPolicies, precondition(row.HPAResourceVersion))
+		if err != nil {
+			log.Error("failed to patch HPA", "err", err)
+			failed = true
+		}
+	}
+
+	if !pdbCurrent {
+		if err := patchPDB(out, clientset, row); err != nil {
+			log.Error("failed to patch PDB", "err", err)
+			failed = true
+		}
+	}
+
+	if failed {
+		return rowFailed
+	}
+	r.state.markApplied(*stateFile, csvRow)
+	return rowPatched
+}
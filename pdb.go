@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+)
+
+// noPDB marks workloads without a PodDisruptionBudget in the PDB column, so unguarded ones stand
+// out instead of hiding behind an empty cell.
+const noPDB = "no PDB"
+
+// pdbCells renders the PDB, PDB Min Available and PDB Max Unavailable columns. The budget cells
+// are N/A without a PDB and empty when the PDB doesn't set that field.
+func pdbCells(deploy DeploymentInfo) []string {
+	if deploy.PDBName == "" {
+		return []string{noPDB, "N/A", "N/A"}
+	}
+	return []string{deploy.PDBName, deploy.PDBMinAvailable, deploy.PDBMaxUnavailable}
+}
+
+// intOrStringCell renders a PDB budget, "" when unset.
+func intOrStringCell(value *intstr.IntOrString) string {
+	if value == nil {
+		return ""
+	}
+	return value.String()
+}
+
+// parsePDBCells reads the PDB columns of a CSV row. Files generated before the columns existed
+// leave the row without a PDB, so nothing is patched.
+func parsePDBCells(record []string, layout csvLayout, row *patchRow) {
+	name := layout.cell(record, "PDB")
+	if name == "" || name == noPDB {
+		return
+	}
+	row.PDBName = name
+	row.PDBMinAvailable = layout.cell(record, "PDB Min Available")
+	row.PDBMaxUnavailable = layout.cell(record, "PDB Max Unavailable")
+	validatePDBBudget(row)
+}
+
+// validatePDBBudget records a parse error when the row sets both budgets (the API server only
+// accepts one) or a budget that is neither a whole number nor a percentage.
+func validatePDBBudget(row *patchRow) {
+	if row.PDBName == "" {
+		return
+	}
+	if row.PDBMinAvailable != "" && row.PDBMaxUnavailable != "" {
+		row.ParseErrors = append(row.ParseErrors, `columns "PDB Min Available" and "PDB Max Unavailable": set only one of them`)
+	}
+	for column, cell := range map[string]string{"PDB Min Available": row.PDBMinAvailable, "PDB Max Unavailable": row.PDBMaxUnavailable} {
+		if cell == "" {
+			continue
+		}
+		value := intstr.Parse(cell)
+		if _, err := intstr.GetScaledValueFromIntOrPercent(&value, 100, false); err != nil || (value.Type == intstr.String && !strings.HasSuffix(cell, "%")) {
+			row.ParseErrors = append(row.ParseErrors, fmt.Sprintf("column %q: %q is neither a whole number nor a percentage", column, cell))
+		}
+	}
+}
+
+// pdbBudget converts a budget cell into the PDB field, nil for an empty cell.
+func pdbBudget(cell string) *intstr.IntOrString {
+	if cell == "" {
+		return nil
+	}
+	value := intstr.Parse(cell)
+	return &value
+}
+
+// pdbChanges lists the budgets of the row that differ from the live PDB.
+func pdbChanges(pdb *policyv1.PodDisruptionBudget, row patchRow) []fieldChange {
+	var changes []fieldChange
+	add := func(field, old, new string) {
+		if old == "" {
+			old = "<unset>"
+		}
+		if new == "" {
+			new = "<unset>"
+		}
+		if old != new {
+			changes = append(changes, fieldChange{Field: field, Old: old, New: new})
+		}
+	}
+	add("PDB Min Available", intOrStringCell(pdb.Spec.MinAvailable), row.PDBMinAvailable)
+	add("PDB Max Unavailable", intOrStringCell(pdb.Spec.MaxUnavailable), row.PDBMaxUnavailable)
+	return changes
+}
+
+// pdbUpToDate reports whether the row's PDB already has its budgets. Rows without a PDB have
+// nothing to patch; a failed lookup counts as "not up to date" so patchPDB reports the error.
+func pdbUpToDate(clientset kubernetes.Interface, row patchRow) bool {
+	if row.PDBName == "" {
+		return true
+	}
+	ctx, cancel := apiContext()
+	defer cancel()
+	pdb, err := clientset.PolicyV1().PodDisruptionBudgets(row.Namespace).Get(ctx, row.PDBName, metav1.GetOptions{})
+	if err != nil {
+		return false
+	}
+	return len(pdbChanges(pdb, row)) == 0
+}
+
+// patchPDB sets minAvailable and maxUnavailable of the row's PDB from the CSV. The budget left
+// empty in the CSV is cleared, since a PDB may only set one of them.
+func patchPDB(out io.Writer, clientset kubernetes.Interface, row patchRow) error {
+	ctx, cancel := apiContext()
+	defer cancel()
+
+	pdbs := clientset.PolicyV1().PodDisruptionBudgets(row.Namespace)
+	var pdb *policyv1.PodDisruptionBudget
+	err := retryTransient("get PDB "+row.PDBName, func() (err error) {
+		pdb, err = pdbs.Get(ctx, row.PDBName, metav1.GetOptions{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get PDB %s: %w", row.PDBName, err)
+	}
+	pdb.Spec.MinAvailable = pdbBudget(row.PDBMinAvailable)
+	pdb.Spec.MaxUnavailable = pdbBudget(row.PDBMaxUnavailable)
+
+	log := loggerTo(out).With("namespace", row.Namespace, "name", row.PDBName)
+	log.Info("updating PDB", "minAvailable", row.PDBMinAvailable, "maxUnavailable", row.PDBMaxUnavailable)
+	err = retryTransient("update PDB "+row.PDBName, func() error {
+		_, err := pdbs.Update(ctx, pdb, metav1.UpdateOptions{DryRun: dryRunAll()})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update PDB %s: %w", row.PDBName, err)
+	}
+	if *dryRun {
+		log.Info("dry run, PDB update validated by the API server but not persisted")
+		return nil
+	}
+	log.Info("PDB patched")
+	return nil
+}
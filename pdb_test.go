@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestPatchPDBSwitchesBudget(t *testing.T) {
+	minAvailable := intstr.FromInt(1)
+	clientset := fake.NewSimpleClientset(&policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-pdb", Namespace: "shop"},
+		Spec:       policyv1.PodDisruptionBudgetSpec{MinAvailable: &minAvailable},
+	})
+	row := patchRow{DeploymentName: "web", Namespace: "shop", UpdateResourceAndHPA: true, PDBName: "web-pdb", PDBMaxUnavailable: "25%"}
+
+	if pdbUpToDate(clientset, row) {
+		t.Fatal("pdbUpToDate() = true, want false before the patch")
+	}
+	if err := patchPDB(io.Discard, clientset, row); err != nil {
+		t.Fatalf("patchPDB() = %v", err)
+	}
+
+	pdb, err := clientset.PolicyV1().PodDisruptionBudgets("shop").Get(context.Background(), "web-pdb", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pdb.Spec.MinAvailable != nil || pdb.Spec.MaxUnavailable == nil || pdb.Spec.MaxUnavailable.String() != "25%" {
+		t.Errorf("PDB budgets = minAvailable %v, maxUnavailable %v; want unset and 25%%", pdb.Spec.MinAvailable, pdb.Spec.MaxUnavailable)
+	}
+	if !pdbUpToDate(clientset, row) {
+		t.Error("pdbUpToDate() = false, want true after the patch")
+	}
+}
+
+func TestPDBColumnsRoundTrip(t *testing.T) {
+	header := csvHeader(nil)
+	layout := parseCSVLayout(header)
+
+	unguarded := csvRecord(0, DeploymentInfo{Kind: kindDeployment, Name: "cron"}, nil)
+	if got := layout.cell(unguarded, "PDB"); got != noPDB {
+		t.Errorf("PDB = %q, want %q for a deployment without a PDB", got, noPDB)
+	}
+	if row := parsePatchRow(unguarded, layout); row.PDBName != "" {
+		t.Errorf("parsed PDBName = %q, want none", row.PDBName)
+	}
+
+	record := csvRecord(0, DeploymentInfo{Kind: kindDeployment, Name: "web", PDBName: "web-pdb", PDBMinAvailable: "2"}, nil)
+	record[layout.columns["PDB Max Unavailable"]] = "1"
+	row := parsePatchRow(record, layout)
+	bothSet := false
+	for _, problem := range row.ParseErrors {
+		bothSet = bothSet || strings.Contains(problem, "set only one")
+	}
+	if row.PDBName != "web-pdb" || !bothSet {
+		t.Errorf("parsed row = PDB %q, errors %q; want web-pdb with an error for both budgets set", row.PDBName, row.ParseErrors)
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// fieldChange is one value the patch of a row would change.
+type fieldChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// previewRow prints the fields the row would change on the live workload, HPA and PDB, old → new,
+// and with -interactive asks whether to apply them. It returns false when the operator declines.
+func previewRow(out io.Writer, clientset kubernetes.Interface, row patchRow, resources, hpa, pdb, replicas bool) bool {
+	var changes []fieldChange
+	if resources || replicas {
+		if live, err := getWorkload(clientset, row); err == nil {
+			if resources {
+				changes = append(changes, workloadChanges(live, row)...)
+			}
+			if want, ok := row.replicaTarget(); replicas && ok {
+				changes = append(changes, fieldChange{Field: "Replicas", Old: strconv.Itoa(int(liveReplicas(live))), New: strconv.Itoa(int(want))})
+			}
+		}
+	}
+	if hpa {
+		ctx, cancel := apiContext()
+		live, err := getHPA(ctx, clientset, row.Namespace, row.DeploymentName)
+		cancel()
+		if err == nil {
+			changes = append(changes, hpaChanges(live, row)...)
+		}
+	}
+	if pdb {
+		ctx, cancel := apiContext()
+		live, err := clientset.PolicyV1().PodDisruptionBudgets(row.Namespace).Get(ctx, row.PDBName, metav1.GetOptions{})
+		cancel()
+		if err == nil {
+			changes = append(changes, pdbChanges(live, row)...)
+		}
+	}
+
+	if len(changes) > 0 {
+		fmt.Fprintf(out, "\n🔎 Changes for %s %s/%s:\n", kubectlKind(row.Kind), row.Namespace, row.DeploymentName)
+		printChanges(out, changes)
+	}
+	if !*interactive {
+		return true
+	}
+	fmt.Fprintf(out, "Apply these changes to %s %s? (y/N): ", kubectlKind(row.Kind), row.DeploymentName)
+	input, _ := stdinReader.ReadString('\n')
+	return strings.EqualFold(strings.TrimSpace(input), "y")
+}
+
+// printChanges prints the changes as aligned "field  old → new" lines.
+func printChanges(out io.Writer, changes []fieldChange) {
+	width := 0
+	for _, change := range changes {
+		if len(change.Field) > width {
+			width = len(change.Field)
+		}
+	}
+	for _, change := range changes {
+		fmt.Fprintf(out, "   %-*s  %s → %s\n", width, change.Field, change.Old, change.New)
+	}
+}
+
+// workloadChanges lists the container resources and rolling update parameters of the row that
+// differ from the live workload, using the same rules as workloadMatchesRow.
+func workloadChanges(live *workload, row patchRow) []fieldChange {
+	var changes []fieldChange
+	add := func(field, old, new string) {
+		if old != new {
+			changes = append(changes, fieldChange{Field: field, Old: old, New: new})
+		}
+	}
+
+	for _, container := range live.Template.Spec.Containers {
+		want := row.resources()
+		if len(row.Containers) > 0 {
+			found := false
+			for _, candidate := range row.Containers {
+				if candidate.Name == container.Name {
+					want, found = candidate, true
+				}
+			}
+			if !found {
+				continue
+			}
+		}
+
+		prefix := container.Name + " "
+		requests, limits := container.Resources.Requests, container.Resources.Limits
+		// Zero requests and limits aren't applied (see setContainerResources).
+		if !limitEquals(requests, v1.ResourceCPU, want.CPURequest) {
+			add(prefix+"CPU Request", liveQuantity(requests, v1.ResourceCPU), want.CPURequest)
+		}
+		if !limitEquals(limits, v1.ResourceCPU, want.CPULimit) {
+			add(prefix+"CPU Limit", liveQuantity(limits, v1.ResourceCPU), want.CPULimit)
+		}
+		if !limitEquals(requests, v1.ResourceMemory, want.MemoryRequest) {
+			add(prefix+"Memory Request", liveQuantity(requests, v1.ResourceMemory), want.MemoryRequest)
+		}
+		if !limitEquals(limits, v1.ResourceMemory, want.MemoryLimit) {
+			add(prefix+"Memory Limit", liveQuantity(limits, v1.ResourceMemory), want.MemoryLimit)
+		}
+		if !limitEquals(requests, v1.ResourceEphemeralStorage, want.EphemeralStorageRequest) {
+			add(prefix+"Ephemeral Storage Request", liveQuantity(requests, v1.ResourceEphemeralStorage), want.EphemeralStorageRequest)
+		}
+		if !limitEquals(limits, v1.ResourceEphemeralStorage, want.EphemeralStorageLimit) {
+			add(prefix+"Ephemeral Storage Limit", liveQuantity(limits, v1.ResourceEphemeralStorage), want.EphemeralStorageLimit)
+		}
+	}
+
+	if live.Strategy != nil && !isRecreate(live.Strategy) {
+		maxUnavailable, maxSurge := "<unset>", "<unset>"
+		if rollingUpdate := live.Strategy.RollingUpdate; rollingUpdate != nil {
+			if rollingUpdate.MaxUnavailable != nil {
+				maxUnavailable = rollingUpdate.MaxUnavailable.String()
+			}
+			if rollingUpdate.MaxSurge != nil {
+				maxSurge = rollingUpdate.MaxSurge.String()
+			}
+		}
+		add("MaxUnavailable", maxUnavailable, row.MaxUnavailable)
+		add("MaxSurge", maxSurge, row.MaxSurge)
+	}
+	return changes
+}
+
+// hpaChanges lists the HPA settings of the row that differ from the live HPA. Cells that leave a
+// setting untouched (a CPU target of 0, an N/A memory target or window, empty policies) are not
+// compared.
+func hpaChanges(hpa *autoscalingv2.HorizontalPodAutoscaler, row patchRow) []fieldChange {
+	var changes []fieldChange
+	add := func(field, old string, new int) {
+		if old != strconv.Itoa(new) {
+			changes = append(changes, fieldChange{Field: field, Old: old, New: strconv.Itoa(new)})
+		}
+	}
+
+	add("Min Replicas", int32Cell(hpa.Spec.MinReplicas), row.MinReplicas)
+	add("Max Replicas", strconv.Itoa(int(hpa.Spec.MaxReplicas)), row.MaxReplicas)
+	if row.CPUTargetUtilization > 0 {
+		add("CPU Target Utilization", utilizationCell(hpa.Spec.Metrics, v1.ResourceCPU), row.CPUTargetUtilization)
+	}
+	if row.MemoryTargetUtilization != nil {
+		add("Memory Target Utilization", utilizationCell(hpa.Spec.Metrics, v1.ResourceMemory), *row.MemoryTargetUtilization)
+	}
+
+	var scaleUp, scaleDown *autoscalingv2.HPAScalingRules
+	if hpa.Spec.Behavior != nil {
+		scaleUp, scaleDown = hpa.Spec.Behavior.ScaleUp, hpa.Spec.Behavior.ScaleDown
+	}
+	if row.ScaleUpStabilization != nil {
+		add("ScaleUp Stabilization", stabilizationCell(scaleUp), *row.ScaleUpStabilization)
+	}
+	if row.ScaleDownStabilization != nil {
+		add("ScaleDown Stabilization", stabilizationCell(scaleDown), *row.ScaleDownStabilization)
+	}
+	if scaleUp != nil && !policiesEqual(scaleUp, row.ScaleUpPolicies) {
+		changes = append(changes, fieldChange{Field: "ScaleUp Policies", Old: encodeScalingPolicies(scaleUp), New: row.ScaleUpPolicies})
+	}
+	if scaleDown != nil && !policiesEqual(scaleDown, row.ScaleDownPolicies) {
+		changes = append(changes, fieldChange{Field: "ScaleDown Policies", Old: encodeScalingPolicies(scaleDown), New: row.ScaleDownPolicies})
+	}
+	return changes
+}
+
+func liveQuantity(list v1.ResourceList, name v1.ResourceName) string {
+	quantity, ok := list[name]
+	if !ok {
+		return "<unset>"
+	}
+	return quantity.String()
+}
+
+func int32Cell(value *int32) string {
+	if value == nil {
+		return "<unset>"
+	}
+	return strconv.Itoa(int(*value))
+}
+
+func utilizationCell(metrics []autoscalingv2.MetricSpec, name v1.ResourceName) string {
+	for _, metric := range metrics {
+		if metric.Type == autoscalingv2.ResourceMetricSourceType && metric.Resource != nil && metric.Resource.Name == name {
+			return int32Cell(metric.Resource.Target.AverageUtilization)
+		}
+	}
+	return "<unset>"
+}
+
+func stabilizationCell(rules *autoscalingv2.HPAScalingRules) string {
+	if rules == nil {
+		return "<unset>"
+	}
+	return int32Cell(rules.StabilizationWindowSeconds)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestWorkloadChangesOnlyListsDifferences(t *testing.T) {
+	maxUnavailable, maxSurge := intstr.FromString("25%"), intstr.FromInt(1)
+	live := &workload{
+		Template: v1.PodTemplateSpec{Spec: v1.PodSpec{Containers: []v1.Container{{
+			Name: "app",
+			Resources: v1.ResourceRequirements{
+				Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("100m"), v1.ResourceMemory: resource.MustParse("256Mi")},
+			},
+		}}}},
+		Strategy: &appsv1.DeploymentStrategy{RollingUpdate: &appsv1.RollingUpdateDeployment{MaxUnavailable: &maxUnavailable, MaxSurge: &maxSurge}},
+	}
+	row := patchRow{CPURequest: "250m", CPULimit: "0m", MemoryRequest: "256Mi", MemoryLimit: "512Mi", MaxUnavailable: "25%", MaxSurge: "2"}
+
+	want := []fieldChange{
+		{Field: "app CPU Request", Old: "100m", New: "250m"},
+		{Field: "app Memory Limit", Old: "<unset>", New: "512Mi"},
+		{Field: "MaxSurge", Old: "1", New: "2"},
+	}
+	if got := workloadChanges(live, row); !reflect.DeepEqual(got, want) {
+		t.Errorf("workloadChanges = %+v, want %+v", got, want)
+	}
+}
+
+func TestHPAChangesSkipsUntouchedSettings(t *testing.T) {
+	minReplicas, window := int32(2), int32(300)
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+		MinReplicas: &minReplicas,
+		MaxReplicas: 5,
+		Metrics:     []autoscalingv2.MetricSpec{utilizationMetric(v1.ResourceCPU, 80)},
+		Behavior: &autoscalingv2.HorizontalPodAutoscalerBehavior{
+			ScaleUp:   &autoscalingv2.HPAScalingRules{StabilizationWindowSeconds: &window},
+			ScaleDown: &autoscalingv2.HPAScalingRules{StabilizationWindowSeconds: &window},
+		},
+	}}
+	// No memory target and no policies in the row: both are left alone and not reported.
+	scaleUp, scaleDown := 0, 300
+	row := patchRow{MinReplicas: 2, MaxReplicas: 10, CPUTargetUtilization: 80, ScaleUpStabilization: &scaleUp, ScaleDownStabilization: &scaleDown}
+
+	want := []fieldChange{
+		{Field: "Max Replicas", Old: "5", New: "10"},
+		{Field: "ScaleUp Stabilization", Old: "300", New: "0"},
+	}
+	if got := hpaChanges(hpa, row); !reflect.DeepEqual(got, want) {
+		t.Errorf("hpaChanges = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"sigs.k8s.io/yaml"
+)
+
+// consoleConfig is the optional -config file shared by a team.
+type consoleConfig struct {
+	Profiles map[string]resourceProfile `json:"profiles"`
+}
+
+// resourceProfile is a named sizing (e.g. small/medium/large). Empty or zero fields keep the value
+// of the CSV row.
+type resourceProfile struct {
+	CPURequest           string `json:"cpuRequest,omitempty"`
+	CPULimit             string `json:"cpuLimit,omitempty"`
+	MemoryRequest        string `json:"memoryRequest,omitempty"`
+	MemoryLimit          string `json:"memoryLimit,omitempty"`
+	MinReplicas          int    `json:"minReplicas,omitempty"`
+	MaxReplicas          int    `json:"maxReplicas,omitempty"`
+	CPUTargetUtilization int    `json:"cpuTargetUtilization,omitempty"`
+}
+
+// loadConsoleConfig reads the config file. A missing file is not an error, it simply defines no
+// profiles; unknown keys are rejected so typos don't silently fall back to raw values.
+func loadConsoleConfig(path string) (consoleConfig, error) {
+	var config consoleConfig
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return config, fmt.Errorf("failed to read config file: %w", err)
+	}
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return config, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// profileNames returns the defined profile names, sorted, for error messages.
+func (c consoleConfig) profileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyProfile replaces the row's raw values with those of the profile named in its Profile cell,
+// or of -profile when the cell is empty. Per-container rows of -wide get the profile's resources
+// in every container.
+func applyProfile(row *patchRow, config consoleConfig) error {
+	name := row.Profile
+	if name == "" {
+		name = *defaultProfile
+	}
+	if name == "" {
+		return nil
+	}
+	profile, ok := config.Profiles[name]
+	if !ok {
+		return fmt.Errorf("unknown profile %q (defined in %s: %v)", name, *configFile, config.profileNames())
+	}
+
+	row.Profile = name
+	setIfNotEmpty(&row.CPURequest, profile.CPURequest)
+	setIfNotEmpty(&row.CPULimit, profile.CPULimit)
+	setIfNotEmpty(&row.MemoryRequest, profile.MemoryRequest)
+	setIfNotEmpty(&row.MemoryLimit, profile.MemoryLimit)
+	for i := range row.Containers {
+		setIfNotEmpty(&row.Containers[i].CPURequest, profile.CPURequest)
+		setIfNotEmpty(&row.Containers[i].CPULimit, profile.CPULimit)
+		setIfNotEmpty(&row.Containers[i].MemoryRequest, profile.MemoryRequest)
+		setIfNotEmpty(&row.Containers[i].MemoryLimit, profile.MemoryLimit)
+	}
+	setIfNotZero(&row.MinReplicas, profile.MinReplicas)
+	setIfNotZero(&row.MaxReplicas, profile.MaxReplicas)
+	setIfNotZero(&row.CPUTargetUtilization, profile.CPUTargetUtilization)
+	return nil
+}
+
+func setIfNotEmpty(field *string, value string) {
+	if value != "" {
+		*field = value
+	}
+}
+
+func setIfNotZero(field *int, value int) {
+	if value != 0 {
+		*field = value
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+// progress counts finished work items across goroutines and redraws the progress bar after each.
+type progress struct {
+	mu    sync.Mutex
+	done  int
+	total int
+	verb  string // e.g. "Collecting"
+}
+
+func newProgress(verb string, total int) *progress {
+	return &progress{total: total, verb: verb}
+}
+
+// step records one finished item.
+func (p *progress) step() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+	showSpinner(p.done, p.total, p.verb)
+}
+
+// stdoutIsTerminal reports whether stdout (possibly redirected by -summary-only) is a terminal;
+// the progress bar is only animated there.
+func stdoutIsTerminal() bool {
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// normalizeMemory parses a memory cell and returns the canonical form of the quantity, so the
+// patch applies exactly what the cell means. The CSV is written in binary units (Mi), so a cell
+// using a decimal SI suffix (k, M, G, ...) is accepted but comes with a warning: "512M" is
+// 512,000,000 bytes, about 24Mi less than "512Mi".
+func normalizeMemory(value string) (normalized, warning string, err error) {
+	value = strings.TrimSpace(value)
+	quantity, err := resource.ParseQuantity(value)
+	if err != nil {
+		return value, "", fmt.Errorf("invalid memory quantity %q: %w", value, err)
+	}
+
+	normalized = quantity.String()
+	if quantity.Format == resource.DecimalSI && strings.IndexAny(value, "kMGTPE") >= 0 {
+		binary := resource.NewQuantity(quantity.Value(), resource.BinarySI)
+		warning = fmt.Sprintf("memory %q uses decimal SI units (%d bytes = %s in binary units); did you mean %q?",
+			value, quantity.Value(), binary.String(), strings.TrimRight(value, "kMGTPE")+binaryEquivalent(value))
+	}
+	return normalized, warning, nil
+}
+
+// binaryEquivalent returns the binary suffix matching the decimal suffix of value, e.g. "M" -> "Mi".
+func binaryEquivalent(value string) string {
+	switch value[len(value)-1] {
+	case 'k':
+		return "Ki"
+	default:
+		return value[len(value)-1:] + "i"
+	}
+}
+
+// normalizeRowMemory normalizes every memory cell of the row in place and prints a warning for each
+// one that uses decimal SI units. Invalid quantities are left untouched for validateRowQuantities to report.
+func normalizeRowMemory(row *patchRow) {
+	normalize := func(field string, value *string) {
+		if *value == "" {
+			return
+		}
+		normalized, warning, err := normalizeMemory(*value)
+		if err != nil {
+			return
+		}
+		if warning != "" {
+			logger.Warn(warning, "name", row.DeploymentName, "column", field)
+		}
+		*value = normalized
+	}
+
+	normalize("Memory Request", &row.MemoryRequest)
+	normalize("Memory Limit", &row.MemoryLimit)
+	for i := range row.Containers {
+		container := &row.Containers[i]
+		normalize(container.Name+" Memory Request", &container.MemoryRequest)
+		normalize(container.Name+" Memory Limit", &container.MemoryLimit)
+	}
+}
+
+// validateRowQuantities parses every resource cell the row would apply, so a typo like "100mm" is
+// reported with its column before anything is patched instead of as a kubectl error. Empty cells
+// are not applied and not checked.
+func validateRowQuantities(row patchRow) error {
+	if !row.UpdateResourceAndHPA {
+		return nil // HPA-only rows don't touch resources
+	}
+
+	var problems []string
+	check := func(column, value string) {
+		if value = strings.TrimSpace(value); value == "" {
+			return
+		}
+		if _, err := resource.ParseQuantity(value); err != nil {
+			problems = append(problems, fmt.Sprintf("column %q: %q is not a valid quantity", column, value))
+		}
+	}
+
+	if len(row.Containers) == 0 {
+		check("CPU Request", row.CPURequest)
+		check("CPU Limit", row.CPULimit)
+		check("Memory Request", row.MemoryRequest)
+		check("Memory Limit", row.MemoryLimit)
+		check("Ephemeral Storage Request", row.EphemeralStorageRequest)
+		check("Ephemeral Storage Limit", row.EphemeralStorageLimit)
+	}
+	for _, container := range row.Containers {
+		check(container.Name+" CPU Request", container.CPURequest)
+		check(container.Name+" CPU Limit", container.CPULimit)
+		check(container.Name+" Memory Request", container.MemoryRequest)
+		check(container.Name+" Memory Limit", container.MemoryLimit)
+		check(container.Name+" Ephemeral Storage Request", container.EphemeralStorageRequest)
+		check(container.Name+" Ephemeral Storage Limit", container.EphemeralStorageLimit)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// validateRequestsWithinLimits refuses a row whose CPU, memory or ephemeral storage request is above the matching
+// limit, which the API server would reject with a much less helpful message. A blank or zero
+// limit means "no limit" and always passes. Run it after validateRowQuantities.
+func validateRequestsWithinLimits(row patchRow) error {
+	if !row.UpdateResourceAndHPA {
+		return nil
+	}
+
+	var problems []string
+	check := func(prefix, kind, request, limit string) {
+		if request == "" || !hasLimit(limit) {
+			return
+		}
+		requested, limited := resource.MustParse(request), resource.MustParse(limit)
+		if requested.Cmp(limited) > 0 {
+			problems = append(problems, fmt.Sprintf("%s%s Request %s is above its limit %s", prefix, kind, request, limit))
+		}
+	}
+
+	if len(row.Containers) == 0 {
+		check("", "CPU", row.CPURequest, row.CPULimit)
+		check("", "Memory", row.MemoryRequest, row.MemoryLimit)
+		check("", "Ephemeral Storage", row.EphemeralStorageRequest, row.EphemeralStorageLimit)
+	}
+	for _, container := range row.Containers {
+		check(container.Name+" ", "CPU", container.CPURequest, container.CPULimit)
+		check(container.Name+" ", "Memory", container.MemoryRequest, container.MemoryLimit)
+		check(container.Name+" ", "Ephemeral Storage", container.EphemeralStorageRequest, container.EphemeralStorageLimit)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestNormalizeMemoryDecimalVersusBinary(t *testing.T) {
+	tests := []struct {
+		value       string
+		normalized  string
+		bytes       int64
+		wantWarning bool
+	}{
+		{"512Mi", "512Mi", 512 * 1024 * 1024, false},
+		{"512M", "512M", 512 * 1000 * 1000, true},
+		{"1Gi", "1Gi", 1024 * 1024 * 1024, false},
+		{"1G", "1G", 1000 * 1000 * 1000, true},
+		{"0.5Gi", "512Mi", 512 * 1024 * 1024, false},
+		{"536870912", "536870912", 512 * 1024 * 1024, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			normalized, warning, err := normalizeMemory(tt.value)
+			if err != nil {
+				t.Fatalf("normalizeMemory(%q): %v", tt.value, err)
+			}
+			if normalized != tt.normalized {
+				t.Errorf("normalized = %q, want %q", normalized, tt.normalized)
+			}
+			if (warning != "") != tt.wantWarning {
+				t.Errorf("warning = %q, want warning: %t", warning, tt.wantWarning)
+			}
+
+			// The normalized value must mean exactly the same number of bytes as the cell.
+			quantity := resource.MustParse(normalized)
+			if got := quantity.Value(); got != tt.bytes {
+				t.Errorf("%q is %d bytes, want %d", normalized, got, tt.bytes)
+			}
+		})
+	}
+}
+
+func TestNormalizeMemoryDecimalAndBinaryAreNotConfused(t *testing.T) {
+	decimal, warning, _ := normalizeMemory("512M")
+	binary, _, _ := normalizeMemory("512Mi")
+
+	decimalQuantity := resource.MustParse(decimal)
+	if decimalQuantity.Cmp(resource.MustParse(binary)) == 0 {
+		t.Fatalf("512M and 512Mi must not be treated as equal")
+	}
+	if warning == "" {
+		t.Fatalf("expected a warning for 512M")
+	}
+}
+
+func TestNormalizeMemoryInvalid(t *testing.T) {
+	if _, _, err := normalizeMemory("512MB"); err == nil {
+		t.Error("expected an error for 512MB")
+	}
+}
+
+func TestValidateRowQuantities(t *testing.T) {
+	row := patchRow{DeploymentName: "web", UpdateResourceAndHPA: true, CPURequest: "100mm", CPULimit: "500m", MemoryRequest: "256Mi", MemoryLimit: "1 Gi"}
+	err := validateRowQuantities(row)
+	if err == nil {
+		t.Fatal("validateRowQuantities() = nil, want an error for CPU Request and Memory Limit")
+	}
+	for _, want := range []string{`"CPU Request": "100mm"`, `"Memory Limit": "1 Gi"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+
+	row.UpdateResourceAndHPA, row.UpdateHPAOnly = false, true
+	if err := validateRowQuantities(row); err != nil {
+		t.Errorf("validateRowQuantities() = %v for an HPA-only row, want nil", err)
+	}
+}
+
+func TestValidateRequestsWithinLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		row     patchRow
+		wantErr bool
+	}{
+		{"within limits", patchRow{CPURequest: "100m", CPULimit: "0.5", MemoryRequest: "256Mi", MemoryLimit: "256Mi"}, false},
+		{"no limit", patchRow{CPURequest: "2", CPULimit: "0", MemoryRequest: "1Gi", MemoryLimit: ""}, false},
+		{"cpu above limit", patchRow{CPURequest: "600m", CPULimit: "500m"}, true},
+		{"container memory above limit", patchRow{Containers: []ContainerResources{{Name: "app", MemoryRequest: "1Gi", MemoryLimit: "512Mi"}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.row.UpdateResourceAndHPA = true
+			if err := validateRequestsWithinLimits(tt.row); (err != nil) != tt.wantErr {
+				t.Errorf("validateRequestsWithinLimits() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
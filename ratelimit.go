@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+var (
+	kubectlLimiter     flowcontrol.RateLimiter
+	kubectlLimiterOnce sync.Once
+)
+
+// validateRateLimits rejects limits the token bucket could never satisfy.
+func validateRateLimits() error {
+	if *qps <= 0 {
+		return fmt.Errorf("-qps must be greater than 0, got %v", *qps)
+	}
+	if *burst < 1 {
+		return fmt.Errorf("-burst must be at least 1, got %d", *burst)
+	}
+	return nil
+}
+
+// applyRateLimits sets the client-go request rate from -qps/-burst.
+func applyRateLimits(config *rest.Config) {
+	config.QPS = float32(*qps)
+	config.Burst = *burst
+}
+
+// throttleKubectl blocks until the token bucket shared by all kubectl invocations allows another
+// one, so bulk patch and restart runs proceed at the -qps/-burst pace.
+func throttleKubectl() {
+	kubectlLimiterOnce.Do(func() {
+		kubectlLimiter = flowcontrol.NewTokenBucketRateLimiter(float32(*qps), *burst)
+	})
+	kubectlLimiter.Accept()
+}
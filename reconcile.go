@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/yaml"
+)
+
+// desiredState is the Git-tracked YAML consumed by -reconcile. It only covers the fields this tool
+// manages; everything a deployment entry leaves out is not reconciled.
+type desiredState struct {
+	Deployments []desiredDeployment `json:"deployments"`
+}
+
+type desiredDeployment struct {
+	Namespace            string `json:"namespace"`
+	Name                 string `json:"name"`
+	CPURequest           string `json:"cpuRequest,omitempty"`
+	CPULimit             string `json:"cpuLimit,omitempty"`
+	MemoryRequest        string `json:"memoryRequest,omitempty"`
+	MemoryLimit          string `json:"memoryLimit,omitempty"`
+	MaxUnavailable       string `json:"maxUnavailable,omitempty"`
+	MaxSurge             string `json:"maxSurge,omitempty"`
+	MinReplicas          int32  `json:"minReplicas,omitempty"`
+	MaxReplicas          int32  `json:"maxReplicas,omitempty"`
+	CPUTargetUtilization int32  `json:"cpuTargetUtilization,omitempty"`
+}
+
+func loadDesiredState(path string) (desiredState, error) {
+	var desired desiredState
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return desired, fmt.Errorf("failed to read desired state: %w", err)
+	}
+	if err := yaml.UnmarshalStrict(data, &desired); err != nil {
+		return desired, fmt.Errorf("invalid desired state %s: %w", path, err)
+	}
+	return desired, nil
+}
+
+// buildReconcilePlan compares the desired state with the live deployments and returns the rows
+// that need a patch, with the desired values filled in and the Update columns set, plus a
+// human-readable line per difference. Desired deployments missing from the cluster are reported
+// as differences too but produce no row.
+func buildReconcilePlan(desired desiredState, live []DeploymentInfo) ([]DeploymentInfo, []string) {
+	byKey := make(map[string]DeploymentInfo, len(live))
+	for _, deploy := range live {
+		byKey[deploy.Namespace+"/"+deploy.Name] = deploy
+	}
+
+	var plan []DeploymentInfo
+	var changes []string
+	for _, want := range desired.Deployments {
+		key := want.Namespace + "/" + want.Name
+		deploy, ok := byKey[key]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("%s: not found in the cluster", key))
+			continue
+		}
+
+		resourcesChanged, hpaChanged := false, false
+		change := func(column, from, to string) {
+			changes = append(changes, fmt.Sprintf("%s: %s %s → %s", key, column, from, to))
+		}
+		setQuantity := func(column string, field *string, value string) {
+			if value != "" && !sameQuantity(*field, value) {
+				change(column, *field, value)
+				*field, resourcesChanged = value, true
+			}
+		}
+		setString := func(column string, field *string, value string) {
+			if value != "" && *field != value {
+				change(column, *field, value)
+				*field, resourcesChanged = value, true
+			}
+		}
+		setInt := func(column string, field *int32, value int32) {
+			if value != 0 && *field != value {
+				change(column, strconv.Itoa(int(*field)), strconv.Itoa(int(value)))
+				*field, hpaChanged = value, true
+			}
+		}
+
+		setQuantity("CPU Request", &deploy.CPURequest, want.CPURequest)
+		setQuantity("CPU Limit", &deploy.CPULimit, want.CPULimit)
+		setQuantity("Memory Request", &deploy.MemoryRequest, want.MemoryRequest)
+		setQuantity("Memory Limit", &deploy.MemoryLimit, want.MemoryLimit)
+		setString("MaxUnavailable", &deploy.MaxUnavailable, want.MaxUnavailable)
+		setString("MaxSurge", &deploy.MaxSurge, want.MaxSurge)
+		if deploy.HasHPA {
+			setInt("Min Replicas", &deploy.MinReplicas, want.MinReplicas)
+			setInt("Max Replicas", &deploy.MaxReplicas, want.MaxReplicas)
+			setInt("CPU Target Utilization", &deploy.CPUTargetUtilization, want.CPUTargetUtilization)
+		} else if want.MinReplicas != 0 || want.MaxReplicas != 0 || want.CPUTargetUtilization != 0 {
+			changes = append(changes, fmt.Sprintf("%s: has no HPA, desired HPA values are not reconciled", key))
+		}
+
+		switch {
+		case resourcesChanged:
+			deploy.UpdateResourceAndHPA = true
+		case hpaChanged:
+			deploy.UpdateHPAOnly = true
+		default:
+			continue
+		}
+		plan = append(plan, deploy)
+	}
+	return plan, changes
+}
+
+// sameQuantity compares two quantities by value so "1" and "1000m" are equal.
+func sameQuantity(have, want string) bool {
+	haveQuantity, err := resource.ParseQuantity(have)
+	if err != nil {
+		return false
+	}
+	wantQuantity, err := resource.ParseQuantity(want)
+	if err != nil {
+		return false
+	}
+	return haveQuantity.Cmp(wantQuantity) == 0
+}
+
+// writeReconcilePlan builds the plan for the live data and writes it in the patch CSV format.
+func writeReconcilePlan(data []DeploymentInfo, desiredPath, planPath string) error {
+	desired, err := loadDesiredState(desiredPath)
+	if err != nil {
+		return err
+	}
+	plan, changes := buildReconcilePlan(desired, data)
+
+	fmt.Printf("\n📋 Reconciliation plan against %s:\n", desiredPath)
+	if len(changes) == 0 {
+		fmt.Println("   The cluster matches the desired state.")
+	}
+	for _, change := range changes {
+		fmt.Printf("   - %s\n", change)
+	}
+	return writeCSV(plan, planPath)
+}
@@ -0,0 +1,31 @@
+package main
+
+import "testing"
+
+func TestBuildReconcilePlan(t *testing.T) {
+	live := []DeploymentInfo{
+		{Namespace: "shop", Name: "web", CPURequest: "1", MemoryRequest: "256Mi", HasHPA: true, MinReplicas: 2, MaxReplicas: 5},
+		{Namespace: "shop", Name: "api", CPURequest: "500m", HasHPA: true, MinReplicas: 1, MaxReplicas: 3},
+		{Namespace: "shop", Name: "worker", CPURequest: "100m"},
+	}
+	desired := desiredState{Deployments: []desiredDeployment{
+		{Namespace: "shop", Name: "web", CPURequest: "1000m", MemoryRequest: "256Mi"}, // equal quantities
+		{Namespace: "shop", Name: "api", MaxReplicas: 6},
+		{Namespace: "shop", Name: "worker", CPURequest: "200m"},
+		{Namespace: "shop", Name: "gone", CPURequest: "1"},
+	}}
+
+	plan, changes := buildReconcilePlan(desired, live)
+	if len(plan) != 2 {
+		t.Fatalf("plan has %d rows, want 2 (api, worker): %+v", len(plan), plan)
+	}
+	if plan[0].Name != "api" || !plan[0].UpdateHPAOnly || plan[0].MaxReplicas != 6 {
+		t.Errorf("api row = %+v, want an HPA-only update to maxReplicas 6", plan[0])
+	}
+	if plan[1].Name != "worker" || !plan[1].UpdateResourceAndHPA || plan[1].CPURequest != "200m" {
+		t.Errorf("worker row = %+v, want a resource update to 200m", plan[1])
+	}
+	if len(changes) != 3 {
+		t.Errorf("changes = %q, want 3 (api, worker, gone)", changes)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// patchesHPA reports whether applying the row would update its HPA.
+func (row patchRow) patchesHPA() bool {
+	return hasReplicaCount(row.Kind) && row.hasHPA() && (row.UpdateResourceAndHPA || row.UpdateHPAOnly)
+}
+
+// missingReferences checks that the workload of every row, and the HPA of every row patching one,
+// exists. The workloads and HPAs are listed once per namespace instead of being fetched per row.
+// The returned slice holds, for each row, the problem found or nil.
+func missingReferences(clientset kubernetes.Interface, rows []patchRow) ([]error, error) {
+	ctx, cancel := apiContext()
+	defer cancel()
+
+	// existing holds "kind/namespace/name" for every listed object, listed "kind/namespace".
+	existing := make(map[string]bool)
+	listed := make(map[string]bool)
+	listNames := func(kind, namespace string) error {
+		if listed[kind+"/"+namespace] {
+			return nil
+		}
+		listed[kind+"/"+namespace] = true
+		var names []string
+		err := retryTransient("list "+kind+"s", func() error {
+			names = names[:0]
+			switch kind {
+			case kindStatefulSet:
+				list, err := clientset.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+				if err != nil {
+					return err
+				}
+				for _, item := range list.Items {
+					names = append(names, item.Name)
+				}
+			case kindDaemonSet:
+				list, err := clientset.AppsV1().DaemonSets(namespace).List(ctx, metav1.ListOptions{})
+				if err != nil {
+					return err
+				}
+				for _, item := range list.Items {
+					names = append(names, item.Name)
+				}
+			case "HPA":
+				hpas, err := listHPAs(ctx, clientset, namespace, metav1.ListOptions{})
+				if err != nil {
+					return err
+				}
+				for _, item := range hpas {
+					names = append(names, item.Name)
+				}
+			default:
+				list, err := clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+				if err != nil {
+					return err
+				}
+				for _, item := range list.Items {
+					names = append(names, item.Name)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list %ss in namespace %s: %w", kind, namespace, err)
+		}
+		for _, name := range names {
+			existing[kind+"/"+namespace+"/"+name] = true
+		}
+		return nil
+	}
+
+	problems := make([]error, len(rows))
+	for i, row := range rows {
+		if err := listNames(row.Kind, row.Namespace); err != nil {
+			return nil, err
+		}
+		if !existing[row.Kind+"/"+row.Namespace+"/"+row.DeploymentName] {
+			problems[i] = fmt.Errorf("%s %s not found in namespace %s", row.Kind, row.DeploymentName, row.Namespace)
+			continue
+		}
+		if !row.patchesHPA() {
+			continue
+		}
+		if err := listNames("HPA", row.Namespace); err != nil {
+			return nil, err
+		}
+		if !existing["HPA/"+row.Namespace+"/"+row.DeploymentName] {
+			problems[i] = fmt.Errorf("HPA %s not found in namespace %s (Max Replicas is set, clear it for a workload without an HPA)", row.DeploymentName, row.Namespace)
+		}
+	}
+	return problems, nil
+}
+
+// preflightReferences is the pass of the patch action that runs before anything is applied: rows
+// naming a workload or HPA missing from their cluster are reported together, so the file can be
+// corrected first instead of failing with a kubectl error halfway through the run. These rows are
+// skipped and passed to fail; with -strict the first one aborts the run. When the objects can't be
+// listed (e.g. RBAC only allows patching) the check is skipped with a warning.
+func preflightReferences(rows []patchRow, fail func(patchRow)) ([]patchRow, error) {
+	initialContext := activeContext
+	defer func() { activeContext = initialContext }()
+
+	var kept []patchRow
+	contexts, groups := rowContexts(rows)
+	for _, context := range contexts {
+		activeContext = context
+		clientset, _, err := getKubeClient()
+		var problems []error
+		if err == nil {
+			problems, err = missingReferences(clientset, groups[context])
+		}
+		if err != nil {
+			logger.Warn("can't check that the rows reference existing objects, applying them unchecked", "context", context, "err", err)
+			kept = append(kept, groups[context]...)
+			continue
+		}
+		for i, row := range groups[context] {
+			if problems[i] == nil {
+				kept = append(kept, row)
+				continue
+			}
+			if *strict {
+				return nil, withExitCode(exitUsage, fmt.Errorf("row %s/%s: %w", row.Namespace, row.DeploymentName, problems[i]))
+			}
+			logger.Warn("row references a missing object, skipping", "context", context, "namespace", row.Namespace, "name", row.DeploymentName, "err", problems[i])
+			fail(row)
+		}
+	}
+	return kept, nil
+}
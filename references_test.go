@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestMissingReferences(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "prod"}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "prod"}},
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "prod"}},
+		&autoscalingv2.HorizontalPodAutoscaler{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "prod"}},
+	)
+	rows := []patchRow{
+		{Kind: kindDeployment, Namespace: "prod", DeploymentName: "api", MaxReplicas: 5, UpdateHPAOnly: true},
+		{Kind: kindDeployment, Namespace: "prod", DeploymentName: "gone", UpdateResourceAndHPA: true},
+		{Kind: kindDeployment, Namespace: "prod", DeploymentName: "web", MaxReplicas: 5, UpdateResourceAndHPA: true},
+		{Kind: kindDeployment, Namespace: "prod", DeploymentName: "web", UpdateResourceAndHPA: true},
+		{Kind: kindStatefulSet, Namespace: "prod", DeploymentName: "db", UpdateReplicas: true},
+		{Kind: kindStatefulSet, Namespace: "prod", DeploymentName: "api", UpdateReplicas: true},
+	}
+	problems, err := missingReferences(clientset, rows)
+	if err != nil {
+		t.Fatalf("missingReferences() = %v", err)
+	}
+	// The missing deployment, the missing HPA of web and the Deployment named like a StatefulSet.
+	want := []bool{false, true, true, false, false, true}
+	for i, missing := range want {
+		if (problems[i] != nil) != missing {
+			t.Errorf("row %d (%s %s): problem = %v, want one: %v", i, rows[i].Kind, rows[i].DeploymentName, problems[i], missing)
+		}
+	}
+}
@@ -0,0 +1,394 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// hpaCoverage summarizes the autoscaling health of a single namespace.
+type hpaCoverage struct {
+	Namespace     string
+	Deployments   int
+	WithHPA       int
+	FixedReplicas int     // HPAs with maxReplicas == minReplicas, i.e. no effective autoscaling
+	cpuTargets    []int32 // CPU target utilization of every HPA that sets one
+}
+
+// buildHPACoverage aggregates the gathered deployment data into one hpaCoverage per namespace,
+// sorted by namespace name.
+func buildHPACoverage(data []DeploymentInfo) []hpaCoverage {
+	byNamespace := make(map[string]*hpaCoverage)
+	for _, deploy := range data {
+		coverage, ok := byNamespace[deploy.Namespace]
+		if !ok {
+			coverage = &hpaCoverage{Namespace: deploy.Namespace}
+			byNamespace[deploy.Namespace] = coverage
+		}
+
+		coverage.Deployments++
+		if !deploy.HasHPA {
+			continue
+		}
+		coverage.WithHPA++
+		if deploy.MaxReplicas == deploy.MinReplicas {
+			coverage.FixedReplicas++
+		}
+		if deploy.CPUTargetUtilization > 0 {
+			coverage.cpuTargets = append(coverage.cpuTargets, deploy.CPUTargetUtilization)
+		}
+	}
+
+	report := make([]hpaCoverage, 0, len(byNamespace))
+	for _, coverage := range byNamespace {
+		report = append(report, *coverage)
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].Namespace < report[j].Namespace })
+	return report
+}
+
+// cpuTargetStats returns the average, minimum and maximum CPU target as display strings,
+// or "N/A" when no HPA in the namespace sets a CPU target.
+func (c hpaCoverage) cpuTargetStats() (avg, min, max string) {
+	if len(c.cpuTargets) == 0 {
+		return "N/A", "N/A", "N/A"
+	}
+
+	lowest, highest, total := c.cpuTargets[0], c.cpuTargets[0], int32(0)
+	for _, target := range c.cpuTargets {
+		total += target
+		if target < lowest {
+			lowest = target
+		}
+		if target > highest {
+			highest = target
+		}
+	}
+	average := float64(total) / float64(len(c.cpuTargets))
+	return strconv.FormatFloat(average, 'f', 1, 64), strconv.Itoa(int(lowest)), strconv.Itoa(int(highest))
+}
+
+var hpaCoverageHeader = []string{
+	"Namespace", "Deployments", "With HPA", "Without HPA",
+	"Avg CPU Target", "Min CPU Target", "Max CPU Target", "Fixed Replicas (min==max)",
+}
+
+func (c hpaCoverage) record() []string {
+	avg, min, max := c.cpuTargetStats()
+	return []string{
+		c.Namespace,
+		strconv.Itoa(c.Deployments),
+		strconv.Itoa(c.WithHPA),
+		strconv.Itoa(c.Deployments - c.WithHPA),
+		avg, min, max,
+		strconv.Itoa(c.FixedReplicas),
+	}
+}
+
+// writeHPACoverageReport saves the per-namespace HPA coverage into a CSV file and prints it as a table.
+func writeHPACoverageReport(data []DeploymentInfo, path string) error {
+	report := buildHPACoverage(data)
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create HPA coverage report: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Comma = csvDelimiter()
+	if err := writer.Write(hpaCoverageHeader); err != nil {
+		return fmt.Errorf("failed to write HPA coverage header: %w", err)
+	}
+	for _, coverage := range report {
+		if err := writer.Write(coverage.record()); err != nil {
+			return fmt.Errorf("failed to write HPA coverage record: %w", err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write HPA coverage report: %w", err)
+	}
+
+	printTable(hpaCoverageHeader, func(row func([]string)) {
+		for _, coverage := range report {
+			row(coverage.record())
+		}
+	})
+	return nil
+}
+
+// printTable renders rows as an aligned table on stdout.
+func printTable(header []string, rows func(row func([]string))) {
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	writeRow := func(cells []string) {
+		for i, cell := range cells {
+			if i > 0 {
+				fmt.Fprint(table, "\t")
+			}
+			fmt.Fprint(table, cell)
+		}
+		fmt.Fprintln(table)
+	}
+
+	fmt.Println()
+	writeRow(header)
+	rows(writeRow)
+	table.Flush()
+}
+
+// unassignedTeam groups the deployments that carry no team label or annotation.
+const unassignedTeam = "unassigned"
+
+// teamUsage holds the resource subtotals of one team. CPU and memory are the requests of all
+// replicas, i.e. per-pod requests multiplied by the replica count.
+type teamUsage struct {
+	Team          string
+	Deployments   int
+	Replicas      int64
+	CPUMillicores int64
+	MemoryMiB     int64
+}
+
+// teamOf returns the value of the team label (falling back to the annotation of the same key).
+func teamOf(deploy DeploymentInfo, key string) string {
+	if team := deploy.Labels[key]; team != "" {
+		return team
+	}
+	if team := deploy.Annotations[key]; team != "" {
+		return team
+	}
+	return unassignedTeam
+}
+
+// buildTeamUsage groups the deployments by team, sorted by team name with "unassigned" last.
+func buildTeamUsage(data []DeploymentInfo, key string) []teamUsage {
+	byTeam := make(map[string]*teamUsage)
+	for _, deploy := range data {
+		team := teamOf(deploy, key)
+		usage, ok := byTeam[team]
+		if !ok {
+			usage = &teamUsage{Team: team}
+			byTeam[team] = usage
+		}
+
+		replicas := int64(deploy.Replicas)
+		usage.Deployments++
+		usage.Replicas += replicas
+		usage.CPUMillicores += milliCPU(deploy.CPURequest) * replicas
+		usage.MemoryMiB += mebibytes(deploy.MemoryRequest) * replicas
+	}
+
+	report := make([]teamUsage, 0, len(byTeam))
+	for _, usage := range byTeam {
+		report = append(report, *usage)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		if (report[i].Team == unassignedTeam) != (report[j].Team == unassignedTeam) {
+			return report[j].Team == unassignedTeam
+		}
+		return report[i].Team < report[j].Team
+	})
+	return report
+}
+
+var teamUsageHeader = []string{"Team", "Deployments", "Replicas", "CPU Requests", "Memory Requests"}
+
+func (u teamUsage) record() []string {
+	return []string{
+		u.Team,
+		strconv.Itoa(u.Deployments),
+		strconv.FormatInt(u.Replicas, 10),
+		fmt.Sprintf("%dm", u.CPUMillicores),
+		fmt.Sprintf("%dMi", u.MemoryMiB),
+	}
+}
+
+// writeTeamReport saves the per-team subtotals plus a grand total into a CSV file and prints them as a table.
+func writeTeamReport(data []DeploymentInfo, key, path string) error {
+	report := buildTeamUsage(data, key)
+	total := teamUsage{Team: "TOTAL"}
+	for _, usage := range report {
+		total.Deployments += usage.Deployments
+		total.Replicas += usage.Replicas
+		total.CPUMillicores += usage.CPUMillicores
+		total.MemoryMiB += usage.MemoryMiB
+	}
+	rows := append(report, total)
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create team report: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Comma = csvDelimiter()
+	if err := writer.Write(teamUsageHeader); err != nil {
+		return fmt.Errorf("failed to write team report header: %w", err)
+	}
+	for _, usage := range rows {
+		if err := writer.Write(usage.record()); err != nil {
+			return fmt.Errorf("failed to write team report record: %w", err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write team report: %w", err)
+	}
+
+	printTable(teamUsageHeader, func(row func([]string)) {
+		for _, usage := range rows {
+			row(usage.record())
+		}
+	})
+	return nil
+}
+
+// milliCPU parses a CPU quantity such as "250m" into millicores, treating invalid values as 0.
+func milliCPU(value string) int64 {
+	quantity, err := resource.ParseQuantity(value)
+	if err != nil {
+		return 0
+	}
+	return quantity.MilliValue()
+}
+
+// mebibytes parses a memory quantity such as "512Mi" into MiB, treating invalid values as 0.
+func mebibytes(value string) int64 {
+	quantity, err := resource.ParseQuantity(value)
+	if err != nil {
+		return 0
+	}
+	return quantity.Value() / (1024 * 1024)
+}
+
+// capacityTotals holds the scheduled footprint of a namespace (or of everything, for the TOTAL
+// row): per-pod requests and limits multiplied by the replica count, and by minReplicas for the
+// footprint the HPAs can scale down to. DaemonSets count one pod, as their pod count follows the nodes.
+type capacityTotals struct {
+	Namespace         string
+	Workloads         int
+	Replicas          int64
+	CPURequests       int64 // millicores
+	CPULimits         int64
+	MemoryRequests    int64 // MiB
+	MemoryLimits      int64
+	MinCPURequests    int64
+	MinMemoryRequests int64
+	MissingRequests   int // workloads without a CPU or memory request
+}
+
+var capacitySummaryHeader = []string{"Namespace", "Workloads", "Replicas", "CPU Requests", "CPU Limits", "Memory Requests", "Memory Limits", "CPU Requests At Min Replicas", "Memory Requests At Min Replicas", "Without Requests"}
+
+// nodeCapacityHeader holds the columns -include-node-capacity adds to the capacity summary.
+var nodeCapacityHeader = []string{"CPU Requests % Of Allocatable", "Memory Requests % Of Allocatable"}
+
+func (c *capacityTotals) add(deploy DeploymentInfo) {
+	replicas, minReplicas := int64(1), int64(1)
+	if hasReplicaCount(deploy.Kind) {
+		replicas, minReplicas = int64(deploy.Replicas), int64(deploy.Replicas)
+		if deploy.HasHPA {
+			minReplicas = int64(deploy.MinReplicas)
+		}
+	}
+	cpuRequest, memoryRequest := milliCPU(deploy.CPURequest), mebibytes(deploy.MemoryRequest)
+
+	c.Workloads++
+	c.Replicas += replicas
+	c.CPURequests += cpuRequest * replicas
+	c.CPULimits += milliCPU(deploy.CPULimit) * replicas
+	c.MemoryRequests += memoryRequest * replicas
+	c.MemoryLimits += mebibytes(deploy.MemoryLimit) * replicas
+	c.MinCPURequests += cpuRequest * minReplicas
+	c.MinMemoryRequests += memoryRequest * minReplicas
+	if cpuRequest == 0 || memoryRequest == 0 {
+		c.MissingRequests++
+	}
+}
+
+// record renders the totals; with the node capacity (-include-node-capacity) the requests are
+// also given as a share of the cluster's allocatable CPU and memory.
+func (c capacityTotals) record(capacity *nodeCapacity) []string {
+	record := []string{
+		c.Namespace,
+		strconv.Itoa(c.Workloads),
+		strconv.FormatInt(c.Replicas, 10),
+		fmt.Sprintf("%dm", c.CPURequests),
+		fmt.Sprintf("%dm", c.CPULimits),
+		fmt.Sprintf("%dMi", c.MemoryRequests),
+		fmt.Sprintf("%dMi", c.MemoryLimits),
+		fmt.Sprintf("%dm", c.MinCPURequests),
+		fmt.Sprintf("%dMi", c.MinMemoryRequests),
+		strconv.Itoa(c.MissingRequests),
+	}
+	if capacity != nil {
+		record = append(record, percentOf(c.CPURequests, capacity.CPU), percentOf(c.MemoryRequests, capacity.Memory))
+	}
+	return record
+}
+
+// buildCapacitySummary returns the totals per namespace, sorted by namespace, followed by the TOTAL row.
+func buildCapacitySummary(data []DeploymentInfo) []capacityTotals {
+	byNamespace := make(map[string]*capacityTotals)
+	total := capacityTotals{Namespace: "TOTAL"}
+	for _, deploy := range data {
+		totals, ok := byNamespace[deploy.Namespace]
+		if !ok {
+			totals = &capacityTotals{Namespace: deploy.Namespace}
+			byNamespace[deploy.Namespace] = totals
+		}
+		totals.add(deploy)
+		total.add(deploy)
+	}
+
+	rows := make([]capacityTotals, 0, len(byNamespace)+1)
+	for _, totals := range byNamespace {
+		rows = append(rows, *totals)
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Namespace < rows[j].Namespace })
+	return append(rows, total)
+}
+
+// writeCapacitySummary saves the per-namespace capacity totals into a CSV file and prints them as a
+// table. capacity is nil unless -include-node-capacity is set.
+func writeCapacitySummary(data []DeploymentInfo, path string, capacity *nodeCapacity) error {
+	rows := buildCapacitySummary(data)
+	header := capacitySummaryHeader
+	if capacity != nil {
+		header = append(append([]string{}, header...), nodeCapacityHeader...)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create capacity summary: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Comma = csvDelimiter()
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("failed to write capacity summary header: %w", err)
+	}
+	for _, totals := range rows {
+		if err := writer.Write(totals.record(capacity)); err != nil {
+			return fmt.Errorf("failed to write capacity summary record: %w", err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write capacity summary: %w", err)
+	}
+
+	printTable(header, func(row func([]string)) {
+		for _, totals := range rows {
+			row(totals.record(capacity))
+		}
+	})
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+// precondition returns the resourceVersion to enforce on a patch, or "" when
+// -check-resource-version is off or the CSV didn't record one.
+func precondition(resourceVersion string) string {
+	if !*checkResourceVersion {
+		return ""
+	}
+	return resourceVersion
+}
+
+// metadataPrecondition returns the `"metadata":{"resourceVersion":...},` prefix of a merge patch.
+// The API server rejects the patch with a conflict when the live resourceVersion differs.
+func metadataPrecondition(resourceVersion string) string {
+	if resourceVersion == "" {
+		return ""
+	}
+	return fmt.Sprintf(`"metadata":{"resourceVersion":%q},`, resourceVersion)
+}
+
+// checkResourceVersions compares the resourceVersions recorded in the CSV with the live deployment
+// and HPA before anything is patched, so a concurrent edit is reported with both versions instead
+// of surfacing as a conflict halfway through the row.
+func checkResourceVersions(clientset kubernetes.Interface, row patchRow) error {
+	ctx, cancel := apiContext()
+	defer cancel()
+
+	if want := precondition(row.ResourceVersion); want != "" && row.UpdateResourceAndHPA {
+		live, err := getWorkload(clientset, row)
+		if err != nil {
+			return err
+		}
+		if live.ResourceVersion != want {
+			return fmt.Errorf("conflict: %s %s was modified since the CSV was generated (resourceVersion %s, now %s), skipping", kubectlKind(row.Kind), row.DeploymentName, want, live.ResourceVersion)
+		}
+	}
+
+	if want := precondition(row.HPAResourceVersion); want != "" {
+		hpa, err := getHPA(ctx, clientset, row.Namespace, row.DeploymentName)
+		if err != nil {
+			return fmt.Errorf("failed to get HPA %s: %w", row.DeploymentName, err)
+		}
+		if hpa.ResourceVersion != want {
+			return fmt.Errorf("conflict: HPA %s was modified since the CSV was generated (resourceVersion %s, now %s), skipping", row.DeploymentName, want, hpa.ResourceVersion)
+		}
+	}
+	return nil
+}
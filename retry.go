@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+)
+
+// validateMaxRetries rejects a negative -max-retries.
+func validateMaxRetries() error {
+	if *maxRetries < 0 {
+		return fmt.Errorf("-max-retries must be 0 or more, got %d", *maxRetries)
+	}
+	return nil
+}
+
+// retryBackoff waits 500ms before the first retry and doubles the wait for each further one, up to
+// -max-retries retries.
+func retryBackoff() wait.Backoff {
+	return wait.Backoff{Steps: *maxRetries + 1, Duration: 500 * time.Millisecond, Factor: 2, Jitter: 0.1, Cap: 10 * time.Second}
+}
+
+// isTransient reports whether err is worth retrying: the API server throttled the request (429),
+// was briefly unavailable or overloaded, or the connection was reset. Anything the server actually
+// decided on (not found, conflict, invalid) fails right away, and so does an exceeded -timeout.
+func isTransient(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	return apierrors.IsTooManyRequests(err) || apierrors.IsServiceUnavailable(err) ||
+		apierrors.IsServerTimeout(err) || apierrors.IsTimeout(err) || apierrors.IsInternalError(err) ||
+		utilnet.IsConnectionReset(err) || utilnet.IsProbableEOF(err)
+}
+
+// retryTransient calls fn until it succeeds, fails with an error that isn't transient, or
+// -max-retries retries are used up, logging every retry so a slow run explains itself. The
+// operation names the call in the log, e.g. "list deployments".
+func retryTransient(operation string, fn func() error) error {
+	attempt := 0
+	return retry.OnError(retryBackoff(), func(err error) bool {
+		if !isTransient(err) {
+			return false
+		}
+		attempt++
+		if attempt <= *maxRetries {
+			logger.Warn("transient API error, retrying", "operation", operation, "retry", attempt, "maxRetries", *maxRetries, "err", err)
+		}
+		return true
+	}, fn)
+}
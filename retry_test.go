@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestRetryTransient(t *testing.T) {
+	previous := *maxRetries
+	*maxRetries = 1
+	defer func() { *maxRetries = previous }()
+
+	calls := 0
+	err := retryTransient("list deployments", func() error {
+		calls++
+		if calls == 1 {
+			return apierrors.NewTooManyRequests("slow down", 0)
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Errorf("throttled once: err = %v after %d calls, want success after 2", err, calls)
+	}
+
+	calls = 0
+	notFound := apierrors.NewNotFound(schema.GroupResource{Resource: "deployments"}, "web")
+	err = retryTransient("get deployment web", func() error {
+		calls++
+		return notFound
+	})
+	if !errors.Is(err, notFound) || calls != 1 {
+		t.Errorf("not found: err = %v after %d calls, want it returned without retrying", err, calls)
+	}
+
+	calls = 0
+	err = retryTransient("list deployments", func() error {
+		calls++
+		return apierrors.NewServiceUnavailable("overloaded")
+	})
+	if !apierrors.IsServiceUnavailable(err) || calls != 2 {
+		t.Errorf("unavailable: err = %v after %d calls, want it returned after 1 retry", err, calls)
+	}
+}
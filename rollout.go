@@ -0,0 +1,39 @@
+package main
+
+import (
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+// workloadAge renders the time since the workload was created the way kubectl get shows it, e.g. "45d".
+func workloadAge(created metav1.Time) string {
+	if created.IsZero() {
+		return "N/A"
+	}
+	return duration.HumanDuration(time.Since(created.Time))
+}
+
+// lastRollout returns when the workload last rolled out, in RFC 3339, or N/A when unknown: the
+// later of the restartedAt annotation kubectl rollout restart (and the restart action) stamps on
+// the pod template, and, for Deployments, the last update of the Progressing condition, which
+// moves whenever a new ReplicaSet rolls out. Workloads never restarted or updated since creation
+// report their creation time through the condition.
+func lastRollout(template v1.PodTemplateSpec, conditions []appsv1.DeploymentCondition) string {
+	var latest time.Time
+	if restartedAt, err := time.Parse(time.RFC3339, template.Annotations[restartedAtAnnotation]); err == nil {
+		latest = restartedAt
+	}
+	for _, condition := range conditions {
+		if condition.Type == appsv1.DeploymentProgressing && condition.LastUpdateTime.After(latest) {
+			latest = condition.LastUpdateTime.Time
+		}
+	}
+	if latest.IsZero() {
+		return "N/A"
+	}
+	return latest.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// csvComment starts the lines of a CSV file the patch action ignores, e.g. the example row of the
+// template written by the init action.
+const csvComment = '#'
+
+// scaffoldExample is the example row of the CSV template: a Deployment with an HPA, flagged for a
+// resource and HPA update.
+func scaffoldExample() DeploymentInfo {
+	scaleUp, scaleDown := int32(0), int32(300)
+	return DeploymentInfo{
+		Kind:                   kindDeployment,
+		Name:                   "example-app",
+		Namespace:              "default",
+		Replicas:               2,
+		HasHPA:                 true,
+		MinReplicas:            2,
+		MaxReplicas:            5,
+		CPURequest:             "250m",
+		CPULimit:               "500m",
+		MemoryRequest:          "256Mi",
+		MemoryLimit:            "512Mi",
+		MaxUnavailable:         "25%",
+		MaxSurge:               "25%",
+		Strategy:               "RollingUpdate",
+		CPUTargetUtilization:   70,
+		ScaleUpStabilization:   &scaleUp,
+		ScaleDownStabilization: &scaleDown,
+		UpdateResourceAndHPA:   true,
+	}
+}
+
+// scaffoldCSV is the "Create a CSV template" action: it writes the file the patch action reads
+// (-input, deployment-info.csv by default) with the header row and one commented example row, so
+// rows can be filled in by hand without generating from a cluster first. An existing file is
+// never overwritten.
+func scaffoldCSV() error {
+	if *outputFormat != "csv" {
+		return withExitCode(exitUsage, fmt.Errorf("the CSV template can't be written with -format=%s, use -format=csv", *outputFormat))
+	}
+	path := *input
+	if path == "" {
+		path = defaultOutputPath("csv")
+	}
+	if _, err := os.Stat(path); err == nil {
+		return withExitCode(exitUsage, fmt.Errorf("%s already exists, remove it or point -input at a new file", path))
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to check %s: %w", path, err)
+	}
+
+	var header, example bytes.Buffer
+	for _, part := range []struct {
+		buffer *bytes.Buffer
+		record []string
+	}{{&header, csvHeader(nil)}, {&example, csvRecord(0, scaffoldExample(), nil)}} {
+		writer := csv.NewWriter(part.buffer)
+		writer.Comma = csvDelimiter()
+		if err := writer.Write(part.record); err != nil {
+			return fmt.Errorf("failed to encode CSV template: %w", err)
+		}
+		writer.Flush()
+	}
+
+	data := append(header.Bytes(), csvComment, ' ')
+	data = append(data, example.Bytes()...)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write CSV template: %w", err)
+	}
+	logger.Info("CSV template created, add one row per workload (lines starting with # are ignored) and run the patch action", "path", path)
+	return nil
+}
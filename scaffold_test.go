@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestScaffoldCSV(t *testing.T) {
+	inTempDir(t)
+	if err := scaffoldCSV(); err != nil {
+		t.Fatalf("scaffoldCSV: %v", err)
+	}
+
+	rows, err := readPatchRows()
+	if err != nil || len(rows) != 0 {
+		t.Fatalf("readPatchRows() = %+v, %v, want no rows: the example is commented out", rows, err)
+	}
+
+	// Uncommenting the example gives a row the patch action accepts.
+	data, err := os.ReadFile("deployment-info.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("deployment-info.csv", []byte(strings.Replace(string(data), "# ", "", 1)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rows, err = readPatchRows()
+	if err != nil || len(rows) != 1 {
+		t.Fatalf("readPatchRows() = %+v, %v, want the example row", rows, err)
+	}
+	if row := rows[0]; len(row.ParseErrors) > 0 || row.DeploymentName != "example-app" || row.MaxReplicas != 5 || !row.UpdateResourceAndHPA {
+		t.Errorf("example row = %+v, want example-app with max 5 replicas flagged for update", row)
+	}
+
+	if err := scaffoldCSV(); exitCode(err) != exitUsage {
+		t.Errorf("second scaffoldCSV() = %v, want a usage error instead of overwriting", err)
+	}
+}
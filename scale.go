@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// hasHPA reports whether the row was exported with an HPA: the HPA columns of workloads without
+// one are N/A, which leaves Max Replicas at 0.
+func (row patchRow) hasHPA() bool {
+	return row.MaxReplicas > 0
+}
+
+// replicaTarget returns the replica count the row scales its workload to. Only workloads without
+// an HPA are scaled, by rows with UpdateResourceAndHPA or UpdateReplicas: an HPA owns spec.replicas
+// and would undo the change on its next sync (see warnReplicaEdit).
+func (row patchRow) replicaTarget() (int32, bool) {
+	if !hasReplicaCount(row.Kind) || row.hasHPA() || (!row.UpdateResourceAndHPA && !row.UpdateReplicas) {
+		return 0, false
+	}
+	replicas, err := strconv.Atoi(row.Replicas)
+	if err != nil || replicas < 0 {
+		return 0, false
+	}
+	return int32(replicas), true
+}
+
+// validateReplicas refuses a row that would scale its workload with a Replicas cell that isn't a
+// replica count.
+func validateReplicas(row patchRow) error {
+	if !hasReplicaCount(row.Kind) || row.hasHPA() || (!row.UpdateResourceAndHPA && !row.UpdateReplicas) {
+		return nil
+	}
+	if replicas, err := strconv.Atoi(row.Replicas); err != nil || replicas < 0 {
+		return fmt.Errorf("column \"Replicas\": %q is not a replica count", row.Replicas)
+	}
+	return nil
+}
+
+// replicasUpToDate reports whether the live workload already runs the row's replica count. An
+// unset spec.replicas means 1.
+func replicasUpToDate(clientset kubernetes.Interface, row patchRow, replicas int32) bool {
+	live, err := getWorkload(clientset, row)
+	if err != nil {
+		return false
+	}
+	return liveReplicas(live) == replicas
+}
+
+func liveReplicas(live *workload) int32 {
+	if live.Replicas == nil {
+		return 1
+	}
+	return *live.Replicas
+}
+
+// scaleWorkload sets spec.replicas of the row's Deployment or StatefulSet through its scale
+// subresource, which leaves the rest of the spec alone.
+func scaleWorkload(out io.Writer, clientset kubernetes.Interface, row patchRow, replicas int32) error {
+	ctx, cancel := apiContext()
+	defer cancel()
+
+	getScale := clientset.AppsV1().Deployments(row.Namespace).GetScale
+	updateScale := clientset.AppsV1().Deployments(row.Namespace).UpdateScale
+	if row.Kind == kindStatefulSet {
+		getScale = clientset.AppsV1().StatefulSets(row.Namespace).GetScale
+		updateScale = clientset.AppsV1().StatefulSets(row.Namespace).UpdateScale
+	}
+
+	var scale *autoscalingv1.Scale
+	err := retryTransient("get scale of "+row.DeploymentName, func() (err error) {
+		scale, err = getScale(ctx, row.DeploymentName, metav1.GetOptions{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get the scale of %s: %w", row.DeploymentName, err)
+	}
+
+	log := loggerTo(out).With("namespace", row.Namespace, "kind", row.Kind, "name", row.DeploymentName)
+	log.Info("scaling", "from", scale.Spec.Replicas, "to", replicas)
+	scale.Spec.Replicas = replicas
+	err = retryTransient("scale "+row.DeploymentName, func() error {
+		_, err := updateScale(ctx, row.DeploymentName, scale, metav1.UpdateOptions{DryRun: dryRunAll()})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to scale %s: %w", row.DeploymentName, err)
+	}
+	if *dryRun {
+		log.Info("dry run, scale validated by the API server but not persisted")
+		return nil
+	}
+	log.Info("replicas updated")
+	return nil
+}
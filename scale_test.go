@@ -0,0 +1,69 @@
+package main
+
+import (
+	"io"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestReplicaTarget(t *testing.T) {
+	tests := []struct {
+		name string
+		row  patchRow
+		want int32
+		ok   bool
+	}{
+		{"no HPA, resources row", patchRow{Kind: kindDeployment, Replicas: "3", UpdateResourceAndHPA: true}, 3, true},
+		{"no HPA, UpdateReplicas", patchRow{Kind: kindStatefulSet, Replicas: "0", UpdateReplicas: true}, 0, true},
+		{"no HPA, HPA-only row", patchRow{Kind: kindDeployment, Replicas: "3", UpdateHPAOnly: true}, 0, false},
+		{"HPA-managed", patchRow{Kind: kindDeployment, Replicas: "3", MinReplicas: 2, MaxReplicas: 5, UpdateReplicas: true}, 0, false},
+		{"DaemonSet", patchRow{Kind: kindDaemonSet, Replicas: "N/A", UpdateResourceAndHPA: true}, 0, false},
+	}
+	for _, tt := range tests {
+		if got, ok := tt.row.replicaTarget(); got != tt.want || ok != tt.ok {
+			t.Errorf("%s: replicaTarget() = %d, %v; want %d, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	if err := validateReplicas(patchRow{Kind: kindDeployment, Replicas: "three", UpdateReplicas: true}); err == nil {
+		t.Error("validateReplicas() accepted a Replicas cell that isn't a number")
+	}
+}
+
+func TestScaleWorkloadUpdatesTheScaleSubresource(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	var scaled *autoscalingv1.Scale
+	clientset.PrependReactor("get", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "scale" {
+			return false, nil, nil
+		}
+		return true, &autoscalingv1.Scale{ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "jobs"}, Spec: autoscalingv1.ScaleSpec{Replicas: 1}}, nil
+	})
+	clientset.PrependReactor("update", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "scale" {
+			return false, nil, nil
+		}
+		scaled = action.(k8stesting.UpdateAction).GetObject().(*autoscalingv1.Scale)
+		return true, scaled, nil
+	})
+
+	row := patchRow{Kind: kindDeployment, Namespace: "jobs", DeploymentName: "worker", Replicas: "4", UpdateReplicas: true}
+	if err := scaleWorkload(io.Discard, clientset, row, 4); err != nil {
+		t.Fatalf("scaleWorkload() = %v", err)
+	}
+	if scaled == nil || scaled.Spec.Replicas != 4 {
+		t.Fatalf("scale update = %+v, want 4 replicas", scaled)
+	}
+
+	replicas := int32(4)
+	clientset = fake.NewSimpleClientset(&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "jobs"}, Spec: appsv1.DeploymentSpec{Replicas: &replicas}})
+	if !replicasUpToDate(clientset, row, 4) || replicasUpToDate(clientset, row, 5) {
+		t.Error("replicasUpToDate() doesn't compare with the live spec.replicas")
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// SecurityPosture holds the securityContext basics of a deployment's primary (first) container.
+type SecurityPosture struct {
+	RunsAsRoot               bool `json:"runsAsRoot"`
+	Privileged               bool `json:"privileged"`
+	AllowPrivilegeEscalation bool `json:"allowPrivilegeEscalation"`
+}
+
+// securityPosture evaluates the primary container with the usual precedence: container-level
+// runAsNonRoot/runAsUser override the pod-level ones, privileged and allowPrivilegeEscalation only
+// exist on the container.
+func securityPosture(spec v1.PodSpec) SecurityPosture {
+	var posture SecurityPosture
+	if len(spec.Containers) == 0 {
+		return posture
+	}
+	container := spec.Containers[0]
+
+	var runAsNonRoot *bool
+	var runAsUser *int64
+	if spec.SecurityContext != nil {
+		runAsNonRoot = spec.SecurityContext.RunAsNonRoot
+		runAsUser = spec.SecurityContext.RunAsUser
+	}
+	if sc := container.SecurityContext; sc != nil {
+		if sc.RunAsNonRoot != nil {
+			runAsNonRoot = sc.RunAsNonRoot
+		}
+		if sc.RunAsUser != nil {
+			runAsUser = sc.RunAsUser
+		}
+		posture.Privileged = sc.Privileged != nil && *sc.Privileged
+		// Privileged containers can always escalate.
+		posture.AllowPrivilegeEscalation = posture.Privileged ||
+			(sc.AllowPrivilegeEscalation != nil && *sc.AllowPrivilegeEscalation)
+	}
+
+	nonRoot := (runAsNonRoot != nil && *runAsNonRoot) || (runAsUser != nil && *runAsUser != 0)
+	posture.RunsAsRoot = !nonRoot
+	return posture
+}
+
+// securityHeader returns the -include-security columns.
+func securityHeader() []string {
+	if !*includeSecurity {
+		return nil
+	}
+	return []string{"Runs As Root", "Privileged", "Allow Privilege Escalation"}
+}
+
+func securityRecord(deploy DeploymentInfo) []string {
+	if !*includeSecurity {
+		return nil
+	}
+	return []string{
+		strconv.FormatBool(deploy.Security.RunsAsRoot),
+		strconv.FormatBool(deploy.Security.Privileged),
+		strconv.FormatBool(deploy.Security.AllowPrivilegeEscalation),
+	}
+}
+
+// checkSecurityContext flags a primary container that runs as root, is privileged or may escalate privileges.
+func checkSecurityContext(deploy DeploymentInfo) *Finding {
+	var violations []string
+	if deploy.Security.RunsAsRoot {
+		violations = append(violations, "may run as root (runAsNonRoot not set)")
+	}
+	if deploy.Security.Privileged {
+		violations = append(violations, "is privileged")
+	}
+	if deploy.Security.AllowPrivilegeEscalation {
+		violations = append(violations, "allows privilege escalation")
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+
+	return &Finding{
+		Namespace:  deploy.Namespace,
+		Deployment: deploy.Name,
+		Check:      "security-context",
+		Message:    "primary container " + strings.Join(violations, ", "),
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// validateSelector rejects a malformed -selector before anything is listed, so the error names the
+// flag instead of coming back from the API server.
+func validateSelector() error {
+	if *labelSelector == "" {
+		return nil
+	}
+	if _, err := labels.Parse(*labelSelector); err != nil {
+		return fmt.Errorf("invalid -selector %q: %v (expected e.g. app=frontend, tier in (web,api) or !canary)", *labelSelector, err)
+	}
+	return nil
+}
+
+// selectorListOptions returns the list options limiting the generate action to -selector.
+func selectorListOptions() metav1.ListOptions {
+	return metav1.ListOptions{LabelSelector: *labelSelector}
+}
+
+// nameFilter is -name-filter compiled by validateNameFilter; nil matches every workload.
+var nameFilter *regexp.Regexp
+
+// validateNameFilter compiles -name-filter once, rejecting an invalid pattern before anything is listed.
+func validateNameFilter() error {
+	if *nameFilterPattern == "" {
+		return nil
+	}
+	filter, err := regexp.Compile(*nameFilterPattern)
+	if err != nil {
+		return fmt.Errorf("invalid -name-filter %q: %v", *nameFilterPattern, err)
+	}
+	nameFilter = filter
+	return nil
+}
+
+// nameMatches reports whether the workload name matches -name-filter.
+func nameMatches(name string) bool {
+	return nameFilter == nil || nameFilter.MatchString(name)
+}
+
+// excludeSelector is -exclude-selector parsed by validateExcludeSelector; nil excludes nothing.
+var excludeSelector labels.Selector
+
+// validateExcludeSelector parses -exclude-selector once, rejecting a malformed one before anything
+// is listed.
+func validateExcludeSelector() error {
+	if *excludeSelectorFlag == "" {
+		return nil
+	}
+	selector, err := labels.Parse(*excludeSelectorFlag)
+	if err != nil {
+		return fmt.Errorf("invalid -exclude-selector %q: %v (expected e.g. app.kubernetes.io/managed-by=addon-manager)", *excludeSelectorFlag, err)
+	}
+	excludeSelector = selector
+	return nil
+}
+
+// keepWorkload reports whether the generate action builds a row for the workload: its name matches
+// -name-filter, its own labels (not the pod template's) don't match -exclude-selector, and with
+// -skip-owned it has no owner references, i.e. no operator or other controller manages it.
+func keepWorkload(meta metav1.ObjectMeta) bool {
+	if !nameMatches(meta.Name) {
+		return false
+	}
+	if excludeSelector != nil && excludeSelector.Matches(labels.Set(meta.Labels)) {
+		return false
+	}
+	return !*skipOwned || len(meta.OwnerReferences) == 0
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestKeepWorkload(t *testing.T) {
+	savedSelector, savedSkipOwned := *excludeSelectorFlag, *skipOwned
+	defer func() {
+		*excludeSelectorFlag, *skipOwned, excludeSelector = savedSelector, savedSkipOwned, nil
+	}()
+	*excludeSelectorFlag, *skipOwned = "app.kubernetes.io/managed-by in (addon-manager,eks)", true
+	if err := validateExcludeSelector(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		meta metav1.ObjectMeta
+		want bool
+	}{
+		{"user workload", metav1.ObjectMeta{Name: "web", Labels: map[string]string{"app": "web"}}, true},
+		{"excluded by label", metav1.ObjectMeta{Name: "coredns", Labels: map[string]string{"app.kubernetes.io/managed-by": "eks"}}, false},
+		{"owned by a controller", metav1.ObjectMeta{Name: "db", OwnerReferences: []metav1.OwnerReference{{Kind: "PostgresCluster", Name: "db"}}}, false},
+	}
+	for _, tt := range tests {
+		if got := keepWorkload(tt.meta); got != tt.want {
+			t.Errorf("%s: keepWorkload() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	*excludeSelectorFlag = "managed-by in (a"
+	if err := validateExcludeSelector(); err == nil {
+		t.Error("validateExcludeSelector() accepted a malformed selector")
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// matchingServices returns the sorted names of the Services in the namespace whose selector
+// matches the pod labels, joined with commas. Services without a selector (e.g. ExternalName or
+// manually managed endpoints) never match.
+func matchingServices(services []v1.Service, namespace string, podLabels map[string]string) string {
+	var names []string
+	for _, svc := range services {
+		if svc.Namespace != namespace || len(svc.Spec.Selector) == 0 {
+			continue
+		}
+		if labels.SelectorFromSet(svc.Spec.Selector).Matches(labels.Set(podLabels)) {
+			names = append(names, svc.Name)
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
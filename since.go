@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// validateSince rejects a negative -since.
+func validateSince() error {
+	if *since < 0 {
+		return fmt.Errorf("-since must be 0 or more, got %s", *since)
+	}
+	return nil
+}
+
+// oldestPodStart returns when the oldest pod matching the deployment's selector started, and false
+// when no pod matches (e.g. the deployment is scaled to zero). Pods not started yet count from
+// their creation.
+func oldestPodStart(clientset kubernetes.Interface, deploy appsv1.Deployment) (time.Time, bool, error) {
+	selector, err := metav1.LabelSelectorAsSelector(deploy.Spec.Selector)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid selector of deployment %s: %w", deploy.Name, err)
+	}
+	ctx, cancel := apiContext()
+	defer cancel()
+
+	var pods *v1.PodList
+	err = retryTransient("list pods of "+deploy.Name, func() (err error) {
+		pods, err = clientset.CoreV1().Pods(deploy.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+		return err
+	})
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to list the pods of deployment %s: %w", deploy.Name, err)
+	}
+
+	var oldest time.Time
+	for _, pod := range pods.Items {
+		started := pod.CreationTimestamp.Time
+		if pod.Status.StartTime != nil {
+			started = pod.Status.StartTime.Time
+		}
+		if oldest.IsZero() || started.Before(oldest) {
+			oldest = started
+		}
+	}
+	return oldest, !oldest.IsZero(), nil
+}
+
+// filterSince drops the deployments whose oldest pod started less than -since ago, so a fleet-wide
+// restart only recycles long-running pods. Deployments without pods are kept. Without -since every
+// deployment is kept and no pods are listed.
+func filterSince(clientset kubernetes.Interface, deployments []appsv1.Deployment) ([]appsv1.Deployment, error) {
+	if *since == 0 {
+		return deployments, nil
+	}
+	var kept []appsv1.Deployment
+	for _, deploy := range deployments {
+		started, ok, err := oldestPodStart(clientset, deploy)
+		if err != nil {
+			return nil, err
+		}
+		if age := time.Since(started); ok && age < *since {
+			logger.Info("pods started recently, not restarting", "namespace", deploy.Namespace, "name", deploy.Name, "oldestPodAge", age.Round(time.Second), "since", *since)
+			continue
+		}
+		kept = append(kept, deploy)
+	}
+	return kept, nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestFilterSince(t *testing.T) {
+	deployment := func(name string) appsv1.Deployment {
+		return appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "prod"},
+			Spec:       appsv1.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": name}}},
+		}
+	}
+	pod := func(name, app string, age time.Duration) *v1.Pod {
+		started := metav1.NewTime(time.Now().Add(-age))
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "prod", Labels: map[string]string{"app": app}},
+			Status:     v1.PodStatus{StartTime: &started},
+		}
+	}
+	clientset := fake.NewSimpleClientset(
+		pod("api-1", "api", 10*24*time.Hour),
+		pod("api-2", "api", time.Hour),
+		pod("web-1", "web", 2*time.Hour),
+	)
+	deployments := []appsv1.Deployment{deployment("api"), deployment("web"), deployment("idle")}
+
+	old := *since
+	defer func() { *since = old }()
+
+	*since = 0
+	if kept, err := filterSince(clientset, deployments); err != nil || len(kept) != 3 {
+		t.Fatalf("filterSince() without -since = %d deployments, %v; want all 3", len(kept), err)
+	}
+
+	*since = 7 * 24 * time.Hour
+	kept, err := filterSince(clientset, deployments)
+	if err != nil {
+		t.Fatalf("filterSince() = %v", err)
+	}
+	var names []string
+	for _, deploy := range kept {
+		names = append(names, deploy.Name)
+	}
+	// api's oldest pod counts, web's pods are too young, idle has no pods to judge by.
+	if len(names) != 2 || names[0] != "api" || names[1] != "idle" {
+		t.Errorf("filterSince(-since=168h) kept %v, want [api idle]", names)
+	}
+
+	*since = -time.Hour
+	if err := validateSince(); err == nil {
+		t.Error("validateSince() accepted a negative -since")
+	}
+}
+
+func TestRestartCandidates(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "shop"}},
+	)
+	old := *since
+	defer func() { *since = old }()
+	*since = 0
+
+	if candidates, err := restartCandidates(clientset, "shop", "web"); err != nil || len(candidates) != 1 || candidates[0].Name != "web" {
+		t.Errorf("restartCandidates(web) = %v, %v; want only web", candidates, err)
+	}
+	if candidates, err := restartCandidates(clientset, "shop", "all"); err != nil || len(candidates) != 2 {
+		t.Errorf("restartCandidates(all) = %v, %v; want both deployments", candidates, err)
+	}
+	if _, err := restartCandidates(clientset, "shop", "cart"); exitCode(err) != exitUsage {
+		t.Errorf("restartCandidates(cart) = %v, want a usage error for a missing deployment", err)
+	}
+	if _, err := restartCandidates(clientset, "jobs", "all"); exitCode(err) != exitNothingToDo {
+		t.Errorf("restartCandidates(all) in an empty namespace = %v, want exit code %d", err, exitNothingToDo)
+	}
+}
+
+func TestRestartDryRunRowsMatchCandidates(t *testing.T) {
+	replicas := int32(3)
+	clientset := fake.NewSimpleClientset(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &replicas,
+				Template: v1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web"}}},
+			},
+		},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "shop"}},
+		&policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-pdb", Namespace: "shop"},
+			Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+		},
+	)
+	old := *since
+	defer func() { *since = old }()
+	*since = 0
+
+	data, err := restartDryRunRows(clientset, "shop", "web")
+	if err != nil {
+		t.Fatalf("restartDryRunRows(web): %v", err)
+	}
+	if len(data) != 1 || data[0].Name != "web" || data[0].Replicas != 3 || data[0].PDBName != "web-pdb" {
+		t.Errorf("restartDryRunRows(web) = %+v, want only web with 3 replicas guarded by web-pdb", data)
+	}
+	if data, err := restartDryRunRows(clientset, "shop", "all"); err != nil || len(data) != 2 {
+		t.Errorf("restartDryRunRows(all) = %d rows, %v; want both deployments", len(data), err)
+	}
+}
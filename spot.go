@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// defaultSpotNodeKeys are the node labels the major clouds and Karpenter put on spot/preemptible nodes.
+const defaultSpotNodeKeys = "cloud.google.com/gke-spot=true,cloud.google.com/gke-preemptible=true," +
+	"eks.amazonaws.com/capacityType=SPOT,karpenter.sh/capacity-type=spot,kubernetes.azure.com/scalesetpriority=spot"
+
+// nodeIndicator is a node label that identifies a node pool. An empty Value matches any value.
+type nodeIndicator struct {
+	Key   string
+	Value string
+}
+
+// parseNodeIndicators parses a comma-separated list of "key" or "key=value" entries.
+func parseNodeIndicators(list string) []nodeIndicator {
+	var indicators []nodeIndicator
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(entry, "=")
+		indicators = append(indicators, nodeIndicator{Key: key, Value: value})
+	}
+	return indicators
+}
+
+// runsOnlyOnNodes reports whether the pod spec can only be scheduled on nodes carrying one of the
+// indicator labels, either through its nodeSelector or because every required node affinity term
+// demands one. Tolerations alone only permit such nodes, they don't confine the pods to them.
+func runsOnlyOnNodes(spec v1.PodSpec, indicators []nodeIndicator) bool {
+	for _, indicator := range indicators {
+		if value, ok := spec.NodeSelector[indicator.Key]; ok && (indicator.Value == "" || value == indicator.Value) {
+			return true
+		}
+	}
+
+	if spec.Affinity == nil || spec.Affinity.NodeAffinity == nil ||
+		spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return false
+	}
+
+	// Node selector terms are ORed, so every term must require an indicator.
+	terms := spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	if len(terms) == 0 {
+		return false
+	}
+	for _, term := range terms {
+		if !termRequiresIndicator(term, indicators) {
+			return false
+		}
+	}
+	return true
+}
+
+func termRequiresIndicator(term v1.NodeSelectorTerm, indicators []nodeIndicator) bool {
+	for _, expression := range term.MatchExpressions {
+		for _, indicator := range indicators {
+			if expression.Key != indicator.Key {
+				continue
+			}
+			switch expression.Operator {
+			case v1.NodeSelectorOpExists:
+				if indicator.Value == "" {
+					return true
+				}
+			case v1.NodeSelectorOpIn:
+				if indicator.Value == "" {
+					return true
+				}
+				// Every allowed value must be an indicator value, otherwise non-spot nodes qualify too.
+				allSpot := len(expression.Values) > 0
+				for _, value := range expression.Values {
+					if value != indicator.Value {
+						allSpot = false
+					}
+				}
+				if allSpot {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// matchingPDB returns the first PodDisruptionBudget in the namespace whose selector matches the
+// pod labels, or nil when the pods are unguarded.
+func matchingPDB(pdbs []policyv1.PodDisruptionBudget, namespace string, podLabels map[string]string) *policyv1.PodDisruptionBudget {
+	for i, pdb := range pdbs {
+		if pdb.Namespace != namespace || pdb.Spec.Selector == nil {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil || selector.Empty() {
+			continue
+		}
+		if selector.Matches(labels.Set(podLabels)) {
+			return &pdbs[i]
+		}
+	}
+	return nil
+}
+
+// checkSpotAvailability flags deployments confined to spot capacity that a single node preemption
+// can take down: those running one replica or without a PodDisruptionBudget. DaemonSets have a pod
+// per node rather than a replica count, so only the missing PDB applies to them.
+func checkSpotAvailability(deploy DeploymentInfo) *Finding {
+	if !deploy.SpotOnly {
+		return nil
+	}
+
+	replicas := deploy.Replicas
+	if deploy.HasHPA {
+		replicas = deploy.MinReplicas
+	}
+
+	var risks []string
+	if hasReplicaCount(deploy.Kind) && replicas < 2 {
+		risks = append(risks, fmt.Sprintf("only %d replica(s)", replicas))
+	}
+	if deploy.PDBName == "" {
+		risks = append(risks, "no PodDisruptionBudget")
+	}
+	if len(risks) == 0 {
+		return nil
+	}
+
+	return &Finding{
+		Namespace:  deploy.Namespace,
+		Deployment: deploy.Name,
+		Check:      "spot-only",
+		Message:    "runs solely on spot/preemptible nodes with " + strings.Join(risks, " and "),
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// patchState records which rows a patch run has applied, so an interrupted run can be resumed
+// with -resume. Rows are keyed on namespace/name and store a checksum of the intended values;
+// a row whose values changed since is applied again.
+type patchState struct {
+	mu      sync.Mutex        // rows are patched concurrently
+	Applied map[string]string `json:"applied"`
+}
+
+// loadPatchState reads the state file, returning an empty state when it doesn't exist yet.
+func loadPatchState(path string) (*patchState, error) {
+	state := &patchState{Applied: make(map[string]string)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", path, err)
+	}
+	if state.Applied == nil {
+		state.Applied = make(map[string]string)
+	}
+	return state, nil
+}
+
+// save writes the state atomically so a crash mid-write never leaves a corrupt file behind.
+func (s *patchState) save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// markApplied records the row as applied and persists the state immediately.
+func (s *patchState) markApplied(path string, row patchRow) {
+	if *dryRun {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Applied[rowKey(row)] = rowChecksum(row)
+	if err := s.save(path); err != nil {
+		logger.Warn("failed to save the patch state", "err", err)
+	}
+}
+
+func rowKey(row patchRow) string {
+	key := row.Namespace + "/" + row.DeploymentName
+	if row.Kind != "" && row.Kind != kindDeployment {
+		key = row.Kind + ":" + key
+	}
+	if row.Context != "" {
+		key = row.Context + "@" + key
+	}
+	return key
+}
+
+// rowChecksum hashes the intended values of the row (after any -max-replicas-* adjustment) and
+// the -fields it is applied with, so a resumed run with other -fields applies the row again.
+func rowChecksum(row patchRow) string {
+	data, _ := json.Marshal(row)
+	if len(patchFields) > 0 {
+		data = append(data, "fields="+patchFields.String()...)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
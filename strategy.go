@@ -0,0 +1,49 @@
+package main
+
+import (
+	"io"
+
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+// recreateCell fills the MaxUnavailable and MaxSurge columns of Deployments using the Recreate
+// strategy, which has no rolling update parameters, so the empty cells don't look like missing data.
+const recreateCell = "N/A (Recreate)"
+
+// rollingUpdateCells renders the MaxUnavailable and MaxSurge columns.
+func rollingUpdateCells(deploy DeploymentInfo) (string, string) {
+	if deploy.Kind == kindDeployment && deploy.Strategy == string(appsv1.RecreateDeploymentStrategyType) {
+		return recreateCell, recreateCell
+	}
+	return deploy.MaxUnavailable, deploy.MaxSurge
+}
+
+// rollingUpdateCell reads a MaxUnavailable or MaxSurge cell, "" for a Recreate Deployment.
+func rollingUpdateCell(cell string) string {
+	if cell == recreateCell {
+		return ""
+	}
+	return cell
+}
+
+// isRecreate reports whether the live Deployment strategy replaces all pods at once.
+func isRecreate(strategy *appsv1.DeploymentStrategy) bool {
+	return strategy != nil && strategy.Type == appsv1.RecreateDeploymentStrategyType
+}
+
+// patchesRollingUpdate reports whether maxUnavailable and maxSurge of the row are applied. A
+// Recreate Deployment has no rolling update to configure: values set on its row are warned about
+// and left out instead of sending a patch that silently switches the strategy.
+func patchesRollingUpdate(out io.Writer, row patchRow) bool {
+	if !hasRollingUpdateParams(row.Kind) {
+		return false
+	}
+	if row.Strategy != string(appsv1.RecreateDeploymentStrategyType) {
+		return true
+	}
+	if row.MaxUnavailable != "" || row.MaxSurge != "" {
+		loggerTo(out).Warn("deployment uses the Recreate strategy, not setting MaxUnavailable/MaxSurge",
+			"namespace", row.Namespace, "name", row.DeploymentName, "maxUnavailable", row.MaxUnavailable, "maxSurge", row.MaxSurge)
+	}
+	return false
+}
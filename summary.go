@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// runSummary collects the outcome of the selected action for the -summary-only line.
+type runSummary struct {
+	Action     string // past tense verb, e.g. "patched"
+	Succeeded  int
+	Skipped    int
+	Failed     int
+	namespaces []string
+}
+
+// summary is filled in by the actions as they run.
+var summary runSummary
+
+// addNamespace records a namespace the action touched.
+func (s *runSummary) addNamespace(namespace string) {
+	for _, seen := range s.namespaces {
+		if seen == namespace {
+			return
+		}
+	}
+	s.namespaces = append(s.namespaces, namespace)
+}
+
+// line renders the single ChatOps-friendly line, e.g.
+// "patched 7 deployments, 1 failed in namespace prod on cluster eks-1".
+func (s runSummary) line(err error, cluster string) string {
+	if s.Action == "" {
+		if err != nil {
+			return "failed: " + err.Error()
+		}
+		return "nothing was done"
+	}
+
+	parts := []string{fmt.Sprintf("%s %d %s", s.Action, s.Succeeded, plural(s.Succeeded, "deployment"))}
+	if s.Skipped > 0 {
+		parts = append(parts, fmt.Sprintf("%d skipped", s.Skipped))
+	}
+	if s.Failed > 0 {
+		parts = append(parts, fmt.Sprintf("%d failed", s.Failed))
+	}
+	line := strings.Join(parts, ", ")
+
+	switch len(s.namespaces) {
+	case 0:
+	case 1:
+		line += " in namespace " + s.namespaces[0]
+	default:
+		line += " in namespaces " + strings.Join(s.namespaces, ",")
+	}
+	if cluster != "" {
+		line += " on cluster " + cluster
+	}
+	if err != nil && s.Failed == 0 {
+		line += " (" + err.Error() + ")"
+	}
+	return line
+}
+
+func plural(count int, noun string) string {
+	if count == 1 {
+		return noun
+	}
+	return noun + "s"
+}
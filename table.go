@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// deploymentTableHeader holds the columns printed by -print-table.
+var deploymentTableHeader = []string{"Namespace", "Name", "Kind", "Replicas", "CPU Request", "CPU Limit", "Memory Request", "Memory Limit", "HPA", "CPU Target"}
+
+// printDeploymentTable prints the collected rows for -print-table, followed by a TOTAL row with
+// the CPU and memory requests summed over all rows (per pod, like the columns).
+func printDeploymentTable(data []DeploymentInfo) {
+	var cpuRequests, memoryRequests int64
+	printTable(deploymentTableHeader, func(row func([]string)) {
+		for _, deploy := range data {
+			replicas, hpa, target := "N/A", "-", "-"
+			if hasReplicaCount(deploy.Kind) {
+				replicas = strconv.Itoa(int(deploy.Replicas))
+			}
+			if deploy.HasHPA {
+				hpa = fmt.Sprintf("%d-%d", deploy.MinReplicas, deploy.MaxReplicas)
+				target = fmt.Sprintf("%d%%", deploy.CPUTargetUtilization)
+			}
+			row([]string{deploy.Namespace, deploy.Name, deploy.Kind, replicas, deploy.CPURequest, deploy.CPULimit, deploy.MemoryRequest, deploy.MemoryLimit, hpa, target})
+			cpuRequests += milliCPU(deploy.CPURequest)
+			memoryRequests += mebibytes(deploy.MemoryRequest)
+		}
+		row([]string{"TOTAL", fmt.Sprintf("%d %s", len(data), plural(len(data), "workload")), "", "", fmt.Sprintf("%dm", cpuRequests), "", fmt.Sprintf("%dMi", memoryRequests), "", "", ""})
+	})
+}
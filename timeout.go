@@ -0,0 +1,20 @@
+package main
+
+import (
+	"context"
+	"errors"
+)
+
+// apiContext returns the context of one Kubernetes API operation, bounded by -timeout so a hung
+// API server can't block the tool forever.
+func apiContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), *timeout)
+}
+
+// explainTimeout points out that an operation was cut off by -timeout. The wrapped error already
+// names the operation, e.g. "failed to list deployments: context deadline exceeded".
+func explainTimeout(err error) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		logger.Error("the API server did not answer within -timeout", "timeout", *timeout, "err", err)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/kubernetes"
+)
+
+// deploymentUpToDate reports whether the live workload already has the resources and rolling
+// update parameters of the row. Any lookup or parse failure counts as "not up to date" so the
+// regular patch path runs and reports the real error.
+func deploymentUpToDate(clientset kubernetes.Interface, row patchRow) bool {
+	live, err := getWorkload(clientset, row)
+	if err != nil {
+		return false
+	}
+	return workloadMatchesRow(live, row)
+}
+
+func workloadMatchesRow(live *workload, row patchRow) bool {
+	for _, container := range live.Template.Spec.Containers {
+		// Without -wide columns the row values apply to the only container (see aggregateRowContainer).
+		want := row.resources()
+		if len(row.Containers) > 0 {
+			found := false
+			for _, candidate := range row.Containers {
+				if candidate.Name == container.Name {
+					want, found = candidate, true
+				}
+			}
+			if !found {
+				continue // Not edited in the CSV, left untouched by the patch.
+			}
+		}
+
+		if !limitEquals(container.Resources.Requests, v1.ResourceCPU, want.CPURequest) ||
+			!limitEquals(container.Resources.Requests, v1.ResourceMemory, want.MemoryRequest) ||
+			!limitEquals(container.Resources.Limits, v1.ResourceCPU, want.CPULimit) ||
+			!limitEquals(container.Resources.Limits, v1.ResourceMemory, want.MemoryLimit) ||
+			!limitEquals(container.Resources.Requests, v1.ResourceEphemeralStorage, want.EphemeralStorageRequest) ||
+			!limitEquals(container.Resources.Limits, v1.ResourceEphemeralStorage, want.EphemeralStorageLimit) {
+			return false
+		}
+	}
+
+	if live.Strategy == nil || isRecreate(live.Strategy) {
+		return true // StatefulSets and Recreate Deployments have no rolling update parameters to compare.
+	}
+	rollingUpdate := live.Strategy.RollingUpdate
+	if live.Strategy.Type != "RollingUpdate" || rollingUpdate == nil ||
+		rollingUpdate.MaxUnavailable == nil || rollingUpdate.MaxSurge == nil {
+		return false
+	}
+	return rollingUpdate.MaxUnavailable.String() == row.MaxUnavailable && rollingUpdate.MaxSurge.String() == row.MaxSurge
+}
+
+// quantityEquals compares a resource from the list with the quantity string from the CSV. A
+// resource missing from the list counts as zero, which is how it is exported.
+func quantityEquals(list v1.ResourceList, name v1.ResourceName, value string) bool {
+	want, err := resource.ParseQuantity(value)
+	if err != nil {
+		return false
+	}
+	have, ok := list[name]
+	if !ok {
+		return want.IsZero()
+	}
+	return have.Cmp(want) == 0
+}
+
+// limitEquals is quantityEquals for limits and requests, where an empty or zero cell means "not
+// set": the patch doesn't send it, so it matches whatever is live.
+func limitEquals(list v1.ResourceList, name v1.ResourceName, value string) bool {
+	if !hasLimit(value) {
+		return true
+	}
+	return quantityEquals(list, name, value)
+}
+
+// hasLimit reports whether a limit (or request) cell holds a value to apply.
+func hasLimit(value string) bool {
+	quantity, err := resource.ParseQuantity(value)
+	return value != "" && (err != nil || !quantity.IsZero())
+}
+
+// hpaUpToDate reports whether the live HPA already has the replica bounds, CPU target and
+// stabilization windows of the row.
+func hpaUpToDate(clientset kubernetes.Interface, row patchRow) bool {
+	ctx, cancel := apiContext()
+	defer cancel()
+
+	hpa, err := getHPA(ctx, clientset, row.Namespace, row.DeploymentName)
+	if err != nil {
+		return false
+	}
+	if usesHPAV1(clientset) {
+		return hpaTargetsMatchRow(hpa, row) // autoscaling/v1 has no behavior to compare.
+	}
+	return hpaMatchesRow(hpa, row)
+}
+
+func hpaMatchesRow(hpa *autoscalingv2.HorizontalPodAutoscaler, row patchRow) bool {
+	return hpaTargetsMatchRow(hpa, row) && hpaBehaviorMatchesRow(hpa, row)
+}
+
+// hpaTargetsMatchRow compares the replica bounds and utilization targets.
+func hpaTargetsMatchRow(hpa *autoscalingv2.HorizontalPodAutoscaler, row patchRow) bool {
+	if hpa.Spec.MinReplicas == nil || int(*hpa.Spec.MinReplicas) != row.MinReplicas ||
+		int(hpa.Spec.MaxReplicas) != row.MaxReplicas {
+		return false
+	}
+
+	// Only the CPU and memory utilization targets are managed; other metrics are kept by the patch.
+	// A CPU target of 0 (an HPA that doesn't scale on CPU) leaves the metrics unchanged.
+	if row.CPUTargetUtilization > 0 && !utilizationTargetEquals(hpa.Spec.Metrics, v1.ResourceCPU, row.CPUTargetUtilization) {
+		return false
+	}
+	if row.MemoryTargetUtilization != nil && !utilizationTargetEquals(hpa.Spec.Metrics, v1.ResourceMemory, *row.MemoryTargetUtilization) {
+		return false
+	}
+	return true
+}
+
+// hpaBehaviorMatchesRow compares the stabilization windows and scaling policies. Windows left
+// N/A and empty policy cells leave the live behavior untouched and therefore always match.
+func hpaBehaviorMatchesRow(hpa *autoscalingv2.HorizontalPodAutoscaler, row patchRow) bool {
+	var scaleUp, scaleDown *autoscalingv2.HPAScalingRules
+	if behavior := hpa.Spec.Behavior; behavior != nil {
+		scaleUp, scaleDown = behavior.ScaleUp, behavior.ScaleDown
+	}
+	return scalingRulesMatch(scaleUp, row.ScaleUpStabilization, row.ScaleUpPolicies) &&
+		scalingRulesMatch(scaleDown, row.ScaleDownStabilization, row.ScaleDownPolicies)
+}
+
+// scalingRulesMatch compares one scaling direction with the window and policies cell of a row.
+func scalingRulesMatch(rules *autoscalingv2.HPAScalingRules, window *int, cell string) bool {
+	if window == nil && cell == "" {
+		return true
+	}
+	if rules == nil {
+		return false
+	}
+	return (window == nil || int32Equals(rules.StabilizationWindowSeconds, *window)) && policiesEqual(rules, cell)
+}
+
+// policiesEqual reports whether the live scaling rules already have the policies of the cell.
+// An empty cell leaves the policies untouched and therefore always matches.
+func policiesEqual(rules *autoscalingv2.HPAScalingRules, cell string) bool {
+	if cell == "" {
+		return true
+	}
+	want, err := decodeScalingPolicies(cell)
+	if err != nil {
+		return false
+	}
+	return encodeScalingPolicies(rules) == encodeScalingPolicies(&autoscalingv2.HPAScalingRules{SelectPolicy: want.SelectPolicy, Policies: want.Policies})
+}
+
+func utilizationTargetEquals(metrics []autoscalingv2.MetricSpec, name v1.ResourceName, want int) bool {
+	for _, metric := range metrics {
+		if metric.Type == autoscalingv2.ResourceMetricSourceType && metric.Resource != nil && metric.Resource.Name == name {
+			return int32Equals(metric.Resource.Target.AverageUtilization, want)
+		}
+	}
+	return false
+}
+
+func int32Equals(value *int32, want int) bool {
+	return value != nil && int(*value) == want
+}
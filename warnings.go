@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+)
+
+// workloadWarnings renders the Warnings column: the common misconfigurations of the row, i.e.
+// containers without a CPU request or memory limit and replicated workloads without an HPA.
+// Containers are named when the workload has more than one.
+func workloadWarnings(deploy DeploymentInfo) string {
+	var warnings []string
+	missing := func(problem string, check func(ContainerResources) bool) {
+		var names []string
+		for _, container := range deploy.Containers {
+			if check(container) {
+				names = append(names, container.Name)
+			}
+		}
+		switch {
+		case len(names) == 0:
+		case len(deploy.Containers) == 1:
+			warnings = append(warnings, problem)
+		default:
+			warnings = append(warnings, problem+" ("+strings.Join(names, ", ")+")")
+		}
+	}
+	missing("no CPU request", func(c ContainerResources) bool { return milliCPU(c.CPURequest) == 0 })
+	missing("no memory limit", func(c ContainerResources) bool { return mebibytes(c.MemoryLimit) == 0 })
+	if hasReplicaCount(deploy.Kind) && !deploy.HasHPA {
+		warnings = append(warnings, "no HPA")
+	}
+	return strings.Join(warnings, "; ")
+}
+
+// onlyWarnings keeps the rows with at least one warning, for -only-warnings.
+func onlyWarnings(data []DeploymentInfo) []DeploymentInfo {
+	var flagged []DeploymentInfo
+	for _, deploy := range data {
+		if deploy.Warnings != "" {
+			flagged = append(flagged, deploy)
+		}
+	}
+	return flagged
+}
+
+// withoutHPA keeps the replicated workloads no HPA targets, for -no-hpa. DaemonSets can't be
+// autoscaled by an HPA, so they are left out.
+func withoutHPA(data []DeploymentInfo) []DeploymentInfo {
+	var unscaled []DeploymentInfo
+	for _, deploy := range data {
+		if hasReplicaCount(deploy.Kind) && !deploy.HasHPA {
+			unscaled = append(unscaled, deploy)
+		}
+	}
+	return unscaled
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// clearScreen moves the cursor home and clears the terminal, so -watch redraws the table in place.
+const clearScreen = "\033[H\033[2J"
+
+// validateWatch checks -watch-interval.
+func validateWatch() error {
+	if *watchInterval <= 0 {
+		return fmt.Errorf("-watch-interval must be positive, got %s", *watchInterval)
+	}
+	return nil
+}
+
+// watchDeploymentInfo is the -watch mode of the generate action: it lists the workloads every
+// -watch-interval and redraws them as a table, like `watch kubectl get`, until interrupted.
+// Nothing is written to files. A failed poll is shown instead of the table and retried on the
+// next tick, so a brief API server hiccup doesn't end the dashboard.
+func watchDeploymentInfo() error {
+	defer handleInterrupts()()
+	ticker := time.NewTicker(*watchInterval)
+	defer ticker.Stop()
+
+	for {
+		data, _, err := collectDeploymentInfo()
+		if err == nil {
+			data = filterDeploymentInfo(data)
+		}
+		if stdoutIsTerminal() {
+			fmt.Print(clearScreen)
+		} else {
+			fmt.Println()
+		}
+		fmt.Printf("Every %s: %s\t%s\n\n", *watchInterval, watchTarget(), time.Now().Format(time.RFC1123))
+		switch {
+		case err != nil:
+			fmt.Printf("error: %v\n", err)
+		case len(data) == 0:
+			fmt.Println("No workloads found.")
+		default:
+			printDeploymentTable(data)
+		}
+
+		select {
+		case <-interrupted.Done():
+			logger.Info("watch stopped")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// watchTarget describes what -watch lists in its header line.
+func watchTarget() string {
+	namespace, err := getActiveNamespace()
+	if err != nil {
+		namespace = "unknown"
+	}
+	target := "namespace " + namespace
+	if *allNamespaces {
+		target = "all namespaces"
+	}
+	if len(contextNames) > 0 {
+		target += " of " + strings.Join(contextNames, ", ")
+	}
+	return target
+}
+
+// filterDeploymentInfo applies -only-warnings and -no-hpa to the rows shown by -watch.
+func filterDeploymentInfo(data []DeploymentInfo) []DeploymentInfo {
+	if *onlyWarningsFlag {
+		data = onlyWarnings(data)
+	}
+	if *noHPAFlag {
+		data = withoutHPA(data)
+	}
+	return data
+}
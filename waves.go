@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+// restartWave is a group of deployments restarted together.
+type restartWave struct {
+	Order       int
+	Deployments []string
+}
+
+// lastWave holds the deployments without a (valid) order annotation; they restart after all others.
+const lastWave = int(^uint(0) >> 1)
+
+// groupRestartWaves groups deployments by the integer value of the order annotation, lowest first.
+func groupRestartWaves(deployments []appsv1.Deployment, annotation string) []restartWave {
+	byOrder := make(map[int][]string)
+	for _, deploy := range deployments {
+		order := lastWave
+		if value, ok := deploy.Annotations[annotation]; ok {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				logger.Warn("invalid restart order annotation, restarting in the last wave", "name", deploy.Name, "annotation", annotation, "value", value)
+			} else {
+				order = parsed
+			}
+		}
+		byOrder[order] = append(byOrder[order], deploy.Name)
+	}
+
+	waves := make([]restartWave, 0, len(byOrder))
+	for order, names := range byOrder {
+		sort.Strings(names)
+		waves = append(waves, restartWave{Order: order, Deployments: names})
+	}
+	sort.Slice(waves, func(i, j int) bool { return waves[i].Order < waves[j].Order })
+	return waves
+}
+
+// restartInWaves restarts the deployments of target (see restartCandidates) wave by wave, waiting
+// for every rollout of a wave to complete before starting the next one. A failing wave stops the
+// restart so dependents are never restarted on top of a broken dependency.
+func restartInWaves(annotation, target string) error {
+	clientset, namespace, err := getKubeClient()
+	if err != nil {
+		return err
+	}
+	candidates, err := restartCandidates(clientset, namespace, target)
+	if err != nil {
+		return err
+	}
+	summary.Action = "restarted"
+	summary.addNamespace(namespace)
+
+	for _, wave := range groupRestartWaves(candidates, annotation) {
+		label := strconv.Itoa(wave.Order)
+		if wave.Order == lastWave {
+			label = "unordered"
+		}
+		logger.Info("restarting wave", "wave", label, "deployments", wave.Deployments)
+		if err := rollWave(clientset, namespace, wave.Deployments); err != nil {
+			return fmt.Errorf("wave %s did not complete, later waves were not restarted: %w", label, err)
+		}
+	}
+	return nil
+}
+
+// rollWave triggers the rollouts of one wave and waits for them. A deployment that can't be
+// restarted stops the rest of the wave from being triggered, but the rollouts already triggered
+// are still waited for and reported, so the restart never ends while pods roll unobserved.
+func rollWave(clientset kubernetes.Interface, namespace string, deployments []string) error {
+	var triggered []string
+	var errs []error
+	for i, name := range deployments {
+		if err := triggerRollout(clientset, namespace, name); err != nil {
+			summary.Failed++
+			errs = append(errs, err)
+			if rest := deployments[i+1:]; len(rest) > 0 {
+				logger.Warn("not restarted because an earlier deployment of the wave failed", "deployments", rest)
+			}
+			break
+		}
+		triggered = append(triggered, name)
+	}
+	for _, name := range triggered {
+		if err := waitForRollout(clientset, namespace, name, *waveTimeout); err != nil {
+			summary.Failed++
+			logger.Error("rollout did not complete", "name", name, "err", err)
+			errs = append(errs, err)
+			continue
+		}
+		summary.Succeeded++
+		logger.Info("rollout completed", "name", name)
+	}
+	return errors.Join(errs...)
+}
+
+// waitForRollout blocks until the deployment's rollout has completed (the same condition as
+// kubectl rollout status) or the timeout expires.
+func waitForRollout(clientset kubernetes.Interface, namespace, deploymentName string, timeout time.Duration) error {
+	err := wait.PollUntilContextTimeout(context.Background(), 2*time.Second, timeout, true, func(ctx context.Context) (bool, error) {
+		deploy, err := clientset.AppsV1().Deployments(namespace).Get(ctx, deploymentName, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return rolloutComplete(deploy), nil
+	})
+	if err != nil {
+		return fmt.Errorf("rollout of deployment %s not completed within %s: %w", deploymentName, timeout, err)
+	}
+	return nil
+}
+
+// rolloutComplete reports whether all replicas run the latest pod template and are available.
+func rolloutComplete(deploy *appsv1.Deployment) bool {
+	if deploy.Status.ObservedGeneration < deploy.Generation {
+		return false
+	}
+	replicas := int32(1)
+	if deploy.Spec.Replicas != nil {
+		replicas = *deploy.Spec.Replicas
+	}
+	return deploy.Status.UpdatedReplicas == replicas &&
+		deploy.Status.Replicas == deploy.Status.UpdatedReplicas &&
+		deploy.Status.AvailableReplicas == deploy.Status.UpdatedReplicas
+}
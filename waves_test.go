@@ -0,0 +1,114 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGroupRestartWaves(t *testing.T) {
+	const annotation = "example.com/restart-order"
+	deployment := func(name, order string) appsv1.Deployment {
+		deploy := appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		if order != "" {
+			deploy.Annotations = map[string]string{annotation: order}
+		}
+		return deploy
+	}
+
+	tests := []struct {
+		name        string
+		deployments []appsv1.Deployment
+		want        []restartWave
+	}{
+		{
+			name:        "lowest order first",
+			deployments: []appsv1.Deployment{deployment("web", "2"), deployment("db", "0"), deployment("api", "1")},
+			want:        []restartWave{{Order: 0, Deployments: []string{"db"}}, {Order: 1, Deployments: []string{"api"}}, {Order: 2, Deployments: []string{"web"}}},
+		},
+		{
+			name:        "negative orders restart before zero",
+			deployments: []appsv1.Deployment{deployment("api", "0"), deployment("cache", "-1")},
+			want:        []restartWave{{Order: -1, Deployments: []string{"cache"}}, {Order: 0, Deployments: []string{"api"}}},
+		},
+		{
+			name:        "ties share a wave sorted by name",
+			deployments: []appsv1.Deployment{deployment("worker", "1"), deployment("api", "1"), deployment("db", "0")},
+			want:        []restartWave{{Order: 0, Deployments: []string{"db"}}, {Order: 1, Deployments: []string{"api", "worker"}}},
+		},
+		{
+			name:        "missing annotation restarts last",
+			deployments: []appsv1.Deployment{deployment("cron", ""), deployment("db", "5")},
+			want:        []restartWave{{Order: 5, Deployments: []string{"db"}}, {Order: lastWave, Deployments: []string{"cron"}}},
+		},
+		{
+			name:        "invalid annotation joins the missing ones in the last wave",
+			deployments: []appsv1.Deployment{deployment("web", "first"), deployment("cron", ""), deployment("db", "0")},
+			want:        []restartWave{{Order: 0, Deployments: []string{"db"}}, {Order: lastWave, Deployments: []string{"cron", "web"}}},
+		},
+		{
+			name:        "no deployments",
+			deployments: nil,
+			want:        []restartWave{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := groupRestartWaves(tt.deployments, annotation); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("groupRestartWaves() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRolloutComplete(t *testing.T) {
+	three := int32(3)
+	tests := []struct {
+		name     string
+		replicas *int32
+		gen      int64
+		status   appsv1.DeploymentStatus
+		want     bool
+	}{
+		{"complete", &three, 2, appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 3, AvailableReplicas: 3}, true},
+		{"generation not observed yet", &three, 3, appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 3, AvailableReplicas: 3}, false},
+		{"pods still on the old template", &three, 2, appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 2, AvailableReplicas: 3}, false},
+		{"old pods not terminated", &three, 2, appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 4, UpdatedReplicas: 3, AvailableReplicas: 3}, false},
+		{"updated pods not available", &three, 2, appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 3, UpdatedReplicas: 3, AvailableReplicas: 2}, false},
+		{"unset replicas default to one", nil, 1, appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deploy := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Generation: tt.gen},
+				Spec:       appsv1.DeploymentSpec{Replicas: tt.replicas},
+				Status:     tt.status,
+			}
+			if got := rolloutComplete(deploy); got != tt.want {
+				t.Errorf("rolloutComplete() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRollWaveWaitsForTriggeredRollouts(t *testing.T) {
+	saved := summary
+	defer func() { summary = saved }()
+	summary = runSummary{}
+
+	// "web" doesn't exist, so triggering it fails after "api" was already restarted.
+	clientset := fake.NewSimpleClientset(&appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "shop"},
+		Status:     appsv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1},
+	})
+	err := rollWave(clientset, "shop", []string{"api", "web", "worker"})
+	if err == nil {
+		t.Fatal("rollWave() = nil, want the error restarting web")
+	}
+	if summary.Succeeded != 1 || summary.Failed != 1 {
+		t.Errorf("summary = %d succeeded, %d failed; want the api rollout waited for and web failed", summary.Succeeded, summary.Failed)
+	}
+}
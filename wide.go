@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+// wideFields are the per-container columns emitted for every container in -wide mode, in order.
+// Each header cell is "<container> <field>".
+var wideFields = []string{"CPU Request", "CPU Limit", "Memory Request", "Memory Limit", "Ephemeral Storage Request", "Ephemeral Storage Limit"}
+
+// wideContainerNames lists every distinct container name across the deployments, in order of first
+// appearance, when -wide is set. It returns nil otherwise so no extra columns are emitted.
+func wideContainerNames(data []DeploymentInfo) []string {
+	if !*wide {
+		return nil
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, deploy := range data {
+		for _, container := range deploy.Containers {
+			if !seen[container.Name] {
+				seen[container.Name] = true
+				names = append(names, container.Name)
+			}
+		}
+	}
+	return names
+}
+
+// wideHeader returns one group of resource column names per container.
+func wideHeader(containers []string) []string {
+	var header []string
+	for _, name := range containers {
+		for _, field := range wideFields {
+			header = append(header, name+" "+field)
+		}
+	}
+	return header
+}
+
+// wideRecord returns the per-container resource cells of the deployment, leaving the group blank
+// for containers the deployment doesn't have.
+func wideRecord(deploy DeploymentInfo, containers []string) []string {
+	var record []string
+	for _, name := range containers {
+		cells := make([]string, len(wideFields))
+		for _, container := range deploy.Containers {
+			if container.Name == name {
+				cells = []string{container.CPURequest, container.CPULimit, container.MemoryRequest, container.MemoryLimit, container.EphemeralStorageRequest, container.EphemeralStorageLimit}
+				break
+			}
+		}
+		record = append(record, cells...)
+	}
+	return record
+}
+
+// wideColumns maps one container's column group to its positions in the CSV header.
+type wideColumns struct {
+	Container string
+	Index     map[string]int // wideFields entry -> column index
+}
+
+// parseWideColumns finds the per-container column groups written by -wide in a CSV header.
+func parseWideColumns(header []string) []wideColumns {
+	var groups []wideColumns
+	byContainer := make(map[string]int)
+	for i, column := range header {
+		for _, field := range wideFields {
+			name, ok := strings.CutSuffix(column, " "+field)
+			if !ok || name == "" || strings.Contains(name, " ") {
+				continue
+			}
+			position, exists := byContainer[name]
+			if !exists {
+				position = len(groups)
+				byContainer[name] = position
+				groups = append(groups, wideColumns{Container: name, Index: make(map[string]int)})
+			}
+			groups[position].Index[field] = i
+		}
+	}
+	return groups
+}
+
+// wideRowContainers extracts the per-container values of a row. Containers whose group is blank
+// (the deployment doesn't have them) are omitted.
+func wideRowContainers(record []string, groups []wideColumns) []ContainerResources {
+	var containers []ContainerResources
+	for _, group := range groups {
+		cell := func(field string) string {
+			index, ok := group.Index[field]
+			if !ok || index >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[index])
+		}
+
+		container := ContainerResources{
+			Name:                    group.Container,
+			CPURequest:              cell("CPU Request"),
+			CPULimit:                cell("CPU Limit"),
+			MemoryRequest:           cell("Memory Request"),
+			MemoryLimit:             cell("Memory Limit"),
+			EphemeralStorageRequest: cell("Ephemeral Storage Request"),
+			EphemeralStorageLimit:   cell("Ephemeral Storage Limit"),
+		}
+		if container.CPURequest == "" && container.CPULimit == "" && container.MemoryRequest == "" && container.MemoryLimit == "" {
+			continue
+		}
+		containers = append(containers, container)
+	}
+	return containers
+}
+
+// setWideDeploymentResources applies the rolling update parameters once for the whole deployment,
+// then the per-container values of a -wide row.
+func setWideDeploymentResources(out io.Writer, row patchRow) error {
+	if patchesRollingUpdate(out, row) {
+		if err := patchRollingUpdate(out, row.Namespace, row.DeploymentName, row.MaxUnavailable, row.MaxSurge, precondition(row.ResourceVersion)); err != nil {
+			return err
+		}
+	}
+	for _, container := range row.Containers {
+		if err := setContainerResources(out, row.Kind, row.Namespace, row.DeploymentName, container.Name, container); err != nil {
+			return fmt.Errorf("container %s: %w", container.Name, err)
+		}
+	}
+	return nil
+}
+
+// aggregateRowContainer returns the container a row without -wide columns is applied to. Such a row
+// holds the resources summed over all containers, which is only correct for a single container;
+// applying the sums to every container of a multi-container deployment would multiply them.
+func aggregateRowContainer(clientset kubernetes.Interface, row patchRow) (string, error) {
+	live, err := getWorkload(clientset, row)
+	if err != nil {
+		return "", err
+	}
+	containers := live.Template.Spec.Containers
+	if len(containers) != 1 {
+		names := make([]string, len(containers))
+		for i, container := range containers {
+			names[i] = container.Name
+		}
+		return "", fmt.Errorf("%s has %d containers (%s) and the CSV only holds their summed resources; generate with -wide to patch each container", kubectlKind(row.Kind), len(containers), strings.Join(names, ", "))
+	}
+	return containers[0].Name, nil
+}
+
+// multiContainerCount is the number of deployments whose CSV row needs -wide to be patchable.
+func multiContainerCount(data []DeploymentInfo) int {
+	count := 0
+	for _, deploy := range data {
+		if len(deploy.Containers) > 1 {
+			count++
+		}
+	}
+	return count
+}
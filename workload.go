@@ -0,0 +1,397 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Workload kinds as they appear in the Kind column and in HPA scaleTargetRefs.
+const (
+	kindDeployment  = "Deployment"
+	kindStatefulSet = "StatefulSet"
+	kindDaemonSet   = "DaemonSet"
+)
+
+// workloadKinds maps the -resource-type values to the Kind they list.
+var workloadKinds = map[string]string{
+	"deployment":  kindDeployment,
+	"statefulset": kindStatefulSet,
+	"daemonset":   kindDaemonSet,
+}
+
+// selectedKinds returns the kinds listed by -resource-type.
+func selectedKinds() (map[string]bool, error) {
+	kinds := make(map[string]bool)
+	for _, value := range strings.Split(*resourceType, ",") {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" {
+			continue
+		}
+		kind, ok := workloadKinds[value]
+		if !ok {
+			return nil, fmt.Errorf("invalid -resource-type %q: must be a comma-separated list of deployment, statefulset, daemonset", value)
+		}
+		kinds[kind] = true
+	}
+	if len(kinds) == 0 {
+		return nil, fmt.Errorf("-resource-type must name at least one of deployment, statefulset, daemonset")
+	}
+	return kinds, nil
+}
+
+// kubectlKind is the resource name kubectl expects for a workload kind.
+func kubectlKind(kind string) string {
+	return strings.ToLower(kind)
+}
+
+// hasRollingUpdateParams reports whether the kind has the maxUnavailable/maxSurge columns. The
+// StatefulSet maxUnavailable is still feature-gated and it has no maxSurge, so they are left alone.
+func hasRollingUpdateParams(kind string) bool {
+	return kind == kindDeployment
+}
+
+// hasReplicaCount reports whether the kind has spec.replicas and can be scaled by an HPA. A
+// DaemonSet runs one pod per eligible node instead, so its replica and HPA columns are N/A.
+func hasReplicaCount(kind string) bool {
+	return kind != kindDaemonSet
+}
+
+// workloadObjects are the listings shared by every workload kind of a getDeploymentInfo call.
+// They are only read once built, so workloads can be processed concurrently.
+type workloadObjects struct {
+	hpas           map[hpaTarget]*autoscalingv2.HorizontalPodAutoscaler
+	pdbs           []policyv1.PodDisruptionBudget
+	services       []v1.Service
+	spotIndicators []nodeIndicator
+}
+
+// hpaTarget identifies the workload an HPA scales.
+type hpaTarget struct {
+	Namespace, Kind, Name string
+}
+
+// indexHPAs keys the HPAs on the workload they target, so each workload finds its HPA with one
+// lookup instead of a scan of the whole list. The namespace matters with -all-namespaces, where
+// names repeat across namespaces.
+func indexHPAs(hpas []autoscalingv2.HorizontalPodAutoscaler) map[hpaTarget]*autoscalingv2.HorizontalPodAutoscaler {
+	index := make(map[hpaTarget]*autoscalingv2.HorizontalPodAutoscaler, len(hpas))
+	for i, hpa := range hpas {
+		target := hpaTarget{Namespace: hpa.Namespace, Kind: hpa.Spec.ScaleTargetRef.Kind, Name: hpa.Spec.ScaleTargetRef.Name}
+		if _, taken := index[target]; !taken {
+			index[target] = &hpas[i]
+		}
+	}
+	return index
+}
+
+// memoryCell renders a memory request or limit in the canonical form of the quantity, so an
+// unedited row patches exactly the live value ("500M" stays "500M" instead of becoming 476Mi).
+// Unset memory is rendered as "0Mi", which the patch action leaves alone.
+func memoryCell(quantity resource.Quantity) string {
+	if quantity.IsZero() {
+		return "0Mi"
+	}
+	return quantity.String()
+}
+
+// ephemeralStorageCell renders an ephemeral-storage request or limit like memoryCell, and as "0"
+// when unset.
+func ephemeralStorageCell(quantity resource.Quantity) string {
+	if quantity.IsZero() {
+		return "0"
+	}
+	return quantity.String()
+}
+
+// fillPodTemplate records the container resources and the pod-template derived columns.
+func (o workloadObjects) fillPodTemplate(info *DeploymentInfo, template v1.PodTemplateSpec) {
+	var totalCPURequest, totalCPULimit int64
+	// Memory and ephemeral storage are summed as quantities: the totals of a single container
+	// workload are the cells the patch applies, so they keep the exact live value.
+	var totalMemoryRequest, totalMemoryLimit, totalStorageRequest, totalStorageLimit resource.Quantity
+
+	// Aggregate resource requests and limits from all containers in the workload.
+	for _, container := range template.Spec.Containers {
+		resources := container.Resources
+		cpuRequest := resources.Requests.Cpu().MilliValue()
+		cpuLimit := resources.Limits.Cpu().MilliValue()
+		memoryRequest, memoryLimit := *resources.Requests.Memory(), *resources.Limits.Memory()
+		storageRequest, storageLimit := *resources.Requests.StorageEphemeral(), *resources.Limits.StorageEphemeral()
+
+		totalCPURequest += cpuRequest
+		totalCPULimit += cpuLimit
+		totalMemoryRequest.Add(memoryRequest)
+		totalMemoryLimit.Add(memoryLimit)
+		totalStorageRequest.Add(storageRequest)
+		totalStorageLimit.Add(storageLimit)
+
+		info.Containers = append(info.Containers, ContainerResources{
+			Name:                    container.Name,
+			CPURequest:              fmt.Sprintf("%dm", cpuRequest),
+			CPULimit:                fmt.Sprintf("%dm", cpuLimit),
+			MemoryRequest:           memoryCell(memoryRequest),
+			MemoryLimit:             memoryCell(memoryLimit),
+			EphemeralStorageRequest: ephemeralStorageCell(storageRequest),
+			EphemeralStorageLimit:   ephemeralStorageCell(storageLimit),
+		})
+	}
+
+	info.CPURequest = fmt.Sprintf("%dm", totalCPURequest)
+	info.CPULimit = fmt.Sprintf("%dm", totalCPULimit)
+	info.MemoryRequest = memoryCell(totalMemoryRequest)
+	info.MemoryLimit = memoryCell(totalMemoryLimit)
+	info.EphemeralStorageRequest = ephemeralStorageCell(totalStorageRequest)
+	info.EphemeralStorageLimit = ephemeralStorageCell(totalStorageLimit)
+	info.Image = containerImages(template.Spec.Containers)
+	info.ExtendedResources = extendedResources(template.Spec.Containers)
+
+	// Match the PDB and check whether the pods are confined to spot capacity.
+	if pdb := matchingPDB(o.pdbs, info.Namespace, template.Labels); pdb != nil {
+		info.PDBName = pdb.Name
+		info.PDBMinAvailable = intOrStringCell(pdb.Spec.MinAvailable)
+		info.PDBMaxUnavailable = intOrStringCell(pdb.Spec.MaxUnavailable)
+	}
+	info.SpotOnly = runsOnlyOnNodes(template.Spec, o.spotIndicators)
+	info.Security = securityPosture(template.Spec)
+	info.Services = matchingServices(o.services, info.Namespace, template.Labels)
+}
+
+// matchHPA copies the settings of the HPA targeting the workload (if any) into info.
+func (o workloadObjects) matchHPA(info *DeploymentInfo) {
+	hpa, ok := o.hpas[hpaTarget{Namespace: info.Namespace, Kind: info.Kind, Name: info.Name}]
+	if !ok {
+		return
+	}
+	info.HasHPA = true
+	if hpa.Spec.MinReplicas != nil {
+		info.MinReplicas = *hpa.Spec.MinReplicas
+	} else {
+		info.MinReplicas = 1 // Default to 1 if MinReplicas is not set.
+	}
+	info.MaxReplicas = hpa.Spec.MaxReplicas
+	info.HPAResourceVersion = hpa.ResourceVersion
+	info.HPACurrentReplicas = hpa.Status.CurrentReplicas
+	info.HPADesiredReplicas = hpa.Status.DesiredReplicas
+
+	// Extract CPU and memory target utilization
+	for _, metric := range hpa.Spec.Metrics {
+		if metric.Type == autoscalingv2.ResourceMetricSourceType && metric.Resource != nil {
+			if metric.Resource.Name == v1.ResourceCPU && metric.Resource.Target.AverageUtilization != nil {
+				info.CPUTargetUtilization = *metric.Resource.Target.AverageUtilization
+			}
+			if metric.Resource.Name == v1.ResourceMemory && metric.Resource.Target.AverageUtilization != nil {
+				info.MemoryTargetUtilization = metric.Resource.Target.AverageUtilization
+			}
+		}
+	}
+
+	info.OtherMetrics = otherMetricsSummary(hpa.Spec.Metrics)
+	info.CPUCurrentUtilization = currentUtilization(hpa.Status.CurrentMetrics, v1.ResourceCPU)
+
+	// Extract ScaleUp and ScaleDown behaviors
+	if hpa.Spec.Behavior != nil {
+		if hpa.Spec.Behavior.ScaleUp != nil {
+			info.ScaleUpStabilization = hpa.Spec.Behavior.ScaleUp.StabilizationWindowSeconds
+			info.ScaleUpPolicies = encodeScalingPolicies(hpa.Spec.Behavior.ScaleUp)
+		}
+		if hpa.Spec.Behavior.ScaleDown != nil {
+			info.ScaleDownStabilization = hpa.Spec.Behavior.ScaleDown.StabilizationWindowSeconds
+			info.ScaleDownPolicies = encodeScalingPolicies(hpa.Spec.Behavior.ScaleDown)
+		}
+	}
+}
+
+// deploymentInfo collects the row of a Deployment.
+func (o workloadObjects) deploymentInfo(deploy appsv1.Deployment) DeploymentInfo {
+	info := DeploymentInfo{
+		Kind:              kindDeployment,
+		Name:              deploy.Name,
+		Namespace:         deploy.Namespace,
+		Replicas:          1, // The API default when spec.replicas is unset, e.g. left to the HPA.
+		ResourceVersion:   deploy.ResourceVersion,
+		ReadyReplicas:     deploy.Status.ReadyReplicas,
+		AvailableReplicas: deploy.Status.AvailableReplicas,
+		Conditions:        conditionsSummary(deploy.Status.Conditions),
+		Labels:            deploy.Labels,
+		Annotations:       deploy.Annotations,
+		Strategy:          string(deploy.Spec.Strategy.Type),
+		MinReadySeconds:   deploy.Spec.MinReadySeconds,
+	}
+	if deploy.Spec.Replicas != nil {
+		info.Replicas = *deploy.Spec.Replicas
+	}
+	if info.missingReplicas() > 0 {
+		info.ReplicaIssue = failingCondition(deploy.Status.Conditions)
+	}
+	o.fillPodTemplate(&info, deploy.Spec.Template)
+	info.Age = workloadAge(deploy.CreationTimestamp)
+	info.LastRollout = lastRollout(deploy.Spec.Template, deploy.Status.Conditions)
+
+	// Get `maxUnavailable` dan `maxSurge` dari RollingUpdate Strategy
+	if deploy.Spec.Strategy.Type == "RollingUpdate" && deploy.Spec.Strategy.RollingUpdate != nil {
+		if deploy.Spec.Strategy.RollingUpdate.MaxUnavailable != nil {
+			info.MaxUnavailable = deploy.Spec.Strategy.RollingUpdate.MaxUnavailable.String()
+		}
+		if deploy.Spec.Strategy.RollingUpdate.MaxSurge != nil {
+			info.MaxSurge = deploy.Spec.Strategy.RollingUpdate.MaxSurge.String()
+		}
+	}
+
+	// Match HPA with the deployment (if available).
+	o.matchHPA(&info)
+	return info
+}
+
+// statefulSetInfo collects the row of a StatefulSet. StatefulSets have no maxSurge and their
+// conditions aren't populated, so those columns stay empty.
+func (o workloadObjects) statefulSetInfo(sts appsv1.StatefulSet) DeploymentInfo {
+	info := DeploymentInfo{
+		Kind:              kindStatefulSet,
+		Name:              sts.Name,
+		Namespace:         sts.Namespace,
+		Replicas:          1, // The API default when spec.replicas is unset.
+		ResourceVersion:   sts.ResourceVersion,
+		ReadyReplicas:     sts.Status.ReadyReplicas,
+		AvailableReplicas: sts.Status.AvailableReplicas,
+		Labels:            sts.Labels,
+		Annotations:       sts.Annotations,
+		Strategy:          string(sts.Spec.UpdateStrategy.Type),
+		MinReadySeconds:   sts.Spec.MinReadySeconds,
+	}
+	if sts.Spec.Replicas != nil {
+		info.Replicas = *sts.Spec.Replicas
+	}
+	o.fillPodTemplate(&info, sts.Spec.Template)
+	info.Age = workloadAge(sts.CreationTimestamp)
+	info.LastRollout = lastRollout(sts.Spec.Template, nil)
+	o.matchHPA(&info)
+	return info
+}
+
+// daemonSetInfo collects the row of a DaemonSet. Only the pod template columns are filled: there
+// is no replica count, surge or HPA to report.
+func (o workloadObjects) daemonSetInfo(ds appsv1.DaemonSet) DeploymentInfo {
+	info := DeploymentInfo{
+		Kind:            kindDaemonSet,
+		Name:            ds.Name,
+		Namespace:       ds.Namespace,
+		ResourceVersion: ds.ResourceVersion,
+		Labels:          ds.Labels,
+		Annotations:     ds.Annotations,
+		Strategy:        string(ds.Spec.UpdateStrategy.Type),
+		MinReadySeconds: ds.Spec.MinReadySeconds,
+	}
+	o.fillPodTemplate(&info, ds.Spec.Template)
+	info.Age = workloadAge(ds.CreationTimestamp)
+	info.LastRollout = lastRollout(ds.Spec.Template, nil)
+	return info
+}
+
+// workload is the part of a Deployment, StatefulSet or DaemonSet the patch path compares with a row.
+type workload struct {
+	ResourceVersion string
+	Replicas        *int32
+	Template        v1.PodTemplateSpec
+	Strategy        *appsv1.DeploymentStrategy // nil for StatefulSets and DaemonSets
+}
+
+// getWorkload reads the live object of the row's kind.
+func getWorkload(clientset kubernetes.Interface, row patchRow) (*workload, error) {
+	ctx, cancel := apiContext()
+	defer cancel()
+
+	switch row.Kind {
+	case kindStatefulSet:
+		sts, err := clientset.AppsV1().StatefulSets(row.Namespace).Get(ctx, row.DeploymentName, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get statefulset %s: %w", row.DeploymentName, err)
+		}
+		return &workload{ResourceVersion: sts.ResourceVersion, Replicas: sts.Spec.Replicas, Template: sts.Spec.Template}, nil
+	case kindDaemonSet:
+		ds, err := clientset.AppsV1().DaemonSets(row.Namespace).Get(ctx, row.DeploymentName, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get daemonset %s: %w", row.DeploymentName, err)
+		}
+		return &workload{ResourceVersion: ds.ResourceVersion, Template: ds.Spec.Template}, nil
+	default:
+		deploy, err := clientset.AppsV1().Deployments(row.Namespace).Get(ctx, row.DeploymentName, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get deployment %s: %w", row.DeploymentName, err)
+		}
+		return &workload{ResourceVersion: deploy.ResourceVersion, Replicas: deploy.Spec.Replicas, Template: deploy.Spec.Template, Strategy: &deploy.Spec.Strategy}, nil
+	}
+}
+
+// rowKind resolves the Kind cell of a row. Files written before the column existed only held
+// Deployments; an unknown kind is recorded as a parse error so the row is refused.
+func rowKind(cell string, row *patchRow) string {
+	if cell == "" {
+		return kindDeployment
+	}
+	for _, kind := range workloadKinds {
+		if strings.EqualFold(cell, kind) {
+			return kind
+		}
+	}
+	row.ParseErrors = append(row.ParseErrors, fmt.Sprintf("column \"Kind\": %q is not Deployment, StatefulSet or DaemonSet", cell))
+	return cell
+}
+
+// containerImages renders the Image column: the image of a single container as is, and
+// "name=image:tag" per container, comma-separated, when there are several.
+func containerImages(containers []v1.Container) string {
+	if len(containers) == 1 {
+		return containers[0].Image
+	}
+	images := make([]string, 0, len(containers))
+	for _, container := range containers {
+		images = append(images, container.Name+"="+container.Image)
+	}
+	return strings.Join(images, ", ")
+}
+
+// extendedResources renders the Extended Resources column: every resource besides CPU, memory and
+// ephemeral storage (GPUs such as nvidia.com/gpu, hugepages, device plugin resources) as
+// "name=quantity", summed over the containers and sorted by name, e.g. "nvidia.com/gpu=2". A
+// container's limit is used, or its request when it sets no limit for the resource.
+func extendedResources(containers []v1.Container) string {
+	totals := make(map[v1.ResourceName]*resource.Quantity)
+	add := func(name v1.ResourceName, quantity resource.Quantity) {
+		switch name {
+		case v1.ResourceCPU, v1.ResourceMemory, v1.ResourceEphemeralStorage, v1.ResourceStorage:
+			return
+		}
+		if total, ok := totals[name]; ok {
+			total.Add(quantity)
+			return
+		}
+		quantity = quantity.DeepCopy()
+		totals[name] = &quantity
+	}
+	for _, container := range containers {
+		for name, quantity := range container.Resources.Limits {
+			add(name, quantity)
+		}
+		for name, quantity := range container.Resources.Requests {
+			if _, limited := container.Resources.Limits[name]; !limited {
+				add(name, quantity)
+			}
+		}
+	}
+
+	pairs := make([]string, 0, len(totals))
+	for name, total := range totals {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", name, total.String()))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
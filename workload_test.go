@@ -0,0 +1,404 @@
+package main
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestStatefulSetInfoMatchesHPAByKind(t *testing.T) {
+	hpa := func(kind string, max int32) autoscalingv2.HorizontalPodAutoscaler {
+		return autoscalingv2.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "db-" + kind, Namespace: "shop"},
+			Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: kind, Name: "db"},
+				MaxReplicas:    max,
+			},
+		}
+	}
+	// A Deployment of the same name must not lend its HPA to the StatefulSet.
+	objects := workloadObjects{hpas: indexHPAs([]autoscalingv2.HorizontalPodAutoscaler{hpa(kindDeployment, 9), hpa(kindStatefulSet, 4)})}
+	sts := appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "shop"}}
+
+	info := objects.statefulSetInfo(sts)
+	if info.Kind != kindStatefulSet || info.MaxReplicas != 4 || info.MinReplicas != 1 {
+		t.Errorf("info = %+v, want a StatefulSet row with the StatefulSet HPA (min 1, max 4)", info)
+	}
+	if info.Replicas != 1 {
+		t.Errorf("Replicas = %d, want the API default 1 for an unset spec.replicas", info.Replicas)
+	}
+}
+
+func TestDeploymentWithoutHPAHasNAHPAColumns(t *testing.T) {
+	deploy := appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"}}
+	info := workloadObjects{}.deploymentInfo(deploy)
+	if info.HasHPA {
+		t.Fatal("HasHPA = true, want false without a matching HPA")
+	}
+
+	record := csvRecord(0, info, nil)
+	layout := parseCSVLayout(csvHeader(nil))
+	for _, column := range []string{"Min Replicas", "Max Replicas", "CPU Target Utilization", "Current Replicas", "Desired Replicas", "CPU Current Utilization"} {
+		if got := layout.cell(record, column); got != "N/A" {
+			t.Errorf("%s = %q, want N/A without an HPA", column, got)
+		}
+	}
+	if row := parsePatchRow(record, layout); len(row.ParseErrors) > 0 || row.MaxReplicas != 0 {
+		t.Errorf("parsePatchRow() = %+v, want no HPA values and no parse errors", row)
+	}
+	if got := withoutHPA([]DeploymentInfo{info, {Kind: kindDeployment, HasHPA: true}}); len(got) != 1 || got[0].Name != "web" {
+		t.Errorf("withoutHPA() = %+v, want only web", got)
+	}
+}
+
+func TestRowKind(t *testing.T) {
+	for cell, want := range map[string]string{"": kindDeployment, "statefulset": kindStatefulSet, "Deployment": kindDeployment} {
+		var row patchRow
+		if got := rowKind(cell, &row); got != want || len(row.ParseErrors) > 0 {
+			t.Errorf("rowKind(%q) = %q (errors %q), want %q", cell, got, row.ParseErrors, want)
+		}
+	}
+	var row patchRow
+	if rowKind("CronJob", &row); len(row.ParseErrors) != 1 {
+		t.Errorf("rowKind(CronJob) recorded %q, want one parse error", row.ParseErrors)
+	}
+}
+
+func TestDaemonSetRowRoundTripsWithoutHPA(t *testing.T) {
+	ds := appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "node-agent", Namespace: "kube-system"}}
+	info := workloadObjects{}.daemonSetInfo(ds)
+
+	record := csvRecord(0, info, nil)
+	header := csvHeader(nil)
+	layout := parseCSVLayout(header)
+	for _, column := range []string{"Replicas", "Min Replicas", "Max Replicas", "CPU Target Utilization", "Missing Replicas", "Ready Replicas", "Available Replicas"} {
+		if got := layout.cell(record, column); got != "N/A" {
+			t.Errorf("%s = %q, want N/A for a DaemonSet", column, got)
+		}
+	}
+
+	row := parsePatchRow(record, layout)
+	if row.Kind != kindDaemonSet || len(row.ParseErrors) > 0 {
+		t.Errorf("parsed row = kind %q, errors %q; want a DaemonSet row without errors", row.Kind, row.ParseErrors)
+	}
+	if err := validateReplicaBounds(row); err != nil {
+		t.Errorf("validateReplicaBounds = %v, want nil for a row without an HPA", err)
+	}
+}
+
+func TestDeploymentInfoDefaultsNilReplicasToOne(t *testing.T) {
+	deploy := appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+		Status:     appsv1.DeploymentStatus{AvailableReplicas: 1},
+	}
+	info := workloadObjects{}.deploymentInfo(deploy)
+	if info.Replicas != 1 {
+		t.Errorf("Replicas = %d, want 1 when spec.replicas is unset", info.Replicas)
+	}
+	if missing := info.missingReplicas(); missing != 0 {
+		t.Errorf("missingReplicas() = %d, want 0", missing)
+	}
+}
+
+func TestContainerImages(t *testing.T) {
+	single := []v1.Container{{Name: "app", Image: "shop/web:1.4.2"}}
+	if got := containerImages(single); got != "shop/web:1.4.2" {
+		t.Errorf("containerImages(single) = %q", got)
+	}
+	multi := append(single, v1.Container{Name: "proxy", Image: "envoyproxy/envoy:v1.27.0"})
+	if got, want := containerImages(multi), "app=shop/web:1.4.2, proxy=envoyproxy/envoy:v1.27.0"; got != want {
+		t.Errorf("containerImages(multi) = %q, want %q", got, want)
+	}
+}
+
+func TestWorkloadWarnings(t *testing.T) {
+	info := DeploymentInfo{
+		Kind: kindDeployment,
+		Containers: []ContainerResources{
+			{Name: "app", CPURequest: "100m", MemoryLimit: "256Mi"},
+			{Name: "sidecar", CPURequest: "0m", MemoryLimit: "0Mi"},
+		},
+	}
+	if got, want := workloadWarnings(info), "no CPU request (sidecar); no memory limit (sidecar); no HPA"; got != want {
+		t.Errorf("workloadWarnings() = %q, want %q", got, want)
+	}
+
+	info.Containers, info.HasHPA = info.Containers[:1], true
+	if got := workloadWarnings(info); got != "" {
+		t.Errorf("workloadWarnings() = %q, want none", got)
+	}
+}
+
+func TestRecreateDeploymentRollingUpdateCells(t *testing.T) {
+	deploy := appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "batch", Namespace: "shop"},
+		Spec:       appsv1.DeploymentSpec{Strategy: appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType}},
+	}
+	info := workloadObjects{}.deploymentInfo(deploy)
+
+	record := csvRecord(0, info, nil)
+	layout := parseCSVLayout(csvHeader(nil))
+	if got := layout.cell(record, "Strategy"); got != "Recreate" {
+		t.Errorf("Strategy = %q, want Recreate", got)
+	}
+	for _, column := range []string{"MaxUnavailable", "MaxSurge"} {
+		if got := layout.cell(record, column); got != recreateCell {
+			t.Errorf("%s = %q, want %q", column, got, recreateCell)
+		}
+	}
+
+	row := parsePatchRow(record, layout)
+	if row.MaxUnavailable != "" || row.MaxSurge != "" || patchesRollingUpdate(io.Discard, row) {
+		t.Errorf("row = %+v, want no rolling update parameters to patch", row)
+	}
+	row.MaxSurge = "25%"
+	if patchesRollingUpdate(io.Discard, row) {
+		t.Error("patchesRollingUpdate() = true for a Recreate Deployment with MaxSurge set, want false")
+	}
+}
+
+func TestHPAReplicaStatusColumns(t *testing.T) {
+	cpuUsage := int32(85)
+	objects := workloadObjects{hpas: indexHPAs([]autoscalingv2.HorizontalPodAutoscaler{{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: kindDeployment, Name: "web"},
+			MaxReplicas:    10,
+		},
+		Status: autoscalingv2.HorizontalPodAutoscalerStatus{
+			CurrentReplicas: 3,
+			DesiredReplicas: 5,
+			CurrentMetrics: []autoscalingv2.MetricStatus{{
+				Type:     autoscalingv2.ResourceMetricSourceType,
+				Resource: &autoscalingv2.ResourceMetricStatus{Name: v1.ResourceCPU, Current: autoscalingv2.MetricValueStatus{AverageUtilization: &cpuUsage}},
+			}},
+		},
+	}})}
+	info := objects.deploymentInfo(appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"}})
+
+	record := csvRecord(0, info, nil)
+	layout := parseCSVLayout(csvHeader(nil))
+	if current, desired := layout.cell(record, "Current Replicas"), layout.cell(record, "Desired Replicas"); current != "3" || desired != "5" {
+		t.Errorf("Current/Desired Replicas = %s/%s, want 3/5", current, desired)
+	}
+	if got := layout.cell(record, "CPU Current Utilization"); got != "85" {
+		t.Errorf("CPU Current Utilization = %q, want 85", got)
+	}
+
+	// Before metrics-server reports, the HPA has no current metrics.
+	info.CPUCurrentUtilization = nil
+	if got := layout.cell(csvRecord(0, info, nil), "CPU Current Utilization"); got != "N/A" {
+		t.Errorf("CPU Current Utilization = %q, want N/A without metrics", got)
+	}
+}
+
+func TestExtendedResources(t *testing.T) {
+	containers := []v1.Container{
+		{Name: "trainer", Resources: v1.ResourceRequirements{
+			Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("4"), "nvidia.com/gpu": resource.MustParse("1")},
+			Limits:   v1.ResourceList{v1.ResourceMemory: resource.MustParse("16Gi"), "nvidia.com/gpu": resource.MustParse("1"), "hugepages-2Mi": resource.MustParse("1Gi")},
+		}},
+		{Name: "sidecar", Resources: v1.ResourceRequirements{
+			Requests: v1.ResourceList{"nvidia.com/gpu": resource.MustParse("1"), v1.ResourceEphemeralStorage: resource.MustParse("1Gi")},
+		}},
+	}
+	if got, want := extendedResources(containers), "hugepages-2Mi=1Gi, nvidia.com/gpu=2"; got != want {
+		t.Errorf("extendedResources() = %q, want %q", got, want)
+	}
+	if got := extendedResources([]v1.Container{{Name: "web"}}); got != "" {
+		t.Errorf("extendedResources() = %q, want empty without extended resources", got)
+	}
+}
+
+func TestEphemeralStorageColumns(t *testing.T) {
+	container := func(name, request, limit string) v1.Container {
+		resources := v1.ResourceRequirements{Requests: v1.ResourceList{}, Limits: v1.ResourceList{}}
+		if request != "" {
+			resources.Requests[v1.ResourceEphemeralStorage] = resource.MustParse(request)
+		}
+		if limit != "" {
+			resources.Limits[v1.ResourceEphemeralStorage] = resource.MustParse(limit)
+		}
+		return v1.Container{Name: name, Resources: resources}
+	}
+	deployment := appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"}}
+	deployment.Spec.Template.Spec.Containers = []v1.Container{container("app", "1Gi", "2Gi"), container("logs", "512Mi", "")}
+	info := workloadObjects{}.deploymentInfo(deployment)
+
+	record := csvRecord(0, info, nil)
+	layout := parseCSVLayout(csvHeader(nil))
+	if request, limit := layout.cell(record, "Ephemeral Storage Request"), layout.cell(record, "Ephemeral Storage Limit"); request != "1536Mi" || limit != "2Gi" {
+		t.Errorf("Ephemeral Storage Request/Limit = %s/%s, want 1536Mi/2Gi", request, limit)
+	}
+	if got := info.Containers[1].EphemeralStorageLimit; got != "0" {
+		t.Errorf("logs Ephemeral Storage Limit = %q, want 0 when unset", got)
+	}
+
+	row := parsePatchRow(record, layout)
+	if row.EphemeralStorageRequest != "1536Mi" || row.EphemeralStorageLimit != "2Gi" {
+		t.Errorf("parsed ephemeral storage = %s/%s, want 1536Mi/2Gi", row.EphemeralStorageRequest, row.EphemeralStorageLimit)
+	}
+}
+
+func TestLastRolloutAndAge(t *testing.T) {
+	progressed := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	deployment := appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop", CreationTimestamp: metav1.NewTime(time.Now().Add(-45 * 24 * time.Hour))}}
+	deployment.Status.Conditions = []appsv1.DeploymentCondition{{Type: appsv1.DeploymentProgressing, LastUpdateTime: metav1.NewTime(progressed)}}
+
+	info := workloadObjects{}.deploymentInfo(deployment)
+	if info.LastRollout != "2026-03-01T12:00:00Z" || info.Age != "45d" {
+		t.Errorf("Last Rollout/Age = %s/%s, want 2026-03-01T12:00:00Z/45d", info.LastRollout, info.Age)
+	}
+
+	// A later kubectl rollout restart wins over the condition.
+	deployment.Spec.Template.Annotations = map[string]string{restartedAtAnnotation: "2026-04-02T08:30:00+02:00"}
+	if got := (workloadObjects{}).deploymentInfo(deployment).LastRollout; got != "2026-04-02T06:30:00Z" {
+		t.Errorf("Last Rollout = %s, want the restartedAt time 2026-04-02T06:30:00Z", got)
+	}
+	if got := lastRollout(v1.PodTemplateSpec{}, nil); got != "N/A" {
+		t.Errorf("lastRollout() = %s, want N/A without a restart or condition", got)
+	}
+}
+
+func TestGetDeploymentInfo(t *testing.T) {
+	replicas, maxReplicas := int32(3), int32(6)
+	container := func(name, cpu, memory string, limits v1.ResourceList) v1.Container {
+		return v1.Container{Name: name, Resources: v1.ResourceRequirements{
+			Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu), v1.ResourceMemory: resource.MustParse(memory)},
+			Limits:   limits,
+		}}
+	}
+	clientset := fake.NewSimpleClientset(
+		// Two containers, spec.replicas left to the HPA.
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "shop"},
+			Spec: appsv1.DeploymentSpec{Template: v1.PodTemplateSpec{Spec: v1.PodSpec{Containers: []v1.Container{
+				container("app", "250m", "256Mi", v1.ResourceList{v1.ResourceCPU: resource.MustParse("500m"), v1.ResourceMemory: resource.MustParse("512Mi")}),
+				container("proxy", "100m", "64Mi", nil),
+			}}}},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "shop"},
+			Spec: appsv1.DeploymentSpec{Replicas: &replicas, Template: v1.PodTemplateSpec{Spec: v1.PodSpec{Containers: []v1.Container{
+				container("worker", "1", "1Gi", nil),
+			}}}},
+		},
+		&autoscalingv2.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "shop"},
+			Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: kindDeployment, Name: "api"},
+				MaxReplicas:    maxReplicas,
+			},
+		},
+	)
+
+	data, err := getDeploymentInfo(clientset, "shop")
+	if err != nil {
+		t.Fatalf("getDeploymentInfo() = %v", err)
+	}
+	if len(data) != 2 || data[0].Name != "api" || data[1].Name != "worker" {
+		t.Fatalf("getDeploymentInfo() = %+v, want api and worker", data)
+	}
+
+	api := data[0]
+	if api.CPURequest != "350m" || api.CPULimit != "500m" || api.MemoryRequest != "320Mi" || api.MemoryLimit != "512Mi" || len(api.Containers) != 2 {
+		t.Errorf("api resources = %s/%s, %s/%s in %d containers; want the sums 350m/500m, 320Mi/512Mi of 2",
+			api.CPURequest, api.CPULimit, api.MemoryRequest, api.MemoryLimit, len(api.Containers))
+	}
+	if api.Replicas != 1 || !api.HasHPA || api.MinReplicas != 1 || api.MaxReplicas != maxReplicas {
+		t.Errorf("api replicas = %d, HPA %v %d-%d; want the defaults 1 and 1-%d for nil spec.replicas and minReplicas", api.Replicas, api.HasHPA, api.MinReplicas, api.MaxReplicas, maxReplicas)
+	}
+
+	worker := data[1]
+	if worker.Replicas != 3 || worker.HasHPA || worker.MinReplicas != 0 || worker.MaxReplicas != 0 {
+		t.Errorf("worker replicas = %d, HPA %v %d-%d; want 3 and no HPA", worker.Replicas, worker.HasHPA, worker.MinReplicas, worker.MaxReplicas)
+	}
+	if worker.CPURequest != "1000m" || worker.CPULimit != "0m" || worker.MemoryRequest != "1Gi" || worker.MemoryLimit != "0Mi" {
+		t.Errorf("worker resources = %s/%s, %s/%s; want 1000m/0m, 1Gi/0Mi", worker.CPURequest, worker.CPULimit, worker.MemoryRequest, worker.MemoryLimit)
+	}
+}
+
+func TestUnsetRequestsRowIsUpToDate(t *testing.T) {
+	inTempDir(t)
+	replicas := int32(2)
+	maxUnavailable, maxSurge := intstr.FromString("25%"), intstr.FromString("25%")
+	deploy := appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Strategy: appsv1.DeploymentStrategy{
+				Type:          appsv1.RollingUpdateDeploymentStrategyType,
+				RollingUpdate: &appsv1.RollingUpdateDeployment{MaxUnavailable: &maxUnavailable, MaxSurge: &maxSurge},
+			},
+			// No requests: exported as 0m and 0Mi.
+			Template: v1.PodTemplateSpec{Spec: v1.PodSpec{Containers: []v1.Container{{
+				Name:      "web",
+				Resources: v1.ResourceRequirements{Limits: v1.ResourceList{v1.ResourceCPU: resource.MustParse("500m")}},
+			}}}},
+		},
+	}
+	clientset := fake.NewSimpleClientset(&deploy)
+
+	row := patchRowFromInfo(workloadObjects{}.deploymentInfo(deploy))
+	row.UpdateResourceAndHPA = true
+	live, err := getWorkload(clientset, row)
+	if err != nil {
+		t.Fatalf("getWorkload: %v", err)
+	}
+	if changes := workloadChanges(live, row); len(changes) != 0 {
+		t.Errorf("workloadChanges() for an unedited row = %+v, want none", changes)
+	}
+	state := &patchState{Applied: make(map[string]string)}
+	run := &patchRun{clientset: clientset, metrics: &metricsPreflight{clientset: clientset}, limitRanges: newLimitRangeChecker(clientset), state: state}
+	if outcome := run.apply(io.Discard, row); outcome != rowUpToDate {
+		t.Errorf("apply() of an unedited row without requests = %v, want rowUpToDate", outcome)
+	}
+}
+
+func TestDecimalSIMemoryRoundTrips(t *testing.T) {
+	inTempDir(t)
+	replicas := int32(2)
+	maxUnavailable, maxSurge := intstr.FromString("25%"), intstr.FromString("25%")
+	deploy := appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Strategy: appsv1.DeploymentStrategy{
+				Type:          appsv1.RollingUpdateDeploymentStrategyType,
+				RollingUpdate: &appsv1.RollingUpdateDeployment{MaxUnavailable: &maxUnavailable, MaxSurge: &maxSurge},
+			},
+			Template: v1.PodTemplateSpec{Spec: v1.PodSpec{Containers: []v1.Container{{
+				Name: "web",
+				Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("250m"), v1.ResourceMemory: resource.MustParse("500M")},
+					Limits:   v1.ResourceList{v1.ResourceMemory: resource.MustParse("1G")},
+				},
+			}}}},
+		},
+	}
+	clientset := fake.NewSimpleClientset(&deploy)
+
+	info := workloadObjects{}.deploymentInfo(deploy)
+	if info.MemoryRequest != "500M" || info.MemoryLimit != "1G" || info.Containers[0].MemoryRequest != "500M" {
+		t.Errorf("exported memory = %s/%s (container %s), want 500M/1G", info.MemoryRequest, info.MemoryLimit, info.Containers[0].MemoryRequest)
+	}
+
+	// Generate the CSV row and patch it unedited: nothing may change.
+	layout := parseCSVLayout(csvHeader(nil))
+	row := parsePatchRow(csvRecord(0, info, nil), layout)
+	normalizeRowMemory(&row)
+	row.UpdateResourceAndHPA = true
+	state := &patchState{Applied: make(map[string]string)}
+	run := &patchRun{clientset: clientset, metrics: &metricsPreflight{clientset: clientset}, limitRanges: newLimitRangeChecker(clientset), state: state}
+	if outcome := run.apply(io.Discard, row); outcome != rowUpToDate {
+		t.Errorf("apply() of an unedited row with decimal SI memory = %v, want rowUpToDate", outcome)
+	}
+}